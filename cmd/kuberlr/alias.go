@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/linker"
+)
+
+// ensureKubectlVersion returns the path to the kubectl binary with exactly
+// the given version, downloading it when missing
+func ensureKubectlVersion(v *viper.Viper, version semver.Version) (string, error) {
+	kFinder, err := newKubectlFinder(v)
+	if err != nil {
+		return "", err
+	}
+	for _, b := range kFinder.AllKubectlBinaries(true) {
+		if b.Version.Equals(version) {
+			return b.Path, nil
+		}
+	}
+
+	d, err := newDownloader(v)
+	if err != nil {
+		return "", err
+	}
+	downloadDir := common.LocalDownloadDir()
+	store := newSharedStore(v)
+	shared := store.Usable()
+	if shared {
+		downloadDir = store.Dir()
+	}
+	destination := filepath.Join(downloadDir, common.BuildKubectlNameForLocalBin(version))
+
+	if err := d.GetKubectlBinary(version, destination); err != nil {
+		return "", err
+	}
+	if shared {
+		if err := store.Share(destination); err != nil {
+			return "", err
+		}
+	}
+	return destination, nil
+}
+
+// NewAliasCmd creates a new `kuberlr alias` cobra command
+func NewAliasCmd() *cobra.Command {
+	var dir, prefix, templateFile string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:          "alias <context>[=<version>]...",
+		Short:        "Generate kubectl wrapper scripts bound to kubernetes contexts",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Create ~/bin/kubectl-prod and ~/bin/kubectl-staging, which pick the right
+  kubectl version for their context:
+  $ kuberlr alias --dir ~/bin prod staging
+
+  Bind kubectl-prod to kubectl 1.20.4:
+  $ kuberlr alias --dir ~/bin prod=1.20.4
+
+  Generate the scripts from a custom template, which can use the
+  {{.Name}}, {{.Context}}, {{.Kuberlr}} and {{.Kubectl}} fields and
+  the shellQuote function:
+  $ kuberlr alias --dir ~/bin --template ~/kubectl-alias.tmpl prod`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			tmpl := linker.DefaultAliasTemplate()
+			if templateFile != "" {
+				data, err := ioutil.ReadFile(templateFile)
+				if err != nil {
+					return fmt.Errorf("Cannot read alias template: %v", err)
+				}
+				tmpl = string(data)
+			}
+
+			self, err := os.Executable()
+			if err == nil {
+				self, err = filepath.EvalSymlinks(self)
+			}
+			if err != nil {
+				return fmt.Errorf("Cannot find the location of kuberlr: %v", err)
+			}
+			if dir == "" {
+				dir = filepath.Dir(self)
+			}
+
+			for _, arg := range args {
+				parts := strings.SplitN(arg, "=", 2)
+				alias := linker.Alias{
+					Name:    linker.AliasName(prefix, parts[0]),
+					Context: parts[0],
+					Kuberlr: self,
+				}
+				if alias.Context == "" {
+					return fmt.Errorf("Invalid alias %q: the context is missing", arg)
+				}
+				bound := ""
+				if len(parts) == 2 {
+					version, err := semver.ParseTolerant(parts[1])
+					if err != nil {
+						return fmt.Errorf("Invalid version of alias %q: %v", arg, err)
+					}
+					if alias.Kubectl, err = ensureKubectlVersion(v, version); err != nil {
+						return err
+					}
+					bound = version.String()
+				}
+
+				if err := alias.Write(dir, tmpl, force); err != nil {
+					return err
+				}
+				// `kuberlr prune` must not remove the binaries the
+				// scripts are bound to
+				script, err := filepath.Abs(alias.Path(dir))
+				if err == nil {
+					err = common.RecordAlias(common.AliasesFile(), script, bound)
+				}
+				if err != nil {
+					klog.Warningf("Cannot record alias %s: %v", alias.Path(dir), err)
+				}
+				fmt.Printf("Created %s\n", alias.Path(dir))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "directory where the scripts are created (default: the directory of kuberlr)")
+	cmd.Flags().StringVar(&prefix, "prefix", "kubectl-", "prefix of the name of the scripts")
+	cmd.Flags().StringVar(&templateFile, "template", "", "file holding the Go template used to generate the scripts")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite existing scripts")
+
+	return cmd
+}
@@ -1,55 +1,168 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/spf13/cobra"
+	"k8s.io/klog"
 
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
 	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/integrity"
 )
 
-func printBinTable(bins finder.KubectlBinaries) {
+// binOptions defines what is printed about each binary
+type binOptions struct {
+	// verify executes each binary to compare the version it reports
+	// with its filename
+	verify bool
+	// wide prints the provenance of the binaries, taken from their
+	// metadata
+	wide bool
+	// cache holds the checksums of the binaries computed by previous
+	// verifications
+	cache *integrity.Cache
+}
+
+// provenanceColumns returns where the binary comes from, when it has been
+// installed, the prefix of its checksum and whether it changed since then
+func provenanceColumns(path string, cache *integrity.Cache) []interface{} {
+	m, found, err := common.LoadMetadata(path)
+	if err != nil || !found {
+		return []interface{}{"", "", "", statusText(integrity.StatusUnverifiable)}
+	}
+
+	installed := ""
+	if !m.InstalledAt.IsZero() {
+		installed = m.InstalledAt.Local().Format("2006-01-02")
+	}
+	checksum := m.SHA256
+	if len(checksum) > 12 {
+		checksum = checksum[:12]
+	}
+
+	r := integrity.Verify(path, cache)
+	return []interface{}{m.Source(), installed, checksum, statusText(r.Status)}
+}
+
+// printBinTable prints the given binaries, when verify is true each binary
+// is executed to compare the version it reports with its filename. The
+// number of mismatching binaries is returned
+func printBinTable(bins finder.KubectlBinaries, opts binOptions) int {
+	mismatches := 0
+
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"#", "Version", "Binary"})
+	header := table.Row{"#", "Version", "Binary"}
+	if opts.verify {
+		header = append(header, "Reported")
+	}
+	if opts.wide {
+		header = append(header, "Source", "Installed", "SHA256", "Integrity")
+	}
+	t.AppendHeader(header)
 	for i, b := range bins {
-		t.AppendRow([]interface{}{i + 1, b.Version, b.Path})
+		row := []interface{}{i + 1, b.Version, b.Path}
+		if opts.verify {
+			reported, err := finder.ReportedVersion(b.Path)
+			switch {
+			case err != nil:
+				mismatches++
+				row = append(row, text.FgRed.Sprint(err))
+			case !b.MatchesReportedVersion(reported):
+				mismatches++
+				row = append(row, text.FgRed.Sprintf("%s (mismatch)", reported))
+			default:
+				row = append(row, text.FgGreen.Sprint(reported))
+			}
+		}
+		if opts.wide {
+			row = append(row, provenanceColumns(b.Path, opts.cache)...)
+		}
+		t.AppendRow(row)
 	}
 	t.Render()
+
+	return mismatches
+}
+
+func printBinSection(title string, bins finder.KubectlBinaries, err error, opts binOptions) int {
+	fmt.Printf("%s\n", text.FgGreen.Sprint(title))
+	if err != nil {
+		fmt.Printf("Error retrieving binaries: %v\n", err)
+	} else if len(bins) == 0 {
+		fmt.Println("No binaries found.")
+	} else {
+		return printBinTable(bins, opts)
+	}
+	return 0
 }
 
 // NewBinsCmd creates a new `kuberlr bins` cobra command
 func NewBinsCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "bins",
-		Short: "Print information about the kubectl binaries found",
-		Run: func(cmd *cobra.Command, args []string) {
+	opts := binOptions{}
+
+	cmd := &cobra.Command{
+		Use:          "bins",
+		Short:        "Print information about the kubectl binaries found",
+		SilenceUsage: true,
+		Example: `
+  Print all the kubectl binaries available:
+  $ kuberlr bins
+
+  Execute each binary and make sure it is the version its filename advertises:
+  $ kuberlr bins --verify-version
+
+  Print where each binary comes from, when it has been installed and whether
+  it changed since then:
+  $ kuberlr bins --wide`,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			kFinder := finder.NewKubectlFinder("", "")
+			cfg := config.NewCfg()
+			if v, err := cfg.Load(); err == nil {
+				if kFinder, err = newKubectlFinder(v); err != nil {
+					return err
+				}
+			}
+
+			if opts.wide {
+				opts.cache = integrity.LoadCache(verifyCacheFile())
+				defer func() {
+					if err := opts.cache.Save(); err != nil {
+						klog.V(1).Infof("Cannot save verification cache: %v", err)
+					}
+				}()
+			}
+
+			mismatches := 0
+
 			systemBins, err := kFinder.SystemKubectlBinaries()
+			mismatches += printBinSection("system-wide kubectl binaries", systemBins, err, opts)
 
-			fmt.Printf("%s\n", text.FgGreen.Sprint("system-wide kubectl binaries"))
-			if err != nil {
-				fmt.Printf("Error retrieving binaries: %v\n", err)
-			} else if len(systemBins) == 0 {
-				fmt.Println("No binaries found.")
-			} else {
-				printBinTable(systemBins)
+			if kFinder.SharedBinaryPath != "" {
+				fmt.Printf("\n\n")
+				sharedBins, err := kFinder.SharedKubectlBinaries()
+				mismatches += printBinSection("shared kubectl binaries", sharedBins, err, opts)
 			}
 
 			fmt.Printf("\n\n")
 			localBins, err := kFinder.LocalKubectlBinaries()
+			mismatches += printBinSection("local kubectl binaries", localBins, err, opts)
 
-			fmt.Printf("%s\n", text.FgGreen.Sprint("local kubectl binaries"))
-			if err != nil {
-				fmt.Printf("Error retrieving binaries: %v\n", err)
-			} else if len(localBins) == 0 {
-				fmt.Println("No binaries found.")
-			} else {
-				printBinTable(localBins)
+			if mismatches > 0 {
+				return errors.New("Some binaries do not report the version advertised by their filename")
 			}
+			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&opts.verify, "verify-version", false, "execute each binary and compare the version it reports with its filename")
+	cmd.Flags().BoolVarP(&opts.wide, "wide", "w", false, "print the provenance and the integrity of each binary")
+
+	return cmd
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/changelog"
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// NewChangelogCmd creates a new `kuberlr changelog` cobra command
+func NewChangelogCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "changelog [version]",
+		Short:        "Print the changes introduced by a release of kubectl",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Show what changed with kubectl 1.20.2:
+  $ kuberlr changelog 1.20.2`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := semver.ParseTolerant(args[0])
+			if err != nil {
+				return fmt.Errorf("Invalid version: %v", err)
+			}
+
+			f := changelog.Fetcher{
+				BaseURL:  changelog.DefaultBaseURL,
+				CacheDir: filepath.Join(common.KuberlrDir(), "changelogs"),
+			}
+			section, err := f.Get(version)
+			if err != nil {
+				return err
+			}
+			fmt.Println(section)
+			return nil
+		},
+	}
+}
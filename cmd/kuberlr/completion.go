@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/viper"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// completionCommands are the kubectl commands invoked by shell completion
+var completionCommands = map[string]bool{
+	"__complete":       true,
+	"__completeNoDesc": true,
+	"completion":       true,
+}
+
+// isCompletion returns true when kubectl is invoked by shell completion
+func isCompletion(args []string) bool {
+	return len(args) > 0 && completionCommands[args[0]]
+}
+
+// completionKubectl returns the kubectl binary serving shell completions.
+// Completions are requested on each key press, hence the binary is picked
+// without contacting the API server nor downloading anything: the binary
+// last used with the current context is preferred, followed by the most
+// recent one available. The boolean is false when there's no binary around
+func completionKubectl(v *viper.Viper) (string, bool) {
+	usage, err := common.LoadUsage(common.UsageFile())
+	if err != nil {
+		klog.V(1).Infof("Cannot read the usage of the kubectl binaries: %v", err)
+	}
+	if binary, found := usage.LastUsedWith(kubehelper.CurrentContext()); found {
+		if _, err := os.Stat(binary); err == nil {
+			return binary, true
+		}
+	}
+
+	// the policy is enforced only when it's cached, completion must never
+	// wait for the network nor fail because of it
+	kubectl, err := kubectlFinder(v, cachedPolicy(v)).MostRecentKubectlAvailable()
+	if err != nil {
+		return "", false
+	}
+	return kubectl.Path, true
+}
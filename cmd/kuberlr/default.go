@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// NewDefaultCmd creates a new `kuberlr default` cobra command
+func NewDefaultCmd() *cobra.Command {
+	var fromCluster bool
+
+	cmd := &cobra.Command{
+		Use:          "default [version]",
+		Aliases:      []string{"use"},
+		Short:        "Set the kubectl version to use when the version of the cluster cannot be found",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Print the current default version:
+  $ kuberlr default
+
+  Use kubectl 1.20 when the cluster cannot be reached:
+  $ kuberlr default 1.20
+
+  Use the same version of the current cluster when it cannot be reached:
+  $ kuberlr default --from-cluster`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !fromCluster {
+				version, found, err := common.LoadDefaultVersion(common.DefaultVersionFile())
+				if err != nil {
+					return err
+				}
+				if !found {
+					fmt.Println("No default version set")
+					return nil
+				}
+				fmt.Println(version)
+				return nil
+			}
+			if len(args) > 0 && fromCluster {
+				return errors.New("A version cannot be given when --from-cluster is used")
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			var version semver.Version
+			if fromCluster {
+				// probe the cluster with the same timeouts used by the
+				// kubectl wrapper, APITimeout takes precedence over Timeout
+				kubehelper.SetProbeTimeouts(timeoutsFromConfig(v, "ProbeTimeouts", "APITimeout"))
+				kubeAPI := kubehelper.KubeAPI{}
+				version, err = kubeAPI.Version(v.GetInt64("Timeout"))
+				if err != nil {
+					return fmt.Errorf("Cannot find the version of the kubernetes API server: %v", err)
+				}
+			} else {
+				version, err = semver.ParseTolerant(args[0])
+				if err != nil {
+					return fmt.Errorf("Invalid version: %v", err)
+				}
+			}
+
+			// ensure the binary is around, this allows kuberlr to work
+			// offline later on
+			kFinder, err := newKubectlFinder(v)
+			if err != nil {
+				return err
+			}
+			versioner, err := newVersioner(v, kFinder, nil)
+			if err != nil {
+				return err
+			}
+			if _, err := versioner.EnsureCompatibleKubectlAvailable(version, v.GetBool("AllowDownload")); err != nil {
+				return err
+			}
+
+			if err := common.SaveDefaultVersion(common.DefaultVersionFile(), version); err != nil {
+				return err
+			}
+			fmt.Printf("Default kubectl version set to %s\n", version)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&fromCluster, "from-cluster", false, "use the version of the current kubernetes cluster")
+
+	return cmd
+}
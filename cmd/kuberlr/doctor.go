@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/doctor"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+func runDoctorChecks() doctor.Findings {
+	cfg := config.NewCfg()
+	v, err := cfg.Load()
+
+	findings := doctor.Findings{doctor.CheckConfig(err)}
+
+	p, err := loadPolicy(v)
+	findings = append(findings, doctor.CheckPolicy(err))
+	kFinder := kubectlFinder(v, p)
+	findings = append(findings, doctor.CheckLocalDir(kFinder.LocalBinaryPath))
+	for _, path := range kFinder.SystemPaths() {
+		findings = append(findings, doctor.CheckSystemPath(path))
+	}
+	findings = append(findings,
+		doctor.CheckBinaries(kFinder.AllKubectlBinaries(true), v.GetBool("AllowDownload")),
+		doctor.CheckKubectlLink(),
+	)
+
+	kubeAPI := kubehelper.KubeAPI{}
+	version, err := kubeAPI.Version(v.GetInt64("Timeout"))
+	findings = append(findings, doctor.CheckAPIServer(version.String(), err))
+
+	return findings
+}
+
+func printFindingsTable(findings doctor.Findings) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Check", "Status", "Message"})
+	for _, f := range findings {
+		status := text.FgGreen.Sprint(f.Severity)
+		switch f.Severity {
+		case doctor.SeverityWarning:
+			status = text.FgYellow.Sprint(f.Severity)
+		case doctor.SeverityError:
+			status = text.FgRed.Sprint(f.Severity)
+		}
+		msg := f.Message
+		if f.Remediation != "" {
+			msg = fmt.Sprintf("%s\n-> %s", msg, f.Remediation)
+		}
+		t.AppendRow([]interface{}{f.ID, status, msg})
+	}
+	t.Render()
+}
+
+// NewDoctorCmd creates a new `kuberlr doctor` cobra command
+func NewDoctorCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:          "doctor",
+		Short:        "Check whether kuberlr is properly set up",
+		SilenceUsage: true,
+		Example: `
+  Print a human readable report:
+  $ kuberlr doctor
+
+  Print the findings using JSON, useful when checking a fleet of hosts:
+  $ kuberlr doctor --output json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			findings := runDoctorChecks()
+
+			switch output {
+			case "json":
+				data, err := json.MarshalIndent(findings, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			case "text":
+				printFindingsTable(findings)
+			default:
+				return fmt.Errorf("Unknown output format: %s", output)
+			}
+
+			if findings.HasErrors() {
+				return errors.New("Problems found")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "output format, one of: text, json")
+
+	return cmd
+}
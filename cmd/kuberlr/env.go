@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// contextEnv describes the kubectl client used with a context
+type contextEnv struct {
+	Context string            `json:"context"`
+	Version string            `json:"version"`
+	Binary  string            `json:"binary"`
+	Env     map[string]string `json:"env"`
+}
+
+// shellQuote quotes the given value for POSIX shells
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// printShellEnv prints the environment variables as shell exports, sorted
+// by name
+func printShellEnv(env map[string]string) {
+	keys := []string{}
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Printf("export %s=%s\n", k, shellQuote(env[k]))
+	}
+}
+
+// NewEnvCmd creates a new `kuberlr env` cobra command
+func NewEnvCmd() *cobra.Command {
+	var context, output string
+
+	cmd := &cobra.Command{
+		Use:          "env",
+		Short:        "Print the kubectl client used with a context",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Make a deploy script use the kubectl binary needed by the "prod" context:
+  $ eval "$(kuberlr env --context prod)"
+  $ "$KUBECTL" --context prod apply -f manifests/
+
+  Print the version, the path to the binary and the environment as JSON:
+  $ kuberlr env --context prod --output json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "shell" && output != "json" {
+				return fmt.Errorf("Unknown output format: %s", output)
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+			if err := applyGlobalSettings(v); err != nil {
+				return err
+			}
+			if context == "" {
+				context = kubehelper.CurrentContext()
+			}
+
+			version, kubectlBin, err := resolveContext(v, context)
+			if err != nil {
+				return err
+			}
+
+			report := contextEnv{
+				Context: context,
+				Version: version.String(),
+				Binary:  kubectlBin,
+				// KUBERLR_KUBECTL_VERSION is not exported, it would pin
+				// the version used by every kubectl invocation of the
+				// shell, regardless of the context
+				Env: map[string]string{
+					"KUBECTL": kubectlBin,
+				},
+			}
+			if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+				report.Env["KUBECONFIG"] = kubeconfig
+			}
+
+			if output == "json" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			printShellEnv(report.Env)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&context, "context", "", "kubeconfig context to resolve, defaults to the current one")
+	cmd.Flags().StringVarP(&output, "output", "o", "shell", "output format, one of: shell, json")
+
+	return cmd
+}
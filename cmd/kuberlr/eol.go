@@ -0,0 +1,30 @@
+package main
+
+import (
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/viper"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/eol"
+	"github.com/flavio/kuberlr/internal/warnings"
+)
+
+// checkEndOfLife ensures the version of kubernetes in use is still
+// supported upstream
+func checkEndOfLife(v *viper.Viper, version semver.Version, w *warnings.Warner) {
+	mode := v.GetString("EOLCheck")
+	if mode == "off" {
+		return
+	}
+
+	err := eol.Check(version, time.Now())
+	if err == nil {
+		return
+	}
+	if mode == "fail" {
+		klog.Fatal(err)
+	}
+	w.Warn(warnings.EndOfLife, "%v", err)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+// parseMinors parses a comma separated list of minor releases like "1.19,1.20"
+func parseMinors(value string) ([]semver.Version, error) {
+	minors := []semver.Version{}
+	for _, m := range strings.Split(value, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		v, err := semver.ParseTolerant(m)
+		if err != nil {
+			return minors, fmt.Errorf("Invalid minor release %q: %v", m, err)
+		}
+		minors = append(minors, v)
+	}
+	return minors, nil
+}
+
+func matchesMinors(v semver.Version, minors []semver.Version) bool {
+	for _, m := range minors {
+		if m.Major == v.Major && m.Minor == v.Minor {
+			return true
+		}
+	}
+	return false
+}
+
+// NewExecCmd creates a new `kuberlr exec` cobra command
+func NewExecCmd() *cobra.Command {
+	var all bool
+	var minorsFlag string
+
+	cmd := &cobra.Command{
+		Use:          "exec [--all|--minors <list>] -- [kubectl args]",
+		Short:        "Run kubectl with the given arguments using many of the available binaries",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Make sure all the available binaries can be executed:
+  $ kuberlr exec --all -- version --client
+
+  Check whether a plugin works with kubectl 1.19 and 1.20:
+  $ kuberlr exec --minors 1.19,1.20 -- my-plugin --help`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all == (minorsFlag != "") {
+				return errors.New("Either --all or --minors must be given")
+			}
+			minors, err := parseMinors(minorsFlag)
+			if err != nil {
+				return err
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			kFinder, err := newKubectlFinder(v)
+			if err != nil {
+				return err
+			}
+			bins := finder.KubectlBinaries{}
+			for _, b := range kFinder.AllKubectlBinaries(true) {
+				if all || matchesMinors(b.Version, minors) {
+					bins = append(bins, b)
+				}
+			}
+			if len(bins) == 0 {
+				return errors.New("No kubectl binary found")
+			}
+
+			failures := 0
+			t := table.NewWriter()
+			t.SetOutputMirror(os.Stdout)
+			t.AppendHeader(table.Row{"Version", "Binary", "Result", "Output"})
+			for _, b := range bins {
+				out, err := exec.Command(b.Path, args...).CombinedOutput()
+				result := text.FgGreen.Sprint("ok")
+				if err != nil {
+					failures++
+					result = text.FgRed.Sprint(err)
+				}
+				t.AppendRow([]interface{}{b.Version, b.Path, result, strings.TrimSpace(string(out))})
+			}
+			t.Render()
+
+			if failures > 0 {
+				return fmt.Errorf("%d of %d binaries failed", failures, len(bins))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "use all the available binaries")
+	cmd.Flags().StringVar(&minorsFlag, "minors", "", "use the binaries of these minor releases, e.g. 1.19,1.20")
+
+	return cmd
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/bundle"
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+)
+
+// parsePlatform splits a platform in the "os/arch" format
+func parsePlatform(platform string) (string, string, error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("Invalid platform %q, the expected format is os/arch", platform)
+	}
+	return parts[0], parts[1], nil
+}
+
+// installedKubectl returns the kubectl binary of the given version
+// already downloaded by kuberlr, if any
+func installedKubectl(dirs []string, version semver.Version) (string, bool) {
+	for _, dir := range dirs {
+		p := filepath.Join(dir, common.BuildKubectlNameForLocalBin(version))
+		if _, err := os.Stat(p); err == nil {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// NewExportCmd creates a new `kuberlr export` cobra command
+func NewExportCmd() *cobra.Command {
+	var output string
+	var platforms []string
+
+	cmd := &cobra.Command{
+		Use:          "export <version>...",
+		Short:        "Create a bundle of kubectl binaries to be imported on air-gapped hosts",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Bundle kubectl 1.25.4 and 1.26.0 for this host:
+  $ kuberlr export 1.25.4 1.26.0 --output kubectl-bundle.tar.gz
+
+  Bundle the binaries used by Linux and Windows workstations:
+  $ kuberlr export 1.26.0 --platform linux/amd64 --platform windows/amd64
+
+  Install the bundle on the offline host:
+  $ kuberlr import kubectl-bundle.tar.gz`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			versions := semver.Versions{}
+			for _, arg := range args {
+				version, err := semver.ParseTolerant(arg)
+				if err != nil {
+					return fmt.Errorf("Invalid version %s: %v", arg, err)
+				}
+				versions = append(versions, version)
+			}
+			if len(platforms) == 0 {
+				platforms = []string{runtime.GOOS + "/" + runtime.GOARCH}
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+			if err := applyGlobalSettings(v); err != nil {
+				return err
+			}
+
+			d, err := newDownloader(v)
+			if err != nil {
+				return err
+			}
+			// the downloads are not installed on this host
+			d.Journal = nil
+			d.OnCompletion = nil
+
+			staging, err := ioutil.TempDir("", "kuberlr-export")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(staging)
+
+			installedDirs := []string{common.LocalDownloadDir()}
+			if store := newSharedStore(v); store.Enabled() {
+				installedDirs = append(installedDirs, store.Dir())
+			}
+
+			binaries := []bundle.Binary{}
+			for _, platform := range platforms {
+				goos, arch, err := parsePlatform(platform)
+				if err != nil {
+					return err
+				}
+				for _, version := range versions {
+					b := bundle.Binary{Version: version, OS: goos, Arch: arch}
+					if goos == runtime.GOOS && arch == runtime.GOARCH {
+						if p, found := installedKubectl(installedDirs, version); found {
+							b.Path = p
+							binaries = append(binaries, b)
+							continue
+						}
+					}
+
+					b.Path = filepath.Join(staging, filepath.FromSlash(bundle.BinaryPath(b)))
+					if b.SHA256, err = d.FetchKubectlBinary(version, goos, arch, b.Path); err != nil {
+						return err
+					}
+					binaries = append(binaries, b)
+				}
+			}
+
+			if err := bundle.Write(output, binaries); err != nil {
+				return err
+			}
+			fmt.Printf("Exported %d binaries to %s\n", len(binaries), output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "kubectl-bundle.tar.gz", "tarball to create, compressed when ending with .gz or .tgz")
+	cmd.Flags().StringArrayVar(&platforms, "platform", nil, "os/arch of the binaries to bundle, defaults to the one of this host")
+
+	return cmd
+}
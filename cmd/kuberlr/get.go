@@ -1,40 +1,104 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/blang/semver/v4"
 	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
 	"github.com/flavio/kuberlr/internal/downloader"
 	"github.com/spf13/cobra"
 )
 
 // NewGetCmd creates a new `kuberlr get` cobra command
 func NewGetCmd() *cobra.Command {
-	return &cobra.Command{
+	var last int
+
+	cmd := &cobra.Command{
 		Use:          "get [version to get]",
+		Aliases:      []string{"install"},
 		Short:        "Download the kubectl version specified",
-		Args:         cobra.ExactArgs(1),
+		Args:         cobra.MaximumNArgs(1),
 		SilenceUsage: true,
 		Example: `
   Download version 1.20.0. Note well: the patch version is automatically inferred:
   $ kuberlr get 1.20
-  
+
   Versions can be specified with, or without the 'v' prefix:
-  $ kuberlr get v1.19.1`,
+  $ kuberlr get v1.19.1
+
+  Download the latest patch release of the three most recent minor releases:
+  $ kuberlr install --last 3`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			version, err := semver.ParseTolerant(args[0])
+			if (len(args) == 0) == (last == 0) {
+				return errors.New("Either a version or the --last flag must be given")
+			}
+			if last < 0 {
+				return fmt.Errorf("Invalid number of minor releases: %d", last)
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			d, err := newDownloader(v)
 			if err != nil {
-				return fmt.Errorf("Invalid version: %v", err)
+				return err
+			}
+
+			versions := semver.Versions{}
+			if last > 0 {
+				remote, err := d.RemoteVersions()
+				if err != nil {
+					return err
+				}
+				versions = downloader.LatestPatches(remote, last)
+			} else {
+				version, err := semver.ParseTolerant(args[0])
+				if err != nil {
+					return fmt.Errorf("Invalid version: %v", err)
+				}
+				versions = append(versions, version)
+			}
+
+			downloadDir := common.LocalDownloadDir()
+			store := newSharedStore(v)
+			shared := store.Usable()
+			if shared {
+				downloadDir = store.Dir()
 			}
 
-			destination := filepath.Join(
-				common.LocalDownloadDir(),
-				common.BuildKubectlNameForLocalBin(version))
+			for _, version := range versions {
+				destination := filepath.Join(
+					downloadDir,
+					common.BuildKubectlNameForLocalBin(version))
+				if last > 0 {
+					// keep golden images and onboarding scripts idempotent
+					if _, err := os.Stat(destination); err == nil {
+						fmt.Printf("kubectl %s is already installed\n", version)
+						continue
+					}
+				}
 
-			d := downloader.Downloder{}
-			return d.GetKubectlBinary(version, destination)
+				if err := d.GetKubectlBinary(version, destination); err != nil {
+					return err
+				}
+				if shared {
+					if err := store.Share(destination); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
 		},
 	}
+
+	cmd.Flags().IntVar(&last, "last", 0, "download the latest patch release of the N most recent minor releases")
+
+	return cmd
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+const posixContextHook = `__kuberlr_on_context_change() {
+  local ctx
+  ctx="$(command kuberlr current-context 2>/dev/null)"
+  if [ -n "$ctx" ] && [ "$ctx" != "$__KUBERLR_LAST_CONTEXT" ]; then
+    __KUBERLR_LAST_CONTEXT="$ctx"
+    (command kuberlr sync --context "$ctx" >/dev/null 2>&1 &)
+  fi
+}
+`
+
+var contextHooks = map[string]string{
+	"bash": posixContextHook + `case ";${PROMPT_COMMAND};" in
+  *";__kuberlr_on_context_change;"*) ;;
+  *) PROMPT_COMMAND="__kuberlr_on_context_change${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`,
+	"zsh": posixContextHook + `autoload -Uz add-zsh-hook
+add-zsh-hook precmd __kuberlr_on_context_change
+`,
+	"fish": `function __kuberlr_on_context_change --on-event fish_prompt
+  set -l ctx (command kuberlr current-context 2>/dev/null)
+  if test -n "$ctx"; and test "$ctx" != "$__KUBERLR_LAST_CONTEXT"
+    set -g __KUBERLR_LAST_CONTEXT $ctx
+    command kuberlr sync --context $ctx >/dev/null 2>&1 &
+    disown
+  end
+end
+`,
+}
+
+// NewHookCmd creates a new `kuberlr hook` cobra command
+func NewHookCmd() *cobra.Command {
+	var onContextChange bool
+	var shell string
+
+	cmd := &cobra.Command{
+		Use:          "hook",
+		Short:        "Print shell code integrating kuberlr with the shell",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Download the kubectl binary needed by a context as soon as it becomes the
+  current one, add this to ~/.bashrc or ~/.zshrc:
+  $ eval "$(kuberlr hook --on-context-change)"
+
+  Same for fish, add this to ~/.config/fish/config.fish:
+  $ kuberlr hook --on-context-change --shell fish | source`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !onContextChange {
+				return errors.New("No hook requested, use --on-context-change")
+			}
+			if shell == "" {
+				shell = filepath.Base(os.Getenv("SHELL"))
+			}
+
+			hook, found := contextHooks[shell]
+			if !found {
+				return fmt.Errorf("Unsupported shell %q, use one of: bash, zsh, fish", shell)
+			}
+			fmt.Print(hook)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&onContextChange, "on-context-change", false, "prepare the kubectl binary of a context as soon as it becomes the current one")
+	cmd.Flags().StringVar(&shell, "shell", "", "shell to generate the code for (bash, zsh, fish), defaults to $SHELL")
+
+	return cmd
+}
+
+// NewCurrentContextCmd creates a new `kuberlr current-context` cobra command
+func NewCurrentContextCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "current-context",
+		Short:        "Print the current kubeconfig context without contacting the cluster",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context := kubehelper.CurrentContext()
+			if context == "" {
+				return errors.New("No current context")
+			}
+			fmt.Println(context)
+			return nil
+		},
+	}
+}
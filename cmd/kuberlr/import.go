@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/bundle"
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+)
+
+// openBundle returns the directory holding the contents of the given
+// bundle, tarballs are extracted inside of a temporary directory which
+// is removed by the returned function
+func openBundle(path string) (string, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if info.IsDir() {
+		return path, func() {}, nil
+	}
+
+	dir, err := ioutil.TempDir("", "kuberlr-import")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	if err := bundle.Extract(path, dir); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return dir, cleanup, nil
+}
+
+// NewImportCmd creates a new `kuberlr import` cobra command
+func NewImportCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:          "import <directory|tarball>",
+		Short:        "Install the kubectl binaries shipped inside of a directory or a tarball",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Seed an air-gapped host with the binaries copied from a mirror of the
+  upstream release bucket (e.g. v1.20.1/bin/linux/amd64/kubectl):
+  $ kuberlr import /media/usb/kubernetes-release
+
+  Import binaries named after their version and platform, like
+  kubectl-v1.20.1-linux-amd64, from a tarball:
+  $ kuberlr import kubectl-bundle.tar.gz
+
+  Replace the binaries that are already installed:
+  $ kuberlr import --force kubectl-bundle.tar.gz`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+			if err := applyGlobalSettings(v); err != nil {
+				return err
+			}
+
+			dir, cleanup, err := openBundle(args[0])
+			if err != nil {
+				return fmt.Errorf("Cannot open %s: %v", args[0], err)
+			}
+			defer cleanup()
+
+			binaries, err := bundle.Scan(dir)
+			if err != nil {
+				return fmt.Errorf("Cannot read %s: %v", args[0], err)
+			}
+			if len(binaries) == 0 {
+				return fmt.Errorf("No kubectl binary found inside of %s", args[0])
+			}
+
+			d, err := newDownloader(v)
+			if err != nil {
+				return err
+			}
+			downloadDir := common.LocalDownloadDir()
+			store := newSharedStore(v)
+			shared := store.Usable()
+			if shared {
+				downloadDir = store.Dir()
+			}
+
+			failed := 0
+			for _, b := range binaries {
+				if b.OS != runtime.GOOS || b.Arch != runtime.GOARCH {
+					fmt.Printf("Skipping kubectl %s, built for %s/%s\n", b.Version, b.OS, b.Arch)
+					continue
+				}
+				destination := filepath.Join(downloadDir, common.BuildKubectlNameForLocalBin(b.Version))
+				if _, err := os.Stat(destination); err == nil && !force {
+					fmt.Printf("kubectl %s is already installed\n", b.Version)
+					continue
+				}
+
+				if err := d.InstallFile(b.Version, b.Path, b.SHA256, destination); err != nil {
+					fmt.Printf("Cannot import kubectl %s: %v\n", b.Version, err)
+					failed++
+					continue
+				}
+				if shared {
+					if err := store.Share(destination); err != nil {
+						fmt.Printf("Cannot share %s with the other users: %v\n", destination, err)
+					}
+				}
+				fmt.Printf("Imported kubectl %s as %s\n", b.Version, destination)
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d binaries cannot be imported", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "replace the binaries that are already installed")
+
+	return cmd
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/blang/semver/v4"
+	"github.com/spf13/viper"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/krew"
+)
+
+// pluginCandidate returns the first argument that is neither a flag nor the
+// value of a global flag, which could be the name of a kubectl plugin
+func pluginCandidate(args []string) string {
+	i := common.CommandIndex(args)
+	if i < 0 {
+		return ""
+	}
+	return args[i]
+}
+
+// checkKrewPlugin ensures the krew plugin that is about to be invoked, if any,
+// supports the version of kubectl chosen by kuberlr
+func checkKrewPlugin(v *viper.Viper, version semver.Version, args []string) {
+	mode := v.GetString("PluginCheck")
+	if mode == "off" {
+		return
+	}
+
+	name := pluginCandidate(args)
+	if name == "" {
+		return
+	}
+
+	plugin, found, err := krew.FindPlugin(krew.Root(), name)
+	if err != nil {
+		klog.V(1).Info(err)
+		return
+	}
+	if !found {
+		return
+	}
+
+	err = krew.CheckCompatibility(plugin, v.GetStringMapString("PluginKubectlVersions"), version)
+	if err == nil {
+		return
+	}
+	if mode == "fail" {
+		klog.Fatal(err)
+	}
+	klog.Warning(err)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/blang/semver/v4"
+	"github.com/spf13/viper"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/kustomize"
+	"github.com/flavio/kuberlr/internal/warnings"
+)
+
+// checkKustomize warns when kubectl is about to build a kustomization
+// written for a version of kustomize different from the one embedded by
+// the version of kubectl chosen by kuberlr
+func checkKustomize(v *viper.Viper, version semver.Version, args []string, w *warnings.Warner) {
+	if v.GetString("KustomizeCheck") != "warn" {
+		return
+	}
+
+	dir, found := kustomize.Target(args)
+	if !found {
+		return
+	}
+
+	// the version pinned by the project wins over the configuration
+	pinned, found, err := kustomize.PinnedVersion(dir)
+	if err != nil {
+		klog.V(1).Info(err)
+		return
+	}
+	if !found {
+		configured := v.GetString("KustomizeVersion")
+		if configured == "" {
+			return
+		}
+		if pinned, err = semver.ParseTolerant(configured); err != nil {
+			klog.Warningf("Invalid KustomizeVersion %q: %v", configured, err)
+			return
+		}
+	}
+
+	if err := kustomize.Check(version, pinned); err != nil {
+		w.Warn(warnings.Kustomize, "%v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/linker"
+)
+
+// NewLinkCmd creates a new `kuberlr link` cobra command
+func NewLinkCmd() *cobra.Command {
+	var dir, strategy string
+
+	cmd := &cobra.Command{
+		Use:          "link",
+		Short:        "Create a kubectl entry pointing to kuberlr",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Create kubectl next to the kuberlr binary:
+  $ kuberlr link
+
+  Create kubectl inside of ~/bin using a hard link:
+  $ kuberlr link --dir ~/bin --strategy hardlink`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strategy == "" {
+				cfg := config.NewCfg()
+				v, err := cfg.Load()
+				if err != nil {
+					return err
+				}
+				strategy = v.GetString("LinkStrategy")
+			}
+			s, err := linker.ParseStrategy(strategy)
+			if err != nil {
+				return err
+			}
+
+			self, err := os.Executable()
+			if err == nil {
+				self, err = filepath.EvalSymlinks(self)
+			}
+			if err != nil {
+				return fmt.Errorf("Cannot find the location of kuberlr: %v", err)
+			}
+			if dir == "" {
+				dir = filepath.Dir(self)
+			}
+
+			used, err := linker.Create(self, dir, s)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created %s (%s)\n", linker.Destination(dir, used), used)
+			if used == linker.Copy {
+				fmt.Println("Note well: the copy must be refreshed whenever kuberlr is upgraded")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "directory where kubectl is created (default: the directory of kuberlr)")
+	cmd.Flags().StringVar(&strategy, "strategy", "", "one of: auto, symlink, hardlink, copy, shim (default: LinkStrategy from the configuration)")
+
+	return cmd
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/downloader"
+)
+
+// NewListRemoteCmd creates a new `kuberlr list-remote` cobra command
+func NewListRemoteCmd() *cobra.Command {
+	var minor, since string
+	filter := downloader.VersionFilter{}
+
+	cmd := &cobra.Command{
+		Use:          "list-remote",
+		Short:        "List the kubectl versions released upstream",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  List all the stable versions of kubectl, newest first:
+  $ kuberlr list-remote
+
+  Print the latest patch release of kubectl 1.20:
+  $ kuberlr list-remote --minor 1.20 --limit 1
+
+  List all the versions released after 1.21.0, release candidates included:
+  $ kuberlr list-remote --since 1.21.0 --include-prerelease
+
+  Avoid the anonymous rate limits of the GitHub API:
+  $ GITHUB_TOKEN=<token> kuberlr list-remote`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if minor != "" {
+				v, err := semver.ParseTolerant(minor)
+				if err != nil {
+					return fmt.Errorf("Invalid minor version: %v", err)
+				}
+				filter.Minor = &v
+			}
+			if since != "" {
+				v, err := semver.ParseTolerant(since)
+				if err != nil {
+					return fmt.Errorf("Invalid version: %v", err)
+				}
+				filter.Since = &v
+			}
+			if filter.Limit < 0 {
+				return fmt.Errorf("Invalid limit: %d", filter.Limit)
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			d, err := newDownloader(v)
+			if err != nil {
+				return err
+			}
+			versions, err := d.RemoteVersions()
+			if err != nil {
+				return err
+			}
+
+			for _, version := range filter.Apply(versions) {
+				fmt.Printf("v%s\n", version)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&minor, "minor", "", "list only the versions of this minor release (e.g. 1.20)")
+	cmd.Flags().StringVar(&since, "since", "", "list only the versions greater or equal than this one")
+	cmd.Flags().IntVar(&filter.Limit, "limit", 0, "list at most this number of versions, newest first")
+	cmd.Flags().BoolVar(&filter.IncludePrerelease, "include-prerelease", false, "include alpha, beta and release candidate versions")
+
+	return cmd
+}
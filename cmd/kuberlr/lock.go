@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/lockfile"
+)
+
+// NewLockCmd creates a new `kuberlr lock` cobra command
+func NewLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Manage the kubectl binaries listed inside of a lockfile",
+	}
+
+	cmd.AddCommand(NewLockApplyCmd())
+
+	return cmd
+}
+
+// NewLockApplyCmd creates a new `kuberlr lock apply` cobra command
+func NewLockApplyCmd() *cobra.Command {
+	var prune bool
+
+	cmd := &cobra.Command{
+		Use:          "apply <lockfile>",
+		Short:        "Install exactly the kubectl binaries listed inside of a lockfile",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Install the kubectl binaries listed inside of the lockfile of a repository:
+  $ kuberlr lock apply kuberlr.lock
+
+  Remove also the binaries downloaded by kuberlr that are not listed:
+  $ kuberlr lock apply --prune kuberlr.lock`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lock, err := lockfile.Load(args[0])
+			if err != nil {
+				return err
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			d, err := newDownloader(v)
+			if err != nil {
+				return err
+			}
+
+			// the binaries are managed inside of the store where
+			// `kuberlr get` would download them
+			kFinder, err := newKubectlFinder(v)
+			if err != nil {
+				return err
+			}
+			downloadDir := common.LocalDownloadDir()
+			installed, err := kFinder.LocalKubectlBinaries()
+			store := newSharedStore(v)
+			shared := store.Usable()
+			if shared {
+				downloadDir = store.Dir()
+				installed, err = kFinder.SharedKubectlBinaries()
+			}
+			if err != nil {
+				return err
+			}
+
+			plan, err := lock.PlanFor(installed)
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range plan.Install {
+				version, err := entry.ParsedVersion()
+				if err != nil {
+					return err
+				}
+				destination := filepath.Join(
+					downloadDir,
+					common.BuildKubectlNameForLocalBin(version))
+
+				// the download is compared with the lockfile before
+				// being installed
+				entry := entry
+				mismatch := fmt.Errorf(
+					"The checksum of kubectl %s doesn't match the one recorded inside of %s",
+					version, args[0])
+				d.Verify = func(version semver.Version, binary string) error {
+					matches, err := entry.Matches(binary)
+					if err != nil {
+						return err
+					}
+					if !matches {
+						return mismatch
+					}
+					return nil
+				}
+				if err := d.GetKubectlBinary(version, destination); err != nil {
+					return err
+				}
+				// the binary could have been installed by a concurrent
+				// download instead
+				matches, err := entry.Matches(destination)
+				if err != nil {
+					return err
+				}
+				if !matches {
+					if _, err := common.RemoveBinary(destination); err != nil {
+						return fmt.Errorf("Cannot remove %s: %v", destination, err)
+					}
+					return mismatch
+				}
+				if shared {
+					if err := store.Share(destination); err != nil {
+						return err
+					}
+				}
+			}
+
+			if !prune {
+				if len(plan.Unlisted) > 0 {
+					fmt.Printf("%d kubectl binaries are not listed inside of %s, use --prune to remove them\n",
+						len(plan.Unlisted), args[0])
+				}
+				return nil
+			}
+			for _, b := range plan.Unlisted {
+				removed, err := common.RemoveBinary(b.Path)
+				if err != nil {
+					return fmt.Errorf("Cannot remove %s: %v", b.Path, err)
+				}
+				if !removed {
+					fmt.Printf("Skipped kubectl %s (%s): it's in use\n", b.Version, b.Path)
+					continue
+				}
+				fmt.Printf("Removed kubectl %s (%s)\n", b.Version, b.Path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&prune, "prune", false, "remove the binaries downloaded by kuberlr that are not listed inside of the lockfile")
+
+	return cmd
+}
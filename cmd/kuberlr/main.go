@@ -5,22 +5,33 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/flavio/kuberlr/internal/osexec"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"k8s.io/klog"
 
 	"github.com/flavio/kuberlr/cmd/kuberlr/flags"
+	"github.com/flavio/kuberlr/internal/common"
 	"github.com/flavio/kuberlr/internal/config"
-	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+	"github.com/flavio/kuberlr/internal/linker"
+	"github.com/flavio/kuberlr/internal/warnings"
 )
 
 func main() {
+	// shims created by `kuberlr link` ask kuberlr to behave like kubectl
+	shim := len(os.Args) > 1 && os.Args[1] == linker.ShimFlag
+	if shim {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	klog.InitFlags(nil)
 	flag.Parse()
 
 	binary := osexec.TrimExt(filepath.Base(os.Args[0]))
-	if strings.HasSuffix(binary, "kubectl") {
+	if shim || strings.HasSuffix(binary, "kubectl") {
 		kubectlWrapperMode()
 	}
 	nativeMode()
@@ -37,28 +48,145 @@ func newRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		// grab the base filename if the binary file is link
 		Use: filepath.Base(os.Args[0]),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				// commands deal with configuration errors on their own
+				return nil
+			}
+			return applyGlobalSettings(v)
+		},
 	}
 
 	cmd.AddCommand(
 		NewVersionCmd(),
 		NewBinsCmd(),
 		NewGetCmd(),
+		NewDoctorCmd(),
+		NewDefaultCmd(),
+		NewLinkCmd(),
+		NewListRemoteCmd(),
+		NewChangelogCmd(),
+		NewSupportMatrixCmd(),
+		NewRepairCmd(),
+		NewWhyCmd(),
+		NewUpgradeCmd(),
+		NewSyncCmd(),
+		NewHookCmd(),
+		NewCurrentContextCmd(),
+		NewWatchCmd(),
+		NewExecCmd(),
+		NewVerifyCmd(),
+		NewLockCmd(),
+		NewStatsCmd(),
+		NewAliasCmd(),
+		NewPruneCmd(),
+		NewRunCmd(),
+		NewEnvCmd(),
+		NewImportCmd(),
+		NewExportCmd(),
+		NewPrefetchCmd(),
 	)
 
 	flags.RegisterVerboseFlag(cmd.PersistentFlags())
+	cmd.PersistentFlags().BoolVar(&offlineFlag, "offline", false, "never reach the network, use only the local binaries and the cached versions")
 
 	return cmd
 }
 
+// offlineFlag is set by the `--offline` flag of the kuberlr commands
+var offlineFlag bool
+
+// applyGlobalSettings configures the parts of kuberlr that are
+// shared by all its commands
+func applyGlobalSettings(v *viper.Viper) error {
+	if offlineFlag || v.GetBool("Offline") || os.Getenv("KUBERLR_OFFLINE") == "1" {
+		common.SetOffline()
+	}
+	if v.GetBool("PureGoResolver") {
+		common.UsePureGoResolver()
+	}
+	if err := common.SetProxy(v.GetString("ProxyURL"), v.GetString("NoProxy")); err != nil {
+		return err
+	}
+	kubehelper.SetProbeTimeouts(timeoutsFromConfig(v, "ProbeTimeouts", "APITimeout"))
+	return common.SetLocalNamingTemplate(v.GetString("NamingTemplate"))
+}
+
+// markInUse prevents the given binary from being removed while kubectl is
+// running. It returns false when the binary doesn't exist anymore. Only
+// the binaries downloaded by kuberlr can be removed by kuberlr, the
+// other ones are left untouched
+func markInUse(v *viper.Viper, kubectlBin string) bool {
+	dir := filepath.Dir(kubectlBin)
+	if dir != common.LocalDownloadDir() && dir != newSharedStore(v).Dir() {
+		return true
+	}
+
+	lock, err := common.LockInUse(kubectlBin)
+	if err != nil {
+		klog.V(2).Infof("Cannot mark %s as in use: %v", kubectlBin, err)
+		return true
+	}
+	if _, err := os.Stat(kubectlBin); err != nil {
+		lock.Release()
+		return false
+	}
+	if err := lock.KeepAcrossExec(); err != nil {
+		klog.V(2).Infof("Cannot keep %s marked as in use: %v", kubectlBin, err)
+	}
+	return true
+}
+
 func kubectlWrapperMode() {
+	start := time.Now()
 	cfg := config.NewCfg()
 	v, err := cfg.Load()
 	if err != nil {
 		klog.Fatal(err)
 	}
+	if err := applyGlobalSettings(v); err != nil {
+		klog.Fatal(err)
+	}
+
+	if isCompletion(os.Args[1:]) {
+		if kubectlBin, found := completionKubectl(v); found {
+			err = osexec.Exec(kubectlBin, append([]string{kubectlBin}, os.Args[1:]...), os.Environ())
+			klog.Fatal(err)
+		}
+	}
+
+	collectGarbage(v)
+	maybeAutoUpgrade(v)
+	maybeAutoPrune(v)
 
-	kFinder := finder.NewKubectlFinder("", v.GetString("SystemPath"))
-	versioner := finder.NewVersioner(kFinder)
+	kFinder, err := newKubectlFinder(v)
+	if err != nil {
+		klog.Fatal(err)
+	}
+	context := kubehelper.CurrentContext()
+	warner := warnings.NewWarner(
+		context,
+		v.GetDuration("WarningInterval"),
+		v.GetStringSlice("SilencedWarnings"))
+	versioner, err := newVersioner(v, kFinder, warner)
+	if err != nil {
+		klog.Fatal(err)
+	}
+	recordProbes(versioner, context)
+	var versionCacheHit *bool
+	versioner.SetServerCacheObserver(func(hit bool) {
+		versionCacheHit = &hit
+	})
+	if defaultVersion, found, err := common.LoadDefaultVersion(common.DefaultVersionFile()); err != nil {
+		klog.V(1).Infof("Cannot read default kubectl version: %v", err)
+	} else if found {
+		versioner.SetDefaultVersion(defaultVersion)
+	}
+	if err := pinVersion(v, versioner, context); err != nil {
+		klog.Fatal(err)
+	}
 	version, err := versioner.KubectlVersionToUse(v.GetInt64("Timeout"))
 	if err != nil {
 		klog.Fatal(err)
@@ -70,8 +198,46 @@ func kubectlWrapperMode() {
 	if err != nil {
 		klog.Fatal(err)
 	}
+	// the binary might have been removed by a concurrent cleanup before
+	// being marked as in use, look for it again
+	if !markInUse(v, kubectlBin) {
+		kubectlBin, err = versioner.EnsureCompatibleKubectlAvailable(
+			version,
+			v.GetBool("AllowDownload"))
+		if err != nil {
+			klog.Fatal(err)
+		}
+		markInUse(v, kubectlBin)
+	}
+
+	if err := common.RecordUsage(common.UsageFile(), kubectlBin, context, time.Now()); err != nil {
+		klog.V(1).Infof("Cannot record the usage of %s: %v", kubectlBin, err)
+	}
+
+	checkEndOfLife(v, version, warner)
+	checkKrewPlugin(v, version, os.Args[1:])
+	checkKustomize(v, version, os.Args[1:], warner)
+
+	// the default arguments follow the command, the ones given
+	// explicitly take precedence over them
+	defaultArgs, err := common.DefaultArgs(v.GetStringMapStringSlice("DefaultArgs"), version)
+	if err != nil {
+		klog.Fatal(err)
+	}
+	childArgs := append([]string{kubectlBin}, common.InsertDefaultArgs(os.Args[1:], defaultArgs)...)
+
+	invocation := common.Invocation{
+		At:              start,
+		Overhead:        time.Since(start),
+		Downloaded:      downloadedThisRun,
+		VersionCacheHit: versionCacheHit,
+	}
+	if err := common.RecordInvocation(common.ActivityFile(), invocation); err != nil {
+		klog.V(1).Infof("Cannot record the invocation: %v", err)
+	}
 
-	childArgs := append([]string{kubectlBin}, os.Args[1:]...)
-	err = osexec.Exec(kubectlBin, childArgs, os.Environ())
+	// export the version in use, for the plugins that want to know it
+	childEnv := append(os.Environ(), common.ResolvedVersionEnvKey+"="+version.String())
+	err = osexec.Exec(kubectlBin, childArgs, childEnv)
 	klog.Fatal(err)
 }
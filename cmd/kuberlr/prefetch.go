@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/downloader"
+	"github.com/flavio/kuberlr/internal/progress"
+)
+
+// resolvePrefetchArgs turns the arguments of `kuberlr prefetch` into
+// versions. Ranges of minor releases, like "1.27-1.30", and minor releases,
+// like "1.28", are resolved to their most recent patch release
+func resolvePrefetchArgs(d *downloader.Downloder, args []string) (semver.Versions, error) {
+	var remote semver.Versions
+	latestPatch := func(minor semver.Version) (semver.Version, error) {
+		if remote == nil {
+			var err error
+			if remote, err = d.RemoteVersions(); err != nil {
+				return semver.Version{}, err
+			}
+		}
+		v, found := downloader.LatestPatchOf(remote, minor)
+		if !found {
+			return v, fmt.Errorf("There's no release of kubectl %d.%d", minor.Major, minor.Minor)
+		}
+		return v, nil
+	}
+
+	versions := semver.Versions{}
+	seen := map[string]bool{}
+	add := func(v semver.Version) {
+		if !seen[v.String()] {
+			seen[v.String()] = true
+			versions = append(versions, v)
+		}
+	}
+
+	for _, arg := range args {
+		minors, isRange, err := downloader.ParseMinorRange(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !isRange {
+			v, err := semver.ParseTolerant(arg)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid version %s: %v", arg, err)
+			}
+			if strings.Count(strings.TrimPrefix(arg, "v"), ".") > 1 {
+				add(v)
+				continue
+			}
+			minors = semver.Versions{v}
+		}
+
+		for _, minor := range minors {
+			v, err := latestPatch(minor)
+			if err != nil {
+				return nil, err
+			}
+			add(v)
+		}
+	}
+	return versions, nil
+}
+
+// NewPrefetchCmd creates a new `kuberlr prefetch` cobra command
+func NewPrefetchCmd() *cobra.Command {
+	var jobs int
+
+	cmd := &cobra.Command{
+		Use:          "prefetch <version|range>...",
+		Short:        "Download many kubectl versions in parallel",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Download the latest patch release of kubectl 1.27, 1.28, 1.29 and 1.30
+  before a cluster upgrade window:
+  $ kuberlr prefetch 1.27-1.30
+
+  Download specific versions, four at a time:
+  $ kuberlr prefetch --jobs 4 1.26.3 1.27.1 1.28`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jobs < 1 {
+				return fmt.Errorf("Invalid number of jobs: %d", jobs)
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+			if err := applyGlobalSettings(v); err != nil {
+				return err
+			}
+
+			d, err := newDownloader(v)
+			if err != nil {
+				return err
+			}
+			versions, err := resolvePrefetchArgs(d, args)
+			if err != nil {
+				return err
+			}
+
+			downloadDir := common.LocalDownloadDir()
+			store := newSharedStore(v)
+			shared := store.Usable()
+			if shared {
+				downloadDir = store.Dir()
+			}
+
+			// concurrent downloads get a line each, messages are printed
+			// above them
+			pool := progress.NewPool(os.Stderr, d.ProgressStyle)
+			if jobs > 1 && len(versions) > 1 {
+				d.Progress = pool
+			}
+			var mu sync.Mutex
+			onCompletion := d.OnCompletion
+			d.OnCompletion = func(version semver.Version, destination string, elapsed time.Duration) {
+				mu.Lock()
+				defer mu.Unlock()
+				pool.Interrupt(func() {
+					onCompletion(version, destination, elapsed)
+				})
+			}
+			report := func(format string, a ...interface{}) {
+				pool.Interrupt(func() {
+					fmt.Printf(format, a...)
+				})
+			}
+
+			queue := make(chan semver.Version)
+			failed := 0
+			var wg sync.WaitGroup
+			for i := 0; i < jobs; i++ {
+				wg.Add(1)
+				// each worker gets its own HTTP client
+				worker := *d
+				go func() {
+					defer wg.Done()
+					for version := range queue {
+						destination := filepath.Join(downloadDir, common.BuildKubectlNameForLocalBin(version))
+						if _, err := os.Stat(destination); err == nil {
+							report("kubectl %s is already installed\n", version)
+							continue
+						}
+
+						err := worker.GetKubectlBinary(version, destination)
+						if err == nil && shared {
+							err = store.Share(destination)
+						}
+						if err != nil {
+							report("Cannot download kubectl %s: %v\n", version, err)
+							mu.Lock()
+							failed++
+							mu.Unlock()
+							continue
+						}
+						report("Downloaded kubectl %s\n", version)
+					}
+				}()
+			}
+			for _, version := range versions {
+				queue <- version
+			}
+			close(queue)
+			wg.Wait()
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d versions cannot be downloaded", failed, len(versions))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 4, "number of parallel downloads")
+
+	return cmd
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/osexec"
+	"github.com/flavio/kuberlr/internal/progress"
+	"github.com/flavio/kuberlr/internal/prune"
+	"github.com/flavio/kuberlr/internal/upgrade"
+)
+
+func autoPruneStampFile() string {
+	return filepath.Join(common.KuberlrDir(), "last-auto-prune")
+}
+
+// pruneRules returns the cleanup rules defined by the configuration
+func pruneRules(v *viper.Viper) (prune.Rules, error) {
+	rules := prune.Rules{KeepLast: v.GetInt("PruneKeepLast")}
+	if unused := v.GetString("PruneUnusedFor"); unused != "" {
+		d, err := time.ParseDuration(unused)
+		if err != nil {
+			return rules, fmt.Errorf("Invalid PruneUnusedFor value %q: %v", unused, err)
+		}
+		rules.UnusedFor = d
+	}
+	return rules, nil
+}
+
+// maybeAutoPrune starts `kuberlr prune` in the background when the
+// interval defined by the AutoPrune setting elapsed
+func maybeAutoPrune(v *viper.Viper) {
+	interval, err := upgrade.ParseInterval(v.GetString("AutoPrune"))
+	if err != nil {
+		klog.Warningf("AutoPrune: %v", err)
+		return
+	}
+	if rules, err := pruneRules(v); err != nil || !rules.Enabled() {
+		return
+	}
+
+	now := time.Now()
+	if !upgrade.Due(autoPruneStampFile(), interval, now) {
+		return
+	}
+	if err := upgrade.Touch(autoPruneStampFile(), now); err != nil {
+		klog.V(1).Infof("Cannot record automatic cleanup: %v", err)
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		klog.V(1).Infof("Cannot find kuberlr executable: %v", err)
+		return
+	}
+	logFile := filepath.Join(common.KuberlrDir(), "auto-prune.log")
+	if err := osexec.StartDetached(exe, []string{"kuberlr", "prune"}, logFile); err != nil {
+		klog.V(1).Infof("Cannot start automatic cleanup: %v", err)
+	}
+}
+
+// lastUsedFunc returns a function telling when a binary has been used for
+// the last time. The install time is used for the binaries that have never
+// been used, the modification time when that's not known either
+func lastUsedFunc() func(finder.KubectlBinary) time.Time {
+	usage, err := common.LoadUsage(common.UsageFile())
+	if err != nil {
+		klog.V(1).Infof("Cannot read usage of the binaries: %v", err)
+	}
+
+	return func(b finder.KubectlBinary) time.Time {
+		if t, found := usage.LastUsed(b.Path); found {
+			return t
+		}
+		if m, found, err := common.LoadMetadata(b.Path); err == nil && found && !m.InstalledAt.IsZero() {
+			return m.InstalledAt
+		}
+		if info, err := os.Stat(b.Path); err == nil {
+			return info.ModTime()
+		}
+		return time.Time{}
+	}
+}
+
+// pin is a kubectl version configured to be used, together with where it's
+// configured
+type pin struct {
+	version semver.Version
+	source  string
+}
+
+// pins returns the versions of kubectl that are configured to be used: the
+// default version, the versions pinned via the environment, the
+// .kubectl-version file of the current project and ContextVersions, and the
+// versions the scripts generated by `kuberlr alias` are bound to
+func pins(v *viper.Viper) []pin {
+	found := []pin{}
+	add := func(text, source string) {
+		version, err := semver.ParseTolerant(text)
+		if err != nil {
+			klog.V(1).Infof("Ignoring the kubectl version pinned by %s: %v", source, err)
+			return
+		}
+		found = append(found, pin{version: version, source: source})
+	}
+
+	if defaultVersion, ok, err := common.LoadDefaultVersion(common.DefaultVersionFile()); err == nil && ok {
+		found = append(found, pin{version: defaultVersion, source: "default version"})
+	}
+	if text := os.Getenv(common.KubectlVersionEnvKey); text != "" {
+		add(text, common.KubectlVersionEnvKey)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if version, file, ok, err := common.FindProjectVersion(wd); err == nil && ok {
+			found = append(found, pin{version: version, source: file})
+		}
+	}
+	for context, text := range v.GetStringMapString("ContextVersions") {
+		add(text, fmt.Sprintf("context %q via ContextVersions", context))
+	}
+
+	aliases, err := common.LoadAliases(common.AliasesFile())
+	if err != nil {
+		klog.V(1).Infof("Cannot read the aliases: %v", err)
+	}
+	for script, text := range aliases {
+		// the scripts removed by the user don't need their binary
+		if _, err := os.Stat(script); err == nil {
+			add(text, "alias "+script)
+		}
+	}
+	return found
+}
+
+// pinnedVersions returns the versions of kubectl that are configured to be
+// used, see pins
+func pinnedVersions(v *viper.Viper) []semver.Version {
+	pinned := []semver.Version{}
+	for _, p := range pins(v) {
+		pinned = append(pinned, p.version)
+	}
+	return pinned
+}
+
+// pruneCandidates returns the binaries removed by the given cleanup rules,
+// only the binaries downloaded by kuberlr are taken into account
+func pruneCandidates(v *viper.Viper, rules prune.Rules) (finder.KubectlBinaries, error) {
+	rules.Keep = pinnedVersions(v)
+
+	kFinder, err := newKubectlFinder(v)
+	if err != nil {
+		return nil, err
+	}
+	stores := []func() (finder.KubectlBinaries, error){kFinder.LocalKubectlBinaries}
+	if newSharedStore(v).Usable() {
+		stores = append(stores, kFinder.SharedKubectlBinaries)
+	}
+
+	lastUsed := lastUsedFunc()
+	now := time.Now()
+	candidates := finder.KubectlBinaries{}
+	for _, store := range stores {
+		bins, err := store()
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, prune.Plan(bins, rules, lastUsed, now)...)
+	}
+	return candidates, nil
+}
+
+// reclaimableSpace returns how many bytes `kuberlr prune` would free, using
+// the cleanup rules of the configuration or keeping only the newest patch
+// release of each minor release when there are none. The command line to
+// run is returned too
+func reclaimableSpace(v *viper.Viper) (uint64, string) {
+	command := "kuberlr prune"
+	rules, err := pruneRules(v)
+	if err != nil || !rules.Enabled() {
+		rules = prune.Rules{KeepLast: 1}
+		command = "kuberlr prune --keep-last 1"
+	}
+
+	candidates, err := pruneCandidates(v, rules)
+	if err != nil {
+		klog.V(1).Infof("Cannot compute the space used by unneeded binaries: %v", err)
+		return 0, ""
+	}
+	var total uint64
+	for _, b := range candidates {
+		total += uint64(binarySize(b.Path))
+	}
+	return total, command
+}
+
+// binarySize returns the size of the given binary, 0 when it cannot be read
+func binarySize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// NewPruneCmd creates a new `kuberlr prune` cobra command
+func NewPruneCmd() *cobra.Command {
+	var keepLast int
+	var unusedFor time.Duration
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:          "prune",
+		Short:        "Remove the kubectl binaries downloaded by kuberlr that are no longer needed",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Keep only the two newest patch releases of each minor release:
+  $ kuberlr prune --keep-last 2
+
+  Remove the binaries that haven't been used during the last 90 days, but
+  keep the newest patch release of each minor release around for rollbacks:
+  $ kuberlr prune --unused-for 2160h --keep-last 1
+
+  Show what would be removed:
+  $ kuberlr prune --keep-last 2 --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			rules, err := pruneRules(v)
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("keep-last") {
+				rules.KeepLast = keepLast
+			}
+			if cmd.Flags().Changed("unused-for") {
+				rules.UnusedFor = unusedFor
+			}
+			if rules.KeepLast < 0 || rules.UnusedFor < 0 {
+				return fmt.Errorf("Invalid cleanup rules")
+			}
+			if !rules.Enabled() {
+				return fmt.Errorf("No cleanup rule given, use --keep-last or --unused-for")
+			}
+			candidates, err := pruneCandidates(v, rules)
+			if err != nil {
+				return err
+			}
+			var reclaimed int64
+			for _, b := range candidates {
+				size := binarySize(b.Path)
+				if dryRun {
+					fmt.Printf("Would remove kubectl %s (%s, %s)\n", b.Version, b.Path, progress.HumanizeBytes(size))
+					reclaimed += size
+					continue
+				}
+				removed, err := common.RemoveBinary(b.Path)
+				if err != nil {
+					return fmt.Errorf("Cannot remove %s: %v", b.Path, err)
+				}
+				if !removed {
+					fmt.Printf("Skipped kubectl %s (%s): it's in use\n", b.Version, b.Path)
+					continue
+				}
+				fmt.Printf("Removed kubectl %s (%s, %s)\n", b.Version, b.Path, progress.HumanizeBytes(size))
+				reclaimed += size
+			}
+			if dryRun {
+				fmt.Printf("Would reclaim %s\n", progress.HumanizeBytes(reclaimed))
+				return nil
+			}
+			fmt.Printf("Reclaimed %s\n", progress.HumanizeBytes(reclaimed))
+			if err := common.ExpireQuarantine(common.QuarantineDir(), time.Now()); err != nil {
+				return fmt.Errorf("Cannot clean %s: %v", common.QuarantineDir(), err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&keepLast, "keep-last", 0, "keep the newest N patch releases of each minor release (default: PruneKeepLast from the configuration)")
+	cmd.Flags().DurationVar(&unusedFor, "unused-for", 0, "remove the binaries not used for this long (default: PruneUnusedFor from the configuration)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the binaries that would be removed and the space reclaimed")
+
+	return cmd
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+// NewRepairCmd creates a new `kuberlr repair` cobra command
+func NewRepairCmd() *cobra.Command {
+	var dryRun, quarantine bool
+
+	cmd := &cobra.Command{
+		Use:          "repair",
+		Short:        "Fix the kubectl binaries whose filename doesn't match the version they report",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Show what would be done:
+  $ kuberlr repair --dry-run
+
+  Move all the mislabeled binaries out of the way instead of renaming them:
+  $ kuberlr repair --quarantine`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			// only the binaries downloaded by kuberlr are repaired, the
+			// system-wide ones belong to the package manager
+			kFinder, err := newKubectlFinder(v)
+			if err != nil {
+				return err
+			}
+			bins, err := kFinder.LocalKubectlBinaries()
+			if err != nil {
+				return err
+			}
+			sharedBins, err := kFinder.SharedKubectlBinaries()
+			if err != nil {
+				return err
+			}
+			bins = append(bins, sharedBins...)
+
+			repaired := 0
+			for _, b := range bins {
+				repair, needed := finder.PlanRepair(b, common.QuarantineDir(), quarantine)
+				if !needed {
+					continue
+				}
+				repaired++
+
+				action := "rename"
+				if repair.Quarantine {
+					action = "quarantine"
+				}
+				reported := "unknown version"
+				if repair.Reported != nil {
+					reported = "v" + repair.Reported.String()
+				}
+				fmt.Printf("%s %s (reports %s) -> %s\n", action, b.Path, reported, repair.Destination)
+
+				if dryRun {
+					continue
+				}
+				if err := repair.Apply(); err != nil {
+					return fmt.Errorf("Cannot %s %s: %v", action, b.Path, err)
+				}
+			}
+
+			if repaired == 0 {
+				fmt.Println("All binaries report the version advertised by their filename")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print what would be done")
+	cmd.Flags().BoolVar(&quarantine, "quarantine", false, "move mislabeled binaries to the quarantine directory instead of renaming them")
+
+	return cmd
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// isolatedEnvKeys are the environment variables inherited by kubectl
+// when running in isolation, everything else is dropped
+var isolatedEnvKeys = []string{
+	"PATH", "TERM", "LANG", "LC_ALL", "TZ",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+	// required by Windows programs
+	"SYSTEMROOT", "SystemRoot", "COMSPEC", "PATHEXT", "TEMP", "TMP",
+}
+
+// isolatedEnv returns the minimal environment given to kubectl, the home
+// directory is replaced to avoid reading the caches of kubectl
+func isolatedEnv(home, kubeconfig string, version semver.Version) []string {
+	env := []string{}
+	for _, key := range isolatedEnvKeys {
+		if value, found := os.LookupEnv(key); found {
+			env = append(env, key+"="+value)
+		}
+	}
+	return append(env,
+		"HOME="+home,
+		"USERPROFILE="+home,
+		"KUBECONFIG="+kubeconfig,
+		common.ResolvedVersionEnvKey+"="+version.String(),
+	)
+}
+
+// NewRunCmd creates a new `kuberlr run` cobra command
+func NewRunCmd() *cobra.Command {
+	var context, kubeconfig, versionFlag string
+
+	cmd := &cobra.Command{
+		Use:          "run --version <version> [--context <context>] -- [kubectl args]",
+		Short:        "Run kubectl inside of a temporary and minimal environment",
+		Args:         cobra.ArbitraryArgs,
+		SilenceUsage: true,
+		Example: `
+  Run kubectl 1.26.0 against the staging cluster, nothing but the staging
+  context of the kubeconfig is visible to kubectl:
+  $ kuberlr run --context staging --version 1.26 -- get pods
+
+  Use a kubeconfig different from the default one:
+  $ kuberlr run --kubeconfig ./support.yaml --version 1.25.4 -- cluster-info`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if versionFlag == "" {
+				return errors.New("The --version flag is required")
+			}
+			version, err := semver.ParseTolerant(versionFlag)
+			if err != nil {
+				return fmt.Errorf("Invalid version: %v", err)
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			minimal, err := kubehelper.MinimalKubeconfig(kubeconfig, context)
+			if err != nil {
+				return fmt.Errorf("Cannot build the kubeconfig: %v", err)
+			}
+			kubectlBin, err := ensureKubectlVersion(v, version)
+			if err != nil {
+				return err
+			}
+
+			home, err := ioutil.TempDir("", "kuberlr-run")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(home)
+			isolatedKubeconfig := filepath.Join(home, "kubeconfig")
+			if err := ioutil.WriteFile(isolatedKubeconfig, minimal, 0600); err != nil {
+				return err
+			}
+
+			klog.V(2).Infof("Running %s with kubeconfig %s", kubectlBin, isolatedKubeconfig)
+			child := exec.Command(kubectlBin, args...)
+			child.Env = isolatedEnv(home, isolatedKubeconfig, version)
+			child.Stdin = os.Stdin
+			child.Stdout = os.Stdout
+			child.Stderr = os.Stderr
+			err = child.Run()
+
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				// kubectl already reported the error, only its exit
+				// code must be propagated
+				os.RemoveAll(home)
+				os.Exit(exitErr.ExitCode())
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&versionFlag, "version", "", "version of kubectl to run, e.g. 1.26 or 1.26.3")
+	cmd.Flags().StringVar(&context, "context", "", "context to use, the only one visible to kubectl")
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "kubeconfig to read the context from")
+
+	return cmd
+}
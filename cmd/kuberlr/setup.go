@@ -0,0 +1,515 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/downloader"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+	"github.com/flavio/kuberlr/internal/notify"
+	"github.com/flavio/kuberlr/internal/policy"
+	"github.com/flavio/kuberlr/internal/progress"
+	"github.com/flavio/kuberlr/internal/warnings"
+)
+
+// newSharedStore returns the shared store defined by the configuration
+func newSharedStore(v *viper.Viper) common.SharedStore {
+	return common.SharedStore{
+		Path:  v.GetString("SharedStore"),
+		Group: v.GetString("SharedGroup"),
+	}
+}
+
+// loadedPolicy caches the policy, which is needed by different
+// parts of kuberlr
+var loadedPolicy struct {
+	done   bool
+	policy *policy.Policy
+	err    error
+}
+
+// policyFetcher returns the Fetcher of the policy published at PolicyURL,
+// nil when no policy is configured
+func policyFetcher(v *viper.Viper) *policy.Fetcher {
+	url := v.GetString("PolicyURL")
+	if url == "" {
+		return nil
+	}
+	fetcher := &policy.Fetcher{
+		URL:       url,
+		PublicKey: v.GetString("PolicyPublicKey"),
+		CacheFile: filepath.Join(common.KuberlrDir(), "policy.json"),
+		MaxAge:    policy.DefaultMaxAge,
+		Timeouts:  timeoutsFromConfig(v, "DownloadTimeouts", "DownloadTimeout"),
+	}
+	if caFile := v.GetString("DownloadCAFile"); caFile != "" {
+		rootCAs, err := common.LoadCABundle(caFile)
+		if err != nil {
+			klog.Warningf("Invalid DownloadCAFile: %v", err)
+		}
+		fetcher.RootCAs = rootCAs
+	}
+	return fetcher
+}
+
+// loadPolicy returns the policy published at PolicyURL, nil is returned
+// when no policy is configured or when it cannot be obtained. A signed
+// policy that cannot be obtained is an error: it's meant to be enforced
+func loadPolicy(v *viper.Viper) (*policy.Policy, error) {
+	if loadedPolicy.done {
+		return loadedPolicy.policy, loadedPolicy.err
+	}
+	loadedPolicy.done = true
+
+	fetcher := policyFetcher(v)
+	if fetcher == nil {
+		return nil, nil
+	}
+	p, err := fetcher.Get(time.Now())
+	if err != nil {
+		if fetcher.PublicKey != "" {
+			loadedPolicy.err = fmt.Errorf("Cannot load the signed policy from %s: %v", fetcher.URL, err)
+			return nil, loadedPolicy.err
+		}
+		klog.Warningf("Cannot load policy from %s: %v", fetcher.URL, err)
+		return nil, nil
+	}
+	loadedPolicy.policy = p
+	return p, nil
+}
+
+// cachedPolicy returns the policy saved on disk by the previous
+// invocations of kuberlr, nil when there's none. It's meant for the code
+// paths that must never wait for the network, like shell completion
+func cachedPolicy(v *viper.Viper) *policy.Policy {
+	fetcher := policyFetcher(v)
+	if fetcher == nil {
+		return nil
+	}
+	p, err := fetcher.Cached()
+	if err != nil {
+		klog.V(1).Infof("Not enforcing the policy, the cached one cannot be used: %v", err)
+		return nil
+	}
+	return p
+}
+
+// timeoutsFromConfig returns the timeouts defined inside of the given
+// table of the configuration. The overall timeout is read from the given
+// top level option when the table doesn't set it
+func timeoutsFromConfig(v *viper.Viper, table, overall string) common.Timeouts {
+	t := common.Timeouts{
+		Dial:           v.GetDuration(table + ".Dial"),
+		TLSHandshake:   v.GetDuration(table + ".TLSHandshake"),
+		ResponseHeader: v.GetDuration(table + ".ResponseHeader"),
+		Overall:        v.GetDuration(table + ".Overall"),
+		Stall:          v.GetDuration(table + ".Stall"),
+	}
+	if t.Overall == 0 {
+		t.Overall = v.GetDuration(overall)
+	}
+	return t
+}
+
+// probeTimeout returns the time given to the API server to report its
+// version, the legacy Timeout option is used when no other is set
+func probeTimeout(v *viper.Viper) time.Duration {
+	if t := timeoutsFromConfig(v, "ProbeTimeouts", "APITimeout"); t.Overall != 0 {
+		return t.Overall
+	}
+	return time.Duration(v.GetInt64("Timeout")) * time.Second
+}
+
+// systemPaths returns the directories holding the system-wide kubectl
+// binaries. SystemPath is either a list of directories or a single string,
+// which can hold many directories separated like inside of $PATH
+func systemPaths(v *viper.Viper) []string {
+	var paths []string
+	if text, ok := v.Get("SystemPath").(string); ok {
+		paths = filepath.SplitList(text)
+	} else {
+		paths = v.GetStringSlice("SystemPath")
+	}
+	if len(paths) == 0 {
+		return []string{common.SystemPath}
+	}
+	return paths
+}
+
+// newKubectlFinder returns a KubectlFinder configured according
+// to the configuration of kuberlr
+func newKubectlFinder(v *viper.Viper) (*finder.KubectlFinder, error) {
+	p, err := loadPolicy(v)
+	if err != nil {
+		return nil, err
+	}
+	return kubectlFinder(v, p), nil
+}
+
+// kubectlFinder returns a KubectlFinder configured according to the
+// configuration of kuberlr, enforcing the given policy
+func kubectlFinder(v *viper.Viper, p *policy.Policy) *finder.KubectlFinder {
+	paths := systemPaths(v)
+	kFinder := finder.NewKubectlFinder("", paths[0])
+	kFinder.ExtraSysBinaryPaths = paths[1:]
+	kFinder.PreferSystem = v.GetBool("PreferSystem")
+	match, err := finder.ParseVersionMatch(v.GetString("VersionMatch"))
+	if err != nil {
+		klog.Warningf("%v, using %s", err, finder.MatchSkew)
+		match = finder.MatchSkew
+	}
+	kFinder.Match = match
+	kFinder.Policy = p
+	if store := newSharedStore(v); store.Enabled() {
+		kFinder.SharedBinaryPath = store.Dir()
+	}
+
+	return kFinder
+}
+
+// mirrorsFromConfig returns the fallback mirrors defined by the
+// configuration. Each of them is either the URL of the mirror or a table
+// holding its URL and its proxy
+func mirrorsFromConfig(v *viper.Viper) ([]downloader.Mirror, error) {
+	mirrors := []downloader.Mirror{}
+	entries, ok := v.Get("DownloadMirrors").([]interface{})
+	if !ok {
+		for _, u := range v.GetStringSlice("DownloadMirrors") {
+			mirrors = append(mirrors, downloader.Mirror{URL: u})
+		}
+		return mirrors, nil
+	}
+
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case string:
+			mirrors = append(mirrors, downloader.Mirror{URL: e})
+		case map[string]interface{}:
+			m := downloader.Mirror{}
+			for key, value := range e {
+				switch strings.ToLower(key) {
+				case "url":
+					m.URL = fmt.Sprint(value)
+				case "proxy":
+					m.Proxy = fmt.Sprint(value)
+				default:
+					return nil, fmt.Errorf("Invalid DownloadMirrors: unknown field %q", key)
+				}
+			}
+			if m.URL == "" {
+				return nil, fmt.Errorf("Invalid DownloadMirrors: the URL of a mirror is missing")
+			}
+			if m.Proxy != "" {
+				if err := downloader.CheckProxy(m.Proxy); err != nil {
+					return nil, fmt.Errorf("Invalid DownloadMirrors: %v", err)
+				}
+			}
+			mirrors = append(mirrors, m)
+		default:
+			return nil, fmt.Errorf("Invalid DownloadMirrors: unexpected entry %v", entry)
+		}
+	}
+	return mirrors, nil
+}
+
+// newDownloader returns a Downloder configured according
+// to the configuration of kuberlr
+func newDownloader(v *viper.Viper) (*downloader.Downloder, error) {
+	// CI jobs can pick a style without touching the configuration
+	styleName := v.GetString("ProgressStyle")
+	if env := os.Getenv(progress.StyleEnvKey); env != "" {
+		styleName = env
+	}
+	style, err := progress.ParseStyle(styleName)
+	if err != nil {
+		return nil, err
+	}
+
+	token := v.GetString("GitHubToken")
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+
+	notifier, err := downloadNotifier(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var maxRate int64
+	if rate := v.GetString("MaxDownloadRate"); rate != "" {
+		maxRate, err = common.ParseBytes(strings.TrimSuffix(rate, "/s"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid MaxDownloadRate: %v", err)
+		}
+	}
+
+	authText := v.GetString("DownloadAuthHeader")
+	if authText == "" {
+		authText = os.Getenv("KUBERLR_DOWNLOAD_AUTH_HEADER")
+	}
+	var authHeader http.Header
+	if authText != "" {
+		authHeader, err = downloader.ParseAuthHeader(authText)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid DownloadAuthHeader: %v", err)
+		}
+	}
+	netrc := ""
+	if v.GetBool("DownloadNetrc") {
+		netrc = downloader.DefaultNetrc()
+	}
+
+	var rootCAs *x509.CertPool
+	if caFile := v.GetString("DownloadCAFile"); caFile != "" {
+		rootCAs, err = common.LoadCABundle(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid DownloadCAFile: %v", err)
+		}
+	}
+
+	// the mirror mandated by the policy wins over the configured mirrors,
+	// URL template and registry
+	mirror := v.GetString("DownloadURL")
+	proxy := v.GetString("DownloadProxy")
+	fallbacks, err := mirrorsFromConfig(v)
+	if err != nil {
+		return nil, err
+	}
+	urlTemplate := v.GetString("DownloadURLTemplate")
+	registry := v.GetString("OCIRepository")
+	p, err := loadPolicy(v)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil && p.Mirror != "" {
+		mirror = p.Mirror
+		proxy = ""
+		fallbacks = nil
+		urlTemplate = ""
+		registry = ""
+	}
+	if urlTemplate != "" {
+		if err := downloader.CheckURLTemplate(urlTemplate); err != nil {
+			return nil, err
+		}
+	}
+	if proxy != "" {
+		if err := downloader.CheckProxy(proxy); err != nil {
+			return nil, fmt.Errorf("Invalid DownloadProxy: %v", err)
+		}
+	}
+
+	return &downloader.Downloder{
+		ProgressStyle:       style,
+		GitHubToken:         token,
+		ReleasesCache:       filepath.Join(common.KuberlrDir(), "github-releases.json"),
+		StableCache:         filepath.Join(common.KuberlrDir(), "stable-version.json"),
+		StableCacheTTL:      v.GetDuration("StableVersionCacheTTL"),
+		Context:             kubehelper.CurrentContext(),
+		Journal:             &downloader.Journal{Path: filepath.Join(common.KuberlrDir(), "install-journal.json")},
+		SourcePlugin:        v.GetString("SourcePlugin"),
+		BaseURL:             mirror,
+		Proxy:               proxy,
+		FallbackMirrors:     fallbacks,
+		URLTemplate:         urlTemplate,
+		Registry:            registry,
+		QuarantineDir:       common.QuarantineDir(),
+		Timeouts:            timeoutsFromConfig(v, "DownloadTimeouts", "DownloadTimeout"),
+		RootCAs:             rootCAs,
+		AuthHeader:          authHeader,
+		Netrc:               netrc,
+		VerifySignatures:    v.GetBool("VerifySignatures"),
+		Cosign:              v.GetString("CosignPath"),
+		SanityCheck:         v.GetBool("SanityCheck"),
+		Retries:             v.GetInt("DownloadRetries"),
+		RetryDelay:          v.GetDuration("DownloadRetryDelay"),
+		MaxRate:             maxRate,
+		PostDownloadCommand: v.GetStringSlice("PostDownloadCommand"),
+		ReclaimableSpace: func() (uint64, string) {
+			return reclaimableSpace(v)
+		},
+		OnCompletion: func(version semver.Version, destination string, elapsed time.Duration) {
+			recordDownload(version, destination, elapsed)
+			if notifier != nil {
+				notifier(version, elapsed)
+			}
+		},
+	}, nil
+}
+
+// downloadedThisRun is true when the current run of kuberlr
+// downloaded a kubectl binary
+var downloadedThisRun bool
+
+// recordDownload adds the download of the given binary to the activity log
+func recordDownload(version semver.Version, destination string, elapsed time.Duration) {
+	downloadedThisRun = true
+
+	d := common.Download{
+		At:       time.Now(),
+		Version:  version.String(),
+		Duration: elapsed,
+	}
+	if info, err := os.Stat(destination); err == nil {
+		d.Size = info.Size()
+	}
+	if err := common.RecordDownload(common.ActivityFile(), d); err != nil {
+		klog.V(1).Infof("Cannot record the download of %s: %v", destination, err)
+	}
+}
+
+// downloadNotifier returns the function showing a desktop notification
+// when a download took longer than DownloadNotification. Notifications
+// are shown only to interactive sessions
+func downloadNotifier(v *viper.Viper) (func(semver.Version, time.Duration), error) {
+	setting := v.GetString("DownloadNotification")
+	if setting == "off" || setting == "" {
+		return nil, nil
+	}
+	threshold, err := time.ParseDuration(setting)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid DownloadNotification value %q: %v", setting, err)
+	}
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		return nil, nil
+	}
+
+	return func(version semver.Version, elapsed time.Duration) {
+		if elapsed < threshold {
+			return
+		}
+		message := fmt.Sprintf("kubectl %s is ready, the download took %s", version, elapsed.Round(time.Second))
+		if err := notify.Desktop("kuberlr", message); err != nil {
+			klog.V(1).Info(err)
+		}
+	}, nil
+}
+
+// newVersioner returns a Versioner configured according
+// to the configuration of kuberlr
+func newVersioner(v *viper.Viper, kFinder *finder.KubectlFinder, w *warnings.Warner) (*finder.Versioner, error) {
+	d, err := newDownloader(v)
+	if err != nil {
+		return nil, err
+	}
+	versioner := finder.NewVersioner(kFinder, d, w)
+	versioner.SetSharedStore(newSharedStore(v))
+	versioner.SetPolicy(kFinder.Policy)
+	versioner.SetServerVersionCache(common.ServerVersionsFile(), v.GetDuration("ServerVersionCacheTTL"))
+	fallback, silent, err := fallbackFromConfig(v)
+	if err != nil {
+		return nil, err
+	}
+	versioner.SetFallback(fallback)
+	versioner.SetSilentFallback(silent)
+
+	return versioner, nil
+}
+
+// fallbackFromConfig returns the fallbacks attempted when the version of
+// the API server cannot be found. The Fallback list takes precedence over
+// the FallbackPreference switch. The boolean is true when the user asked
+// to silently use the newest binary downloaded by kuberlr
+func fallbackFromConfig(v *viper.Viper) ([]string, bool, error) {
+	if names := v.GetStringSlice("Fallback"); len(names) > 0 {
+		fallback, err := finder.ParseFallback(names)
+		return fallback, false, err
+	}
+	preference := strings.ToLower(v.GetString("FallbackPreference"))
+	fallback, err := finder.FallbackFor(preference)
+	return fallback, preference == finder.FallbackNewestLocal, err
+}
+
+// contextVersion returns the version of kubectl pinned to the given
+// context by the ContextVersions table of the configuration. Context names
+// are compared ignoring the case, like the keys of the configuration
+func contextVersion(v *viper.Viper, context string) (semver.Version, bool, error) {
+	for name, text := range v.GetStringMapString("ContextVersions") {
+		if !strings.EqualFold(name, context) {
+			continue
+		}
+		version, err := semver.ParseTolerant(text)
+		if err != nil {
+			return semver.Version{}, false, fmt.Errorf("Invalid kubectl version pinned to context %q: %v", context, err)
+		}
+		return version, true, nil
+	}
+	return semver.Version{}, false, nil
+}
+
+// pinVersion makes the versioner use the version of kubectl pinned by, in
+// order of precedence, the KUBERLR_KUBECTL_VERSION environment variable, the
+// .kubectl-version file of the current project and the ContextVersions table
+// for the given context
+func pinVersion(v *viper.Viper, versioner *finder.Versioner, context string) error {
+	if text := os.Getenv(common.KubectlVersionEnvKey); text != "" {
+		version, err := semver.ParseTolerant(text)
+		if err != nil {
+			return fmt.Errorf("Invalid %s: %v", common.KubectlVersionEnvKey, err)
+		}
+		klog.V(2).Infof("Using kubectl %s required by %s", version, common.KubectlVersionEnvKey)
+		versioner.SetPinnedVersion(version)
+		return nil
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		// a file that cannot be read doesn't prevent kubectl from
+		// being used, it's ignored
+		version, path, found, err := common.FindProjectVersion(wd)
+		if err != nil {
+			klog.Warningf("Ignoring %s: %v", path, err)
+		} else if found {
+			klog.V(2).Infof("Using kubectl %s required by %s", version, path)
+			versioner.SetPinnedVersion(version)
+			return nil
+		}
+	}
+
+	version, found, err := contextVersion(v, context)
+	if err != nil {
+		return err
+	}
+	if found {
+		versioner.SetPinnedVersion(version)
+	}
+	return nil
+}
+
+// recordProbes makes the versioner record the latency and the outcome of
+// the requests made to the API server of the given context
+func recordProbes(versioner *finder.Versioner, context string) {
+	versioner.SetProbeObserver(func(latency time.Duration, err error) {
+		if err := common.RecordProbe(common.ProbesFile(), context, latency, err != nil, time.Now()); err != nil {
+			klog.V(1).Infof("Cannot record probe of context %q: %v", context, err)
+		}
+	})
+}
+
+// collectGarbage removes the temporary files left behind by the runs
+// of kuberlr that have been interrupted
+func collectGarbage(v *viper.Viper) {
+	dirs := []string{common.KuberlrDir(), common.LocalDownloadDir()}
+	if store := newSharedStore(v); store.Enabled() {
+		dirs = append(dirs, store.Dir())
+	}
+
+	removed, err := common.NewGarbageCollector(dirs...).Run(time.Now())
+	if err != nil {
+		klog.V(1).Infof("Cannot remove stale temporary files: %v", err)
+	}
+	for _, f := range removed {
+		klog.V(2).Infof("Removed stale temporary file %s", f)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/progress"
+)
+
+// statsReport holds the statistics printed by `kuberlr stats`
+type statsReport struct {
+	Probes []common.ProbeSummary   `json:"probes"`
+	Cache  *common.ActivitySummary `json:"cache,omitempty"`
+}
+
+// printCacheTable prints how often the binaries needed by kubectl were
+// already available, the time added to the kubectl calls and the
+// downloads made each month
+func printCacheTable(s common.ActivitySummary) {
+	fmt.Printf("%s\n", text.FgGreen.Sprint("Cache effectiveness"))
+	if s.Invocations == 0 && len(s.Downloads) == 0 {
+		fmt.Println("No activity recorded yet.")
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	versionHitRate := "n/a"
+	if s.VersionLookups > 0 {
+		versionHitRate = fmt.Sprintf("%.0f%%", s.VersionHitRate*100)
+	}
+	t.AppendHeader(table.Row{"kubectl calls", "Hit rate", "Version cache hit rate", "Avg added latency", "Avg added latency (hits)"})
+	t.AppendRow([]interface{}{
+		s.Invocations,
+		fmt.Sprintf("%.0f%%", s.HitRate*100),
+		versionHitRate,
+		s.AvgOverhead.Round(time.Millisecond),
+		s.AvgHitOverhead.Round(time.Millisecond),
+	})
+	t.Render()
+
+	if len(s.Downloads) == 0 {
+		return
+	}
+	t = table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Month", "Downloads", "Size"})
+	for _, p := range s.Downloads {
+		t.AppendRow([]interface{}{p.Month, p.Count, progress.HumanizeBytes(p.Size)})
+	}
+	t.Render()
+}
+
+// printProbeTable prints the recent probes of each context. The contexts
+// whose probes fail, or get close to the timeout, are highlighted
+func printProbeTable(summaries []common.ProbeSummary, timeout time.Duration) {
+	fmt.Printf("%s\n", text.FgGreen.Sprint("API server probes"))
+	if len(summaries) == 0 {
+		fmt.Println("No probes recorded yet.")
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Context", "Probes", "Avg latency", "Max latency", "Failures", "Last probe"})
+	for _, s := range summaries {
+		maxLatency := s.MaxLatency.Round(time.Millisecond).String()
+		if s.MaxLatency > timeout/2 {
+			maxLatency = text.FgYellow.Sprint(maxLatency)
+		}
+		failures := fmt.Sprintf("%.0f%%", s.FailureRate*100)
+		if s.FailureRate > 0 {
+			failures = text.FgRed.Sprint(failures)
+		}
+		t.AppendRow([]interface{}{
+			s.Context,
+			s.Samples,
+			s.AvgLatency.Round(time.Millisecond),
+			maxLatency,
+			failures,
+			s.LastProbe.Local().Format("2006-01-02 15:04"),
+		})
+	}
+	t.Render()
+}
+
+// NewStatsCmd creates a new `kuberlr stats` cobra command
+func NewStatsCmd() *cobra.Command {
+	var probes, cache bool
+	var output string
+
+	cmd := &cobra.Command{
+		Use:          "stats",
+		Short:        "Print statistics about the recent invocations of kuberlr",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Print the latency and the failure rate of the requests made to find the
+  version of the API server of each context:
+  $ kuberlr stats --probes
+
+  Print how often the right kubectl binary was already available, the time
+  added to each kubectl call and the downloads made each month:
+  $ kuberlr stats --cache
+
+  Print all the statistics using JSON:
+  $ kuberlr stats --output json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "text" && output != "json" {
+				return fmt.Errorf("Unknown output format: %s", output)
+			}
+
+			// all the statistics are printed when none is picked
+			if !probes && !cache {
+				probes = true
+				cache = true
+			}
+
+			timeout := 5 * time.Second
+			cfg := config.NewCfg()
+			if v, err := cfg.Load(); err == nil {
+				timeout = probeTimeout(v)
+			}
+
+			report := statsReport{}
+			if probes {
+				recorded, err := common.LoadProbes(common.ProbesFile())
+				if err != nil {
+					return fmt.Errorf("Cannot read probes: %v", err)
+				}
+				report.Probes = recorded.Summaries()
+			}
+			if cache {
+				activity, err := common.LoadActivity(common.ActivityFile())
+				if err != nil {
+					return fmt.Errorf("Cannot read activity: %v", err)
+				}
+				summary := activity.Summary()
+				report.Cache = &summary
+			}
+
+			if output == "json" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			if probes {
+				printProbeTable(report.Probes, timeout)
+			}
+			if probes && cache {
+				fmt.Println()
+			}
+			if cache {
+				printCacheTable(*report.Cache)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&probes, "probes", false, "print the latency and the failure rate of the API server probes of each context")
+	cmd.Flags().BoolVar(&cache, "cache", false, "print the hit rate of the binaries cache, the latency added to kubectl and the downloads")
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "output format, one of: text, json")
+
+	return cmd
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/eol"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// supportEntry describes how a kubernetes context is served by kuberlr
+type supportEntry struct {
+	Context       string `json:"context"`
+	ServerVersion string `json:"serverVersion,omitempty"`
+	Kubectl       string `json:"kubectl,omitempty"`
+	KubectlPath   string `json:"kubectlPath,omitempty"`
+	WithinSkew    bool   `json:"withinSkew"`
+	EndOfLife     string `json:"endOfLife,omitempty"`
+	Expired       bool   `json:"expired"`
+	Error         string `json:"error,omitempty"`
+}
+
+func buildSupportEntry(context string, timeout int64, kFinder *finder.KubectlFinder) supportEntry {
+	entry := supportEntry{Context: context}
+
+	kubeAPI := kubehelper.KubeAPI{Context: context}
+	server, err := kubeAPI.Version(timeout)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.ServerVersion = server.String()
+	if date, found := eol.EndOfLife(server); found {
+		entry.EndOfLife = date.Format("2006-01-02")
+	}
+	entry.Expired = eol.Check(server, time.Now()) != nil
+
+	kubectl := server
+	if b, err := kFinder.FindCompatibleKubectl(server); err == nil {
+		kubectl = b.Version
+		entry.KubectlPath = b.Path
+	}
+	entry.Kubectl = kubectl.String()
+	entry.WithinSkew = finder.WithinSkewPolicy(kubectl, server)
+
+	return entry
+}
+
+func printSupportTable(entries []supportEntry) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Context", "Cluster", "kubectl", "Skew", "End of life"})
+	for _, e := range entries {
+		if e.Error != "" {
+			t.AppendRow([]interface{}{e.Context, text.FgRed.Sprint("unreachable"), "", "", ""})
+			continue
+		}
+
+		kubectl := e.Kubectl
+		if e.KubectlPath == "" {
+			kubectl += " (to download)"
+		}
+		skew := text.FgGreen.Sprint("ok")
+		if !e.WithinSkew {
+			skew = text.FgRed.Sprint("unsupported")
+		}
+		eolDate := e.EndOfLife
+		if e.Expired {
+			eolDate = text.FgRed.Sprint(strings.TrimSpace(eolDate + " expired"))
+		}
+		t.AppendRow([]interface{}{e.Context, e.ServerVersion, kubectl, skew, eolDate})
+	}
+	t.Render()
+}
+
+// NewSupportMatrixCmd creates a new `kuberlr support-matrix` cobra command
+func NewSupportMatrixCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:          "support-matrix",
+		Short:        "Report the kubectl version used with each context and whether it's supported",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Print a human readable report covering all the contexts of the kubeconfig:
+  $ kuberlr support-matrix
+
+  Print the report using JSON:
+  $ kuberlr support-matrix --output json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "text" && output != "json" {
+				return fmt.Errorf("Unknown output format: %s", output)
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			contexts, err := kubehelper.Contexts()
+			if err != nil {
+				return fmt.Errorf("Cannot read kubeconfig: %v", err)
+			}
+
+			kFinder, err := newKubectlFinder(v)
+			if err != nil {
+				return err
+			}
+			entries := []supportEntry{}
+			for _, context := range contexts {
+				entries = append(entries, buildSupportEntry(context, v.GetInt64("Timeout"), kFinder))
+			}
+
+			if output == "json" {
+				data, err := json.MarshalIndent(entries, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			printSupportTable(entries)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "output format, one of: text, json")
+
+	return cmd
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// syncContext makes sure the kubectl binary needed by the given context is
+// available, the path to the binary is returned
+func syncContext(v *viper.Viper, context string) (string, error) {
+	_, kubectlBin, err := resolveContext(v, context)
+	return kubectlBin, err
+}
+
+// resolveContext returns the path to the kubectl binary used with the given
+// context, which is downloaded when missing, and the version of the binary.
+// The version can differ from the one of the API server, e.g. when a binary
+// within the version skew policy is reused
+func resolveContext(v *viper.Viper, context string) (semver.Version, string, error) {
+	d, err := newDownloader(v)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+	d.Context = context
+
+	kFinder, err := newKubectlFinder(v)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+	versioner := finder.NewVersioner(kFinder, d, nil)
+	versioner.SetSharedStore(newSharedStore(v))
+	versioner.SetPolicy(kFinder.Policy)
+	versioner.SetContext(context)
+	versioner.SetServerVersionCache(common.ServerVersionsFile(), v.GetDuration("ServerVersionCacheTTL"))
+	fallback, silent, err := fallbackFromConfig(v)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+	versioner.SetFallback(fallback)
+	versioner.SetSilentFallback(silent)
+	recordProbes(versioner, context)
+	if defaultVersion, found, err := common.LoadDefaultVersion(common.DefaultVersionFile()); err == nil && found {
+		versioner.SetDefaultVersion(defaultVersion)
+	}
+
+	if err := pinVersion(v, versioner, context); err != nil {
+		return semver.Version{}, "", err
+	}
+	version, err := versioner.KubectlVersionToUse(v.GetInt64("Timeout"))
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+	kubectlBin, err := versioner.EnsureCompatibleKubectlAvailable(version, v.GetBool("AllowDownload"))
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+	for _, b := range kFinder.AllKubectlBinaries(false) {
+		if b.Path == kubectlBin {
+			return b.Version, kubectlBin, nil
+		}
+	}
+	return version, kubectlBin, nil
+}
+
+// NewSyncCmd creates a new `kuberlr sync` cobra command
+func NewSyncCmd() *cobra.Command {
+	var context string
+
+	cmd := &cobra.Command{
+		Use:          "sync",
+		Short:        "Make sure the kubectl binary needed by a context is available",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Download the kubectl binary needed by the current context, if missing:
+  $ kuberlr sync
+
+  Prepare the kubectl binary needed by the "prod" context:
+  $ kuberlr sync --context prod`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+			if err := applyGlobalSettings(v); err != nil {
+				return err
+			}
+			if context == "" {
+				context = kubehelper.CurrentContext()
+			}
+
+			kubectlBin, err := syncContext(v, context)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Context %q uses %s\n", context, kubectlBin)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&context, "context", "", "kubeconfig context to prepare, defaults to the current one")
+
+	return cmd
+}
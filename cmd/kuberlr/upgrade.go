@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/osexec"
+	"github.com/flavio/kuberlr/internal/upgrade"
+)
+
+func autoUpgradeStampFile() string {
+	return filepath.Join(common.KuberlrDir(), "last-auto-upgrade")
+}
+
+// maybeAutoUpgrade starts `kuberlr upgrade` in the background when the
+// interval defined by the AutoUpgrade setting elapsed. The invocation of
+// kubectl is never blocked
+func maybeAutoUpgrade(v *viper.Viper) {
+	if common.IsOffline() {
+		return
+	}
+	interval, err := upgrade.ParseInterval(v.GetString("AutoUpgrade"))
+	if err != nil {
+		klog.Warningf("AutoUpgrade: %v", err)
+		return
+	}
+
+	now := time.Now()
+	if !upgrade.Due(autoUpgradeStampFile(), interval, now) {
+		return
+	}
+	// record the attempt right away, this prevents concurrent
+	// invocations of kubectl from starting other upgrades
+	if err := upgrade.Touch(autoUpgradeStampFile(), now); err != nil {
+		klog.V(1).Infof("Cannot record automatic upgrade: %v", err)
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		klog.V(1).Infof("Cannot find kuberlr executable: %v", err)
+		return
+	}
+	// argv[0] must be kuberlr, otherwise a binary linked as kubectl
+	// would start in kubectl wrapper mode
+	logFile := filepath.Join(common.KuberlrDir(), "auto-upgrade.log")
+	if err := osexec.StartDetached(exe, []string{"kuberlr", "upgrade"}, logFile); err != nil {
+		klog.V(1).Infof("Cannot start automatic upgrade: %v", err)
+	}
+}
+
+// NewUpgradeCmd creates a new `kuberlr upgrade` cobra command
+func NewUpgradeCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:          "upgrade",
+		Short:        "Download the latest patch release of the installed minor releases",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Show which versions would be downloaded:
+  $ kuberlr upgrade --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+
+			// only the minor releases downloaded by kuberlr are upgraded
+			kFinder, err := newKubectlFinder(v)
+			if err != nil {
+				return err
+			}
+			installed := []semver.Version{}
+			local, err := kFinder.LocalKubectlBinaries()
+			if err != nil {
+				return err
+			}
+			shared, err := kFinder.SharedKubectlBinaries()
+			if err != nil {
+				return err
+			}
+			for _, b := range append(local, shared...) {
+				installed = append(installed, b.Version)
+			}
+
+			d, err := newDownloader(v)
+			if err != nil {
+				return err
+			}
+			remote, err := d.RemoteVersions()
+			if err != nil {
+				return err
+			}
+
+			versions := upgrade.Plan(installed, remote)
+			if len(versions) == 0 {
+				fmt.Println("All the installed minor releases are up to date")
+				return nil
+			}
+
+			downloadDir := common.LocalDownloadDir()
+			store := newSharedStore(v)
+			useStore := store.Usable()
+			if useStore {
+				downloadDir = store.Dir()
+			}
+
+			for _, version := range versions {
+				fmt.Printf("Upgrading to kubectl %s\n", version)
+				if dryRun {
+					continue
+				}
+
+				destination := filepath.Join(downloadDir, common.BuildKubectlNameForLocalBin(version))
+				if err := d.GetKubectlBinary(version, destination); err != nil {
+					return err
+				}
+				if useStore {
+					if err := store.Share(destination); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the versions that would be downloaded")
+
+	return cmd
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/integrity"
+)
+
+// verifiedStore holds the verification results of the binaries of a store
+type verifiedStore struct {
+	name    string
+	results []integrity.Result
+}
+
+// verifyCacheFile returns the path to the file caching the checksums
+// computed by the verifications
+func verifyCacheFile() string {
+	return filepath.Join(common.KuberlrDir(), "verify-cache.json")
+}
+
+// statusText returns the colored verification status
+func statusText(status integrity.Status) string {
+	switch status {
+	case integrity.StatusCorrupted:
+		return text.FgRed.Sprint(status)
+	case integrity.StatusUnverifiable:
+		return text.FgYellow.Sprint(status)
+	default:
+		return text.FgGreen.Sprint(status)
+	}
+}
+
+func verifyStore(name string, bins finder.KubectlBinaries, err error, cache *integrity.Cache) verifiedStore {
+	store := verifiedStore{name: name, results: []integrity.Result{}}
+	if err != nil {
+		fmt.Printf("Cannot list the %s binaries: %v\n", name, err)
+		return store
+	}
+
+	for _, b := range bins {
+		r := integrity.Verify(b.Path, cache)
+		fmt.Printf("%s: %s\n", statusText(r.Status), r.Path)
+		if r.Error != "" {
+			fmt.Printf("  %s\n", r.Error)
+		}
+		store.results = append(store.results, r)
+	}
+	return store
+}
+
+// quarantineCorrupted moves the corrupted binaries to the quarantine
+// directory, the binaries in use are left untouched
+func quarantineCorrupted(results []integrity.Result) {
+	for _, r := range results {
+		if r.Status != integrity.StatusCorrupted {
+			continue
+		}
+
+		lock, locked, err := common.LockForRemoval(r.Path)
+		if err != nil {
+			fmt.Printf("Cannot quarantine %s: %v\n", r.Path, err)
+			continue
+		}
+		if !locked {
+			fmt.Printf("Cannot quarantine %s: it's in use\n", r.Path)
+			continue
+		}
+		reason := fmt.Sprintf("checksum mismatch, expected %s, got %s", r.Expected, r.Actual)
+		if r.Error != "" {
+			reason = r.Error
+		}
+		destination, err := common.Quarantine(r.Path, common.QuarantineDir(), common.Metadata{}, reason, time.Now())
+		lock.Release()
+		if err != nil {
+			fmt.Printf("Cannot quarantine %s: %v\n", r.Path, err)
+			continue
+		}
+		fmt.Printf("Moved %s to %s\n", r.Path, destination)
+	}
+}
+
+func printVerifySummary(stores []verifiedStore) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Store", "OK", "Corrupted", "Unverifiable"})
+	for _, s := range stores {
+		summary := integrity.Summarize(s.results)
+		t.AppendRow([]interface{}{
+			s.name,
+			summary[integrity.StatusOK],
+			summary[integrity.StatusCorrupted],
+			summary[integrity.StatusUnverifiable],
+		})
+	}
+	t.Render()
+}
+
+// NewVerifyCmd creates a new `kuberlr verify` cobra command
+func NewVerifyCmd() *cobra.Command {
+	var noCache, quarantine bool
+
+	cmd := &cobra.Command{
+		Use:          "verify",
+		Short:        "Make sure the kubectl binaries didn't change since their installation",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Check the integrity of all the binaries, the exit code is not zero when
+  some of them are corrupted:
+  $ kuberlr verify
+
+  Compute the checksum of all the binaries, even the ones that didn't change
+  since the last verification:
+  $ kuberlr verify --no-cache
+
+  Move the corrupted binaries to the quarantine directory:
+  $ kuberlr verify --quarantine`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+			kFinder, err := newKubectlFinder(v)
+			if err != nil {
+				return err
+			}
+
+			var cache *integrity.Cache
+			if !noCache {
+				cache = integrity.LoadCache(verifyCacheFile())
+			}
+
+			stores := []verifiedStore{}
+			bins, err := kFinder.SystemKubectlBinaries()
+			stores = append(stores, verifyStore("system-wide", bins, err, cache))
+			if kFinder.SharedBinaryPath != "" {
+				bins, err := kFinder.SharedKubectlBinaries()
+				stores = append(stores, verifyStore("shared", bins, err, cache))
+			}
+			bins, err = kFinder.LocalKubectlBinaries()
+			stores = append(stores, verifyStore("local", bins, err, cache))
+
+			if cache != nil {
+				if err := cache.Save(); err != nil {
+					klog.V(1).Infof("Cannot save verification cache: %v", err)
+				}
+			}
+
+			if quarantine {
+				for _, s := range stores {
+					quarantineCorrupted(s.results)
+				}
+			}
+
+			fmt.Println()
+			printVerifySummary(stores)
+
+			for _, s := range stores {
+				if integrity.Summarize(s.results).Failed() {
+					return errors.New("Some binaries are corrupted")
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "compute the checksum of all the binaries, even the unchanged ones")
+	cmd.Flags().BoolVar(&quarantine, "quarantine", false, "move the corrupted binaries to the quarantine directory")
+
+	return cmd
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// NewWatchCmd creates a new `kuberlr watch` cobra command
+func NewWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "watch",
+		Short:        "Prepare the kubectl binary of each context as soon as it's added or becomes the current one",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Keep running in the background while switching contexts with kubectx:
+  $ kuberlr watch &`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+			if err := applyGlobalSettings(v); err != nil {
+				return err
+			}
+
+			stop := make(chan struct{})
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt)
+			go func() {
+				<-signals
+				close(stop)
+			}()
+
+			watcher := kubehelper.NewContextWatcher()
+			return watcher.Watch(stop, func(context string) {
+				kubectlBin, err := syncContext(v, context)
+				if err != nil {
+					klog.Warningf("Cannot prepare kubectl for context %q: %v", context, err)
+					return
+				}
+				fmt.Printf("Context %q uses %s\n", context, kubectlBin)
+			})
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+const whyTimeFormat = "2006-01-02 15:04:05"
+
+func printWhy(b finder.KubectlBinary, usage common.Usage, defaultBin string, pinned []pin) {
+	fmt.Println(b.Path)
+
+	metadata, found, err := common.LoadMetadata(b.Path)
+	switch {
+	case err != nil:
+		fmt.Printf("  installed:   cannot read metadata: %v\n", err)
+	case found:
+		context := metadata.Context
+		if context == "" {
+			context = "unknown"
+		}
+		fmt.Printf("  installed:   %s while using context %q\n", metadata.InstalledAt.Local().Format(whyTimeFormat), context)
+		fmt.Printf("  source:      %s\n", metadata.SourceURL)
+	default:
+		fmt.Println("  installed:   not by kuberlr, or by a release that didn't record metadata")
+	}
+
+	if last, found := usage.LastUsed(b.Path); found {
+		fmt.Printf("  last used:   %s\n", last.Local().Format(whyTimeFormat))
+	} else {
+		fmt.Println("  last used:   never")
+	}
+
+	dependents := []string{}
+	if b.Path == defaultBin {
+		dependents = append(dependents, "default version")
+	}
+	// `kuberlr prune` never removes the pinned versions
+	sources := []string{}
+	for _, p := range pinned {
+		if !p.version.Equals(b.Version) || (p.source == "default version" && b.Path == defaultBin) {
+			continue
+		}
+		sources = append(sources, "pinned by "+p.source)
+	}
+	sort.Strings(sources)
+	dependents = append(dependents, sources...)
+	contexts := []string{}
+	for context := range usage[b.Path] {
+		contexts = append(contexts, context)
+	}
+	sort.Strings(contexts)
+	for _, context := range contexts {
+		name := context
+		if name == "" {
+			name = "<no context>"
+		}
+		dependents = append(dependents, fmt.Sprintf("context %q (%s ago)", name,
+			time.Since(usage[b.Path][context]).Round(time.Minute)))
+	}
+
+	if len(dependents) == 0 {
+		fmt.Println("  used by:     nothing, it can be deleted")
+		return
+	}
+	fmt.Println("  used by:")
+	for _, d := range dependents {
+		fmt.Printf("    - %s\n", d)
+	}
+}
+
+// NewWhyCmd creates a new `kuberlr why` cobra command
+func NewWhyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "why [version]",
+		Short:        "Explain why a kubectl binary is around and what uses it",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Find out whether kubectl 1.20.1 can be deleted:
+  $ kuberlr why 1.20.1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := semver.ParseTolerant(args[0])
+			if err != nil {
+				return fmt.Errorf("Invalid version: %v", err)
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return err
+			}
+			kFinder, err := newKubectlFinder(v)
+			if err != nil {
+				return err
+			}
+
+			bins := finder.KubectlBinaries{}
+			for _, b := range kFinder.AllKubectlBinaries(true) {
+				if b.Version.Equals(version) {
+					bins = append(bins, b)
+				}
+			}
+			if len(bins) == 0 {
+				return fmt.Errorf("No kubectl binary with version %s found", version)
+			}
+
+			usage, err := common.LoadUsage(common.UsageFile())
+			if err != nil {
+				return fmt.Errorf("Cannot read usage of binaries: %v", err)
+			}
+
+			defaultBin := ""
+			if defaultVersion, found, err := common.LoadDefaultVersion(common.DefaultVersionFile()); err == nil && found {
+				if b, err := kFinder.FindCompatibleKubectl(defaultVersion); err == nil {
+					defaultBin = b.Path
+				}
+			}
+
+			pinned := pins(v)
+			for i, b := range bins {
+				if i > 0 {
+					fmt.Println()
+				}
+				printWhy(b, usage, defaultBin, pinned)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,182 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// Binary is a kubectl binary shipped inside of a bundle
+type Binary struct {
+	Version semver.Version
+	OS      string
+	Arch    string
+	// Path is the location of the binary
+	Path string
+	// SHA256 is the expected checksum of the binary, empty when unknown
+	SHA256 string
+}
+
+var (
+	// upstreamLayout matches the layout of the upstream release bucket,
+	// e.g. v1.20.1/bin/linux/amd64/kubectl
+	upstreamLayout = regexp.MustCompile(`(?:^|/)v?(\d+\.\d+\.\d+[^/]*)/bin/([a-z0-9]+)/([a-z0-9]+)/kubectl(?:\.exe)?$`)
+	// flatLayout matches binaries named after their version and
+	// platform, e.g. kubectl-v1.20.1-linux-amd64 or kubectl_1.20.1_linux_amd64
+	flatLayout = regexp.MustCompile(`(?:^|/)kubectl[-_]v?(\d+\.\d+\.\d+)[-_]([a-z0-9]+)[-_]([a-z0-9]+)(?:\.exe)?$`)
+)
+
+// parsePath recognizes the kubectl binaries from their path relative
+// to the root of the bundle, which uses slashes as separator
+func parsePath(rel string) (Binary, bool) {
+	for _, layout := range []*regexp.Regexp{upstreamLayout, flatLayout} {
+		m := layout.FindStringSubmatch(rel)
+		if m == nil {
+			continue
+		}
+		version, err := semver.ParseTolerant(m[1])
+		if err != nil {
+			continue
+		}
+		return Binary{Version: version, OS: m[2], Arch: m[3]}, true
+	}
+	return Binary{}, false
+}
+
+// Scan returns the kubectl binaries found inside of the given directory.
+// The checksums are read from the manifest of the bundle, the file with the
+// same name as the binary and the `.sha256` suffix is used otherwise
+func Scan(dir string) ([]Binary, error) {
+	manifest, found, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return manifestBinaries(dir, manifest)
+	}
+
+	binaries := []Binary{}
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		b, found := parsePath(filepath.ToSlash(rel))
+		if !found {
+			return nil
+		}
+
+		b.Path = p
+		if data, err := ioutil.ReadFile(p + ".sha256"); err == nil {
+			// the output of sha256sum is accepted too
+			if fields := strings.Fields(string(data)); len(fields) > 0 {
+				b.SHA256 = fields[0]
+			}
+		}
+		binaries = append(binaries, b)
+		return nil
+	})
+	return binaries, err
+}
+
+// manifestBinaries returns the binaries listed by the manifest of the
+// bundle extracted inside of the given directory
+func manifestBinaries(dir string, manifest Manifest) ([]Binary, error) {
+	binaries := []Binary{}
+	for _, entry := range manifest.Binaries {
+		version, err := semver.ParseTolerant(entry.Version)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid version %s inside of %s: %v", entry.Version, ManifestFile, err)
+		}
+		name := path.Clean(entry.Path)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("%s points outside of the bundle", entry.Path)
+		}
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(p); err != nil {
+			return nil, fmt.Errorf("kubectl %s %s/%s is listed inside of %s but cannot be read: %v",
+				version, entry.OS, entry.Arch, ManifestFile, err)
+		}
+		binaries = append(binaries, Binary{
+			Version: version,
+			OS:      entry.OS,
+			Arch:    entry.Arch,
+			Path:    p,
+			SHA256:  entry.SHA256,
+		})
+	}
+	return binaries, nil
+}
+
+// Extract unpacks the given tarball, optionally compressed with gzip,
+// inside of the given directory. Only regular files and directories are
+// extracted, entries pointing outside of the directory are refused
+func Extract(tarball, dir string) error {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(tarball, ".gz") || strings.HasSuffix(tarball, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("Cannot read %s: %v", tarball, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Cannot read %s: %v", tarball, err)
+		}
+
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("Refusing to extract %s from %s, it points outside of the bundle", hdr.Name, tarball)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
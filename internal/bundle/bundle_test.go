@@ -0,0 +1,131 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		found    bool
+		version  string
+		platform string
+	}{
+		{"v1.20.1/bin/linux/amd64/kubectl", true, "1.20.1", "linux/amd64"},
+		{"release/v1.19.3/bin/windows/386/kubectl.exe", true, "1.19.3", "windows/386"},
+		{"kubectl-v1.21.0-darwin-arm64", true, "1.21.0", "darwin/arm64"},
+		{"bins/kubectl_1.18.6_linux_arm", true, "1.18.6", "linux/arm"},
+		{"v1.20.1/bin/linux/amd64/kubectl.sha256", false, "", ""},
+		{"kubectl1.20.1", false, "", ""},
+		{"README.md", false, "", ""},
+	}
+	for _, test := range tests {
+		b, found := parsePath(test.path)
+		if found != test.found {
+			t.Errorf("%s: found is %v instead of %v", test.path, found, test.found)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if b.Version.String() != test.version || b.OS+"/"+b.Arch != test.platform {
+			t.Errorf("%s: got %s %s/%s", test.path, b.Version, b.OS, b.Arch)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"v1.20.1/bin/linux/amd64/kubectl":        "binary",
+		"v1.20.1/bin/linux/amd64/kubectl.sha256": "abc123  kubectl\n",
+		"kubectl-v1.19.3-linux-amd64":            "binary",
+		"notes.txt":                              "not a binary",
+	}
+	for name, contents := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	binaries, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(binaries) != 2 {
+		t.Fatalf("Expected 2 binaries, got %+v", binaries)
+	}
+	for _, b := range binaries {
+		expected := ""
+		if b.Version.String() == "1.20.1" {
+			expected = "abc123"
+		}
+		if b.SHA256 != expected {
+			t.Errorf("%s: got checksum %q instead of %q", b.Version, b.SHA256, expected)
+		}
+	}
+}
+
+func writeTarball(t *testing.T, path string, entries map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	for name, contents := range entries {
+		hdr := &tar.Header{Name: name, Mode: 0755, Size: int64(len(contents)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tarball := filepath.Join(dir, "bundle.tar.gz")
+	writeTarball(t, tarball, map[string]string{"v1.20.1/bin/linux/amd64/kubectl": "binary"})
+	out := filepath.Join(dir, "out")
+	if err := Extract(tarball, out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(out, "v1.20.1", "bin", "linux", "amd64", "kubectl"))
+	if err != nil || string(data) != "binary" {
+		t.Errorf("Got %q, %v", data, err)
+	}
+
+	evil := filepath.Join(dir, "evil.tar.gz")
+	writeTarball(t, evil, map[string]string{"../escaped": "boom"})
+	if err := Extract(evil, filepath.Join(dir, "evil")); err == nil {
+		t.Error("Expected entries pointing outside of the bundle to be refused")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); !os.IsNotExist(err) {
+		t.Error("The entry has been extracted outside of the bundle")
+	}
+}
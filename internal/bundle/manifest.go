@@ -0,0 +1,173 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestFile is the name of the manifest found at the root of the
+// bundles created by kuberlr
+const ManifestFile = "manifest.json"
+
+// ManifestEntry describes a binary shipped inside of a bundle
+type ManifestEntry struct {
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	// Path is relative to the root of the bundle and uses slashes as
+	// separator
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the contents of a bundle
+type Manifest struct {
+	CreatedAt time.Time       `json:"createdAt"`
+	Binaries  []ManifestEntry `json:"binaries"`
+}
+
+// readManifest loads the manifest of the bundle extracted inside of the
+// given directory, found is false when the bundle doesn't have one
+func readManifest(dir string) (m Manifest, found bool, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if os.IsNotExist(err) {
+		return m, false, nil
+	}
+	if err != nil {
+		return m, false, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, false, fmt.Errorf("Cannot parse %s: %v", ManifestFile, err)
+	}
+	return m, true, nil
+}
+
+// BinaryPath returns the location of the binary inside of a bundle, it
+// follows the layout of the upstream release bucket
+func BinaryPath(b Binary) string {
+	name := "kubectl"
+	if b.OS == "windows" {
+		name += ".exe"
+	}
+	return fmt.Sprintf("v%s/bin/%s/%s/%s", b.Version, b.OS, b.Arch, name)
+}
+
+// fileChecksum returns the sha256 of the given file
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// addFile writes a regular file to the tarball
+func addFile(tw *tar.Writer, name string, mode int64, size int64, contents io.Reader) error {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     mode,
+		Size:     size,
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, contents)
+	return err
+}
+
+// addBinary writes the binary, together with its checksum, to the tarball
+func addBinary(tw *tar.Writer, b Binary, name string) error {
+	f, err := os.Open(b.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := addFile(tw, name, 0755, info.Size(), f); err != nil {
+		return fmt.Errorf("Cannot add %s: %v", b.Path, err)
+	}
+	sum := b.SHA256 + "\n"
+	return addFile(tw, name+".sha256", 0644, int64(len(sum)), strings.NewReader(sum))
+}
+
+// Write creates a tarball, compressed with gzip when its name ends with
+// `.gz` or `.tgz`, holding the given binaries and a manifest listing them.
+// The binaries are laid out like inside of the upstream release bucket,
+// next to their `.sha256` files, hence the extracted bundle can be used as
+// a mirror too. The checksums of the binaries are computed when empty
+func Write(tarball string, binaries []Binary) error {
+	manifest := Manifest{CreatedAt: time.Now().UTC(), Binaries: []ManifestEntry{}}
+	for i, b := range binaries {
+		if b.SHA256 == "" {
+			sum, err := fileChecksum(b.Path)
+			if err != nil {
+				return err
+			}
+			binaries[i].SHA256 = sum
+		}
+		manifest.Binaries = append(manifest.Binaries, ManifestEntry{
+			Version: b.Version.String(),
+			OS:      b.OS,
+			Arch:    b.Arch,
+			Path:    BinaryPath(b),
+			SHA256:  binaries[i].SHA256,
+		})
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(tarball)
+	if err != nil {
+		return err
+	}
+	var w io.WriteCloser = f
+	if strings.HasSuffix(tarball, ".gz") || strings.HasSuffix(tarball, ".tgz") {
+		w = gzip.NewWriter(f)
+	}
+	tw := tar.NewWriter(w)
+
+	// the manifest comes first, it can be read without going through
+	// the whole bundle
+	err = addFile(tw, ManifestFile, 0644, int64(len(data)), strings.NewReader(string(data)))
+	for i := 0; err == nil && i < len(binaries); i++ {
+		err = addBinary(tw, binaries[i], manifest.Binaries[i].Path)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil && w != f {
+		err = w.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tarball)
+		return fmt.Errorf("Cannot write %s: %v", tarball, err)
+	}
+	return nil
+}
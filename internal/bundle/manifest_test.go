@@ -0,0 +1,65 @@
+package bundle
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestWriteAndScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "kubectl1.20.1")
+	if err := ioutil.WriteFile(binary, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	binaries := []Binary{
+		{Version: semver.MustParse("1.20.1"), OS: "linux", Arch: "amd64", Path: binary},
+		{Version: semver.MustParse("1.20.1"), OS: "windows", Arch: "amd64", Path: binary},
+	}
+
+	tarball := filepath.Join(dir, "bundle.tgz")
+	if err := Write(tarball, binaries); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := filepath.Join(dir, "out")
+	if err := Extract(tarball, out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "v1.20.1", "bin", "windows", "amd64", "kubectl.exe.sha256")); err != nil {
+		t.Errorf("Missing checksum file: %v", err)
+	}
+
+	scanned, err := Scan(out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(scanned) != 2 {
+		t.Fatalf("Expected 2 binaries, got %+v", scanned)
+	}
+	// sha256 of "binary"
+	const expected = "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd"
+	for _, b := range scanned {
+		if b.SHA256 != expected {
+			t.Errorf("%s/%s: got checksum %q instead of %q", b.OS, b.Arch, b.SHA256, expected)
+		}
+	}
+	if scanned[1].OS != "windows" || filepath.Base(scanned[1].Path) != "kubectl.exe" {
+		t.Errorf("Unexpected binary %+v", scanned[1])
+	}
+
+	// binaries listed by the manifest must be part of the bundle
+	if err := os.Remove(scanned[0].Path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Scan(out); err == nil {
+		t.Error("Expected missing binaries to be reported")
+	}
+}
@@ -0,0 +1,103 @@
+package changelog
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog"
+)
+
+// DefaultBaseURL is the location of the changelogs of kubernetes, there's
+// one file per minor release
+const DefaultBaseURL = "https://raw.githubusercontent.com/kubernetes/kubernetes/master/CHANGELOG"
+
+// Fetcher retrieves the changelogs of kubernetes and keeps a copy of them
+// inside of a local cache
+type Fetcher struct {
+	// BaseURL is the location of the changelog files
+	BaseURL string
+	// CacheDir is the directory holding the changelogs already downloaded
+	CacheDir string
+}
+
+// fileName returns the name of the changelog covering the given version
+func fileName(v semver.Version) string {
+	return fmt.Sprintf("CHANGELOG-%d.%d.md", v.Major, v.Minor)
+}
+
+// Get returns the section of the changelog describing the given version.
+// The cached changelog is used when it already covers the version,
+// otherwise a fresh copy is downloaded
+func (f *Fetcher) Get(v semver.Version) (string, error) {
+	cached := filepath.Join(f.CacheDir, fileName(v))
+	if data, err := ioutil.ReadFile(cached); err == nil {
+		if section, found := Section(string(data), v); found {
+			return section, nil
+		}
+	}
+
+	data, err := f.download(v)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(f.CacheDir, 0755); err != nil {
+		klog.V(1).Infof("Cannot create %s: %v", f.CacheDir, err)
+	} else if err := ioutil.WriteFile(cached, data, 0644); err != nil {
+		klog.V(1).Infof("Cannot cache %s: %v", cached, err)
+	}
+
+	section, found := Section(string(data), v)
+	if !found {
+		return "", fmt.Errorf("The changelog doesn't have an entry for v%s", v)
+	}
+	return section, nil
+}
+
+func (f *Fetcher) download(v semver.Version) ([]byte, error) {
+	url := fmt.Sprintf("%s/%s", strings.TrimRight(f.BaseURL, "/"), fileName(v))
+	res, err := http.Get(url)
+	if err != nil {
+		return []byte{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return []byte{}, fmt.Errorf("No changelog exists for kubernetes %d.%d", v.Major, v.Minor)
+	}
+	if res.StatusCode != http.StatusOK {
+		return []byte{}, fmt.Errorf("GET %s returned http status %s", url, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// Section extracts the part of the changelog describing the given version.
+// Each release is described by a top level heading like `# v1.20.1`
+func Section(doc string, v semver.Version) (string, bool) {
+	heading := "# v" + v.String()
+	var section strings.Builder
+	found := false
+
+	scanner := bufio.NewScanner(strings.NewReader(doc))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# ") {
+			if found {
+				break
+			}
+			found = strings.TrimSpace(line) == heading
+		}
+		if found {
+			section.WriteString(line)
+			section.WriteString("\n")
+		}
+	}
+
+	return strings.TrimSpace(section.String()), found
+}
@@ -0,0 +1,81 @@
+package changelog
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+const fakeChangelog = `<!-- BEGIN MUNGE: GENERATED_TOC -->
+
+- [v1.20.1](#v1201)
+- [v1.20.0](#v1200)
+
+<!-- END MUNGE: GENERATED_TOC -->
+
+# v1.20.1
+
+## Changes by Kind
+
+### Bug or Regression
+
+- kubectl: fix something (#1234)
+
+# v1.20.0
+
+## Changes by Kind
+
+- kubectl: add something
+`
+
+func TestSection(t *testing.T) {
+	section, found := Section(fakeChangelog, semver.MustParse("1.20.1"))
+	if !found {
+		t.Fatal("Section not found")
+	}
+	if !strings.HasPrefix(section, "# v1.20.1") || !strings.HasSuffix(section, "(#1234)") {
+		t.Errorf("Unexpected section: %q", section)
+	}
+
+	if _, found := Section(fakeChangelog, semver.MustParse("1.20.2")); found {
+		t.Error("Unexpected section found")
+	}
+}
+
+func TestGetUsesCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/CHANGELOG-1.20.md" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(fakeChangelog))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-changelog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := Fetcher{BaseURL: server.URL, CacheDir: dir}
+	for _, v := range []string{"1.20.1", "1.20.0"} {
+		if _, err := f.Get(semver.MustParse(v)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+
+	if _, err := f.Get(semver.MustParse("1.21.0")); err == nil {
+		t.Error("Expected an error")
+	}
+}
@@ -0,0 +1,160 @@
+package common
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// MaxActivityRecords is the number of invocations, and of downloads,
+// remembered by the activity log
+const MaxActivityRecords = 500
+
+// Invocation describes a run of kuberlr on behalf of kubectl
+type Invocation struct {
+	At time.Time `json:"at"`
+	// Overhead is the time spent by kuberlr before running kubectl
+	Overhead time.Duration `json:"overhead"`
+	// Downloaded is true when the binary had to be downloaded
+	Downloaded bool `json:"downloaded,omitempty"`
+	// VersionCacheHit is set when the version of the API server has been
+	// looked up inside of the cache, it's true when it has been found
+	VersionCacheHit *bool `json:"versionCacheHit,omitempty"`
+}
+
+// Download describes a kubectl binary downloaded by kuberlr
+type Download struct {
+	At       time.Time     `json:"at"`
+	Version  string        `json:"version"`
+	Size     int64         `json:"size"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Activity records the recent invocations and downloads of kuberlr
+type Activity struct {
+	Invocations []Invocation `json:"invocations"`
+	Downloads   []Download   `json:"downloads"`
+}
+
+// DownloadPeriod sums up the downloads made during a month
+type DownloadPeriod struct {
+	Month string `json:"month"`
+	Count int    `json:"count"`
+	Size  int64  `json:"size"`
+}
+
+// ActivitySummary describes how effective the binaries cached by kuberlr are
+type ActivitySummary struct {
+	Invocations int `json:"invocations"`
+	// HitRate is the share of the invocations that didn't need a download
+	HitRate float64 `json:"hitRate"`
+	// VersionLookups is the number of invocations that looked up the
+	// version of the API server inside of the cache
+	VersionLookups int `json:"versionLookups"`
+	// VersionHitRate is the share of these lookups that found the version,
+	// without contacting the API server
+	VersionHitRate float64 `json:"versionHitRate"`
+	// AvgOverhead is the average time added to each kubectl call
+	AvgOverhead time.Duration `json:"avgOverhead"`
+	// AvgHitOverhead is the average time added to the kubectl calls
+	// that didn't need a download
+	AvgHitOverhead time.Duration    `json:"avgHitOverhead"`
+	Downloads      []DownloadPeriod `json:"downloads"`
+}
+
+// ActivityFile returns the path to the file recording the activity of kuberlr
+func ActivityFile() string {
+	return filepath.Join(KuberlrDir(), "activity.json")
+}
+
+// LoadActivity reads the activity log, an empty Activity is returned
+// when nothing has been recorded yet
+func LoadActivity(path string) (Activity, error) {
+	activity := Activity{}
+	err := loadStateFile(path, &activity)
+	return activity, err
+}
+
+// RecordInvocation adds the given invocation to the activity log, only the
+// last MaxActivityRecords invocations are kept
+func RecordInvocation(path string, i Invocation) error {
+	return updateActivity(path, func(a *Activity) {
+		a.Invocations = append(a.Invocations, i)
+		if len(a.Invocations) > MaxActivityRecords {
+			a.Invocations = a.Invocations[len(a.Invocations)-MaxActivityRecords:]
+		}
+	})
+}
+
+// RecordDownload adds the given download to the activity log, only the
+// last MaxActivityRecords downloads are kept
+func RecordDownload(path string, d Download) error {
+	return updateActivity(path, func(a *Activity) {
+		a.Downloads = append(a.Downloads, d)
+		if len(a.Downloads) > MaxActivityRecords {
+			a.Downloads = a.Downloads[len(a.Downloads)-MaxActivityRecords:]
+		}
+	})
+}
+
+func updateActivity(path string, update func(*Activity)) error {
+	activity := Activity{}
+	return UpdateStateFile(path, &activity, func() {
+		update(&activity)
+	})
+}
+
+// Summary returns the summary of the activity, downloads are grouped by
+// month, from the oldest to the newest one
+func (a Activity) Summary() ActivitySummary {
+	s := ActivitySummary{
+		Invocations: len(a.Invocations),
+		Downloads:   []DownloadPeriod{},
+	}
+
+	var total, hitTotal time.Duration
+	hits, versionHits := 0, 0
+	for _, i := range a.Invocations {
+		total += i.Overhead
+		if !i.Downloaded {
+			hits++
+			hitTotal += i.Overhead
+		}
+		if i.VersionCacheHit != nil {
+			s.VersionLookups++
+			if *i.VersionCacheHit {
+				versionHits++
+			}
+		}
+	}
+	if s.VersionLookups > 0 {
+		s.VersionHitRate = float64(versionHits) / float64(s.VersionLookups)
+	}
+	if len(a.Invocations) > 0 {
+		s.HitRate = float64(hits) / float64(len(a.Invocations))
+		s.AvgOverhead = total / time.Duration(len(a.Invocations))
+	}
+	if hits > 0 {
+		s.AvgHitOverhead = hitTotal / time.Duration(hits)
+	}
+
+	periods := map[string]*DownloadPeriod{}
+	for _, d := range a.Downloads {
+		month := d.At.Local().Format("2006-01")
+		p, found := periods[month]
+		if !found {
+			p = &DownloadPeriod{Month: month}
+			periods[month] = p
+		}
+		p.Count++
+		p.Size += d.Size
+	}
+	for _, p := range periods {
+		s.Downloads = append(s.Downloads, *p)
+	}
+	sort.Slice(s.Downloads, func(i, j int) bool {
+		return s.Downloads[i].Month < s.Downloads[j].Month
+	})
+
+	return s
+}
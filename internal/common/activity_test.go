@@ -0,0 +1,85 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordActivity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-activity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "activity.json")
+
+	for i := 0; i < MaxActivityRecords+10; i++ {
+		if err := RecordInvocation(path, Invocation{Overhead: time.Millisecond}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := RecordDownload(path, Download{Version: "1.20.1", Size: 100}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	activity, err := LoadActivity(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity.Invocations) != MaxActivityRecords {
+		t.Errorf("Expected %d invocations, got %d", MaxActivityRecords, len(activity.Invocations))
+	}
+	if len(activity.Downloads) != 1 || activity.Downloads[0].Size != 100 {
+		t.Errorf("Unexpected downloads %+v", activity.Downloads)
+	}
+}
+
+func TestActivitySummary(t *testing.T) {
+	march := time.Date(2021, time.March, 10, 12, 0, 0, 0, time.Local)
+	april := time.Date(2021, time.April, 2, 12, 0, 0, 0, time.Local)
+
+	hit, miss := true, false
+	activity := Activity{
+		Invocations: []Invocation{
+			{Overhead: 10 * time.Millisecond, VersionCacheHit: &hit},
+			{Overhead: 30 * time.Millisecond, VersionCacheHit: &miss},
+			{Overhead: 2 * time.Second, Downloaded: true},
+			{Overhead: 20 * time.Millisecond, VersionCacheHit: &hit},
+		},
+		Downloads: []Download{
+			{At: april, Size: 300},
+			{At: march, Size: 100},
+			{At: march, Size: 200},
+		},
+	}
+
+	s := activity.Summary()
+	if s.Invocations != 4 || s.HitRate != 0.75 {
+		t.Errorf("Unexpected hit rate %+v", s)
+	}
+	if s.VersionLookups != 3 || s.VersionHitRate != 2.0/3 {
+		t.Errorf("Unexpected version cache hit rate %+v", s)
+	}
+	if s.AvgOverhead != 515*time.Millisecond || s.AvgHitOverhead != 20*time.Millisecond {
+		t.Errorf("Unexpected overhead %+v", s)
+	}
+	expected := []DownloadPeriod{
+		{Month: "2021-03", Count: 2, Size: 300},
+		{Month: "2021-04", Count: 1, Size: 300},
+	}
+	if len(s.Downloads) != len(expected) {
+		t.Fatalf("Unexpected downloads %+v", s.Downloads)
+	}
+	for i := range expected {
+		if s.Downloads[i] != expected[i] {
+			t.Errorf("Got %+v instead of %+v", s.Downloads[i], expected[i])
+		}
+	}
+
+	if empty := (Activity{}).Summary(); empty.Invocations != 0 || empty.HitRate != 0 {
+		t.Errorf("Unexpected summary of an empty activity %+v", empty)
+	}
+}
@@ -0,0 +1,34 @@
+package common
+
+import "path/filepath"
+
+// Aliases maps the path of each wrapper script generated by `kuberlr alias`
+// to the version of kubectl it's bound to
+type Aliases map[string]string
+
+// AliasesFile returns the path to the file recording the wrapper scripts
+// bound to a kubectl version
+func AliasesFile() string {
+	return filepath.Join(KuberlrDir(), "aliases.json")
+}
+
+// LoadAliases reads the wrapper scripts bound to a kubectl version, empty
+// Aliases are returned when nothing has been recorded yet
+func LoadAliases(path string) (Aliases, error) {
+	aliases := Aliases{}
+	err := loadStateFile(path, &aliases)
+	return aliases, err
+}
+
+// RecordAlias records the given wrapper script is bound to the given
+// kubectl version, an empty version means the script isn't bound to any
+func RecordAlias(path, script, version string) error {
+	aliases := Aliases{}
+	return UpdateStateFile(path, &aliases, func() {
+		if version == "" {
+			delete(aliases, script)
+			return
+		}
+		aliases[script] = version
+	})
+}
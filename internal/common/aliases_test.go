@@ -0,0 +1,36 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAlias(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-aliases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "aliases.json")
+
+	if err := RecordAlias(path, "/home/user/bin/kubectl-prod", "1.20.4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordAlias(path, "/home/user/bin/kubectl-dev", "1.21.0"); err != nil {
+		t.Fatal(err)
+	}
+	// the script isn't bound to a version anymore
+	if err := RecordAlias(path, "/home/user/bin/kubectl-dev", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	aliases, err := LoadAliases(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliases) != 1 || aliases["/home/user/bin/kubectl-prod"] != "1.20.4" {
+		t.Errorf("Unexpected aliases %v", aliases)
+	}
+}
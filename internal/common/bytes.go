@@ -0,0 +1,36 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// byteSizeExpr matches sizes like "512", "1.5M", "10MiB" or "2MB"
+var byteSizeExpr = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([KMGT]?)(i?)(B?)$`)
+
+// ParseBytes converts the given size to an amount of bytes. The K, M, G
+// and T suffixes, optionally followed by "iB", use binary multiples.
+// KB, MB, GB and TB use decimal multiples, like disk vendors do
+func ParseBytes(size string) (int64, error) {
+	m := byteSizeExpr.FindStringSubmatch(strings.TrimSpace(size))
+	if m == nil {
+		return 0, fmt.Errorf("Invalid size %q", size)
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid size %q: %v", size, err)
+	}
+
+	if m[2] != "" {
+		base := 1024.0
+		if m[3] == "" && m[4] == "B" {
+			base = 1000.0
+		}
+		for exp := strings.Index("KMGT", m[2]); exp >= 0; exp-- {
+			value *= base
+		}
+	}
+	return int64(value), nil
+}
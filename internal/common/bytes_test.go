@@ -0,0 +1,32 @@
+package common
+
+import "testing"
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		size     string
+		expected int64
+		valid    bool
+	}{
+		{"512", 512, true},
+		{"512B", 512, true},
+		{"1K", 1024, true},
+		{"1.5M", 1572864, true},
+		{"10MiB", 10485760, true},
+		{"2MB", 2000000, true},
+		{"1 GiB", 1073741824, true},
+		{"fast", 0, false},
+		{"1X", 0, false},
+		{"-1M", 0, false},
+	}
+	for _, test := range tests {
+		actual, err := ParseBytes(test.size)
+		if (err == nil) != test.valid {
+			t.Errorf("%q: unexpected error %v", test.size, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("%q: got %d instead of %d", test.size, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// DefaultArgs returns the arguments to add to the invocations of the given
+// version of kubectl. The rules map version ranges, like ">=1.25.0 <1.26.0"
+// or "1.25.x", to arguments. The arguments of all the matching rules are
+// returned, sorted by range to keep the result stable
+func DefaultArgs(rules map[string][]string, version semver.Version) ([]string, error) {
+	ranges := []string{}
+	for r := range rules {
+		ranges = append(ranges, r)
+	}
+	sort.Strings(ranges)
+
+	args := []string{}
+	for _, r := range ranges {
+		validRange, err := semver.ParseRange(r)
+		if err != nil {
+			return []string{}, fmt.Errorf("Invalid kubectl version range %q for default arguments: %v", r, err)
+		}
+		if validRange(version) {
+			args = append(args, rules[r]...)
+		}
+	}
+	return args, nil
+}
+
+// InsertDefaultArgs returns the given kubectl arguments with the default
+// ones added after the command path, e.g. "get pods", so that neither the
+// dispatch of plugins nor the flags of the command are affected. The
+// default flags given explicitly are skipped, the explicit ones take
+// precedence
+func InsertDefaultArgs(args, defaults []string) []string {
+	end := len(args)
+	for i, arg := range args {
+		if arg == "--" {
+			end = i
+			break
+		}
+	}
+	explicit := map[string]bool{}
+	for _, arg := range args[:end] {
+		if strings.HasPrefix(arg, "-") {
+			explicit[flagName(arg)] = true
+		}
+	}
+
+	extra := []string{}
+	for i := 0; i < len(defaults); i++ {
+		if !explicit[flagName(defaults[i])] {
+			extra = append(extra, defaults[i])
+			continue
+		}
+		// skip the value given as a separate argument too
+		if !strings.Contains(defaults[i], "=") && i+1 < len(defaults) && !strings.HasPrefix(defaults[i+1], "-") {
+			i++
+		}
+	}
+	if len(extra) == 0 {
+		return args
+	}
+
+	// the command path ends with the first flag following the command
+	at := end
+	if command := CommandIndex(args[:end]); command >= 0 {
+		at = command
+		for at < end && !strings.HasPrefix(args[at], "-") {
+			at++
+		}
+	}
+
+	result := append([]string{}, args[:at]...)
+	result = append(result, extra...)
+	return append(result, args[at:]...)
+}
+
+// flagName returns the name of the given flag, without its value
+func flagName(arg string) string {
+	return strings.SplitN(arg, "=", 2)[0]
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestDefaultArgs(t *testing.T) {
+	rules := map[string][]string{
+		"1.25.x":           {"--warnings-as-errors=false"},
+		">=1.24.0 <1.26.0": {"--request-timeout=30s"},
+	}
+
+	tests := []struct {
+		version  string
+		expected []string
+	}{
+		{"1.25.3", []string{"--warnings-as-errors=false", "--request-timeout=30s"}},
+		{"1.24.0", []string{"--request-timeout=30s"}},
+		{"1.26.1", []string{}},
+	}
+	for _, test := range tests {
+		actual, err := DefaultArgs(rules, semver.MustParse(test.version))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: got %v instead of %v", test.version, actual, test.expected)
+		}
+	}
+
+	if _, err := DefaultArgs(map[string][]string{"latest": {"-v"}}, semver.MustParse("1.25.0")); err == nil {
+		t.Error("Expected an error for an invalid range")
+	}
+}
+
+func TestInsertDefaultArgs(t *testing.T) {
+	defaults := []string{"--request-timeout", "30s", "--warnings-as-errors=false"}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			[]string{"get", "pods"},
+			[]string{"get", "pods", "--request-timeout", "30s", "--warnings-as-errors=false"},
+		},
+		{
+			[]string{"--context", "prod", "myplugin", "arg", "--flag"},
+			[]string{"--context", "prod", "myplugin", "arg", "--request-timeout", "30s", "--warnings-as-errors=false", "--flag"},
+		},
+		{
+			[]string{"exec", "mypod", "--", "sh", "--request-timeout=1s"},
+			[]string{"exec", "mypod", "--request-timeout", "30s", "--warnings-as-errors=false", "--", "sh", "--request-timeout=1s"},
+		},
+		{
+			[]string{"--request-timeout=5s", "get", "pods"},
+			[]string{"--request-timeout=5s", "get", "pods", "--warnings-as-errors=false"},
+		},
+		{
+			[]string{"--warnings-as-errors", "-n", "kube-system"},
+			[]string{"--warnings-as-errors", "-n", "kube-system", "--request-timeout", "30s"},
+		},
+	}
+	for _, test := range tests {
+		actual := InsertDefaultArgs(test.args, defaults)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%v: got %v instead of %v", test.args, actual, test.expected)
+		}
+	}
+}
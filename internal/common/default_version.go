@@ -0,0 +1,42 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// DefaultVersionFile returns the path to the file holding the kubectl version
+// to use when the version of the kubernetes API server cannot be found
+func DefaultVersionFile() string {
+	return filepath.Join(KuberlrDir(), "default-version")
+}
+
+// LoadDefaultVersion returns the default version of kubectl chosen by the
+// user. The boolean is false when no default version has been set
+func LoadDefaultVersion(path string) (semver.Version, bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return semver.Version{}, false, nil
+		}
+		return semver.Version{}, false, err
+	}
+
+	v, err := semver.ParseTolerant(strings.TrimSpace(string(data)))
+	if err != nil {
+		return semver.Version{}, false, err
+	}
+	return v, true, nil
+}
+
+// SaveDefaultVersion records the default version of kubectl chosen by the user
+func SaveDefaultVersion(path string, v semver.Version) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(v.String()+"\n"), 0644)
+}
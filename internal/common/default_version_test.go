@@ -0,0 +1,43 @@
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestDefaultVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-default-version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "default-version")
+
+	_, found, err := common.LoadDefaultVersion(path)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if found {
+		t.Error("No default version should be found")
+	}
+
+	expected := semver.MustParse("1.20.3")
+	if err := common.SaveDefaultVersion(path, expected); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	actual, found, err := common.LoadDefaultVersion(path)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !found || !actual.Equals(expected) {
+		t.Errorf("Got %v instead of %v", actual, expected)
+	}
+}
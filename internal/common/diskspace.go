@@ -0,0 +1,19 @@
+package common
+
+// EnsureFreeSpace returns an InsufficientSpaceError when the filesystem
+// holding the given directory doesn't have the required amount of bytes
+// available. Nothing is checked when the free space cannot be determined
+func EnsureFreeSpace(dir string, required uint64) error {
+	available, err := freeSpace(dir)
+	if err != nil {
+		return nil
+	}
+	if available < required {
+		return &InsufficientSpaceError{
+			Path:      dir,
+			Required:  required,
+			Available: available,
+		}
+	}
+	return nil
+}
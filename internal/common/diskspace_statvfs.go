@@ -0,0 +1,16 @@
+//go:build netbsd || solaris
+// +build netbsd solaris
+
+package common
+
+import "golang.org/x/sys/unix"
+
+// freeSpace returns the number of bytes available to unprivileged users
+// inside of the filesystem holding the given path
+func freeSpace(path string) (uint64, error) {
+	var st unix.Statvfs_t
+	if err := unix.Statvfs(path, &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * st.Frsize, nil
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestEnsureFreeSpace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-diskspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := EnsureFreeSpace(dir, 1); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err = EnsureFreeSpace(dir, math.MaxUint64)
+	if !IsInsufficientSpace(err) {
+		t.Errorf("Expected insufficient space error, got %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build unix && !netbsd && !openbsd && !solaris
+
+package common
+
+import "golang.org/x/sys/unix"
+
+// freeSpace returns the number of bytes available to unprivileged users
+// inside of the filesystem holding the given path
+func freeSpace(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	// the types of the fields differ among the operating systems
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package common
+
+import "golang.org/x/sys/windows"
+
+// freeSpace returns the number of bytes available to the current user
+// inside of the volume holding the given path
+func freeSpace(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var available uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &available, nil, nil); err != nil {
+		return 0, err
+	}
+	return available, nil
+}
@@ -2,15 +2,24 @@ package common
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
+
+	"k8s.io/klog"
 )
 
 // SystemPath contains the default path to look for kubectl binaries
 // installed system-wide
 const SystemPath = "/usr/bin"
 
+var (
+	kuberlrDir     string
+	kuberlrDirOnce sync.Once
+)
+
 // HomeDirEnvKey returns the name of the environment variable
 // that holds the name of the user home directory
 func HomeDirEnvKey() string {
@@ -27,14 +36,111 @@ func HomeDir() string {
 	return os.Getenv(HomeDirEnvKey())
 }
 
+// KuberlrDir returns the path to the directory where kuberlr keeps
+// the data of the current user. When the home directory cannot be
+// written, a private directory inside of the temporary directory
+// is used instead
+func KuberlrDir() string {
+	kuberlrDirOnce.Do(func() {
+		kuberlrDir = filepath.Join(HomeDir(), ".kuberlr")
+		if IsWritableDir(kuberlrDir) {
+			return
+		}
+
+		fallback := filepath.Join(os.TempDir(), fmt.Sprintf("kuberlr-%d", os.Getuid()))
+		if err := ensurePrivateDir(fallback); err != nil {
+			klog.Warningf("%s cannot be written and %s cannot be used: %v", kuberlrDir, fallback, err)
+			return
+		}
+		klog.Warningf("%s cannot be written, using %s instead", kuberlrDir, fallback)
+		kuberlrDir = fallback
+	})
+
+	return kuberlrDir
+}
+
+// IsWritableDir returns true when files can be created inside of the
+// given directory. The directory is created when it doesn't exist
+func IsWritableDir(dir string) bool {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".kuberlr-write-test-")
+	if err != nil {
+		return false
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+
+	return true
+}
+
+// WriteFileAtomic replaces the given file, concurrent invocations of
+// kuberlr must never see a partially written file. The temporary file is
+// created next to the destination, using the given name prefix
+func WriteFileAtomic(path string, data []byte, prefix string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), prefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ensurePrivateDir creates the given directory making sure nobody but
+// the current user can tamper with its contents: kuberlr executes the
+// binaries saved inside of it
+func ensurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if !ownedByCurrentUser(info) {
+		return fmt.Errorf("%s is not owned by the current user", dir)
+	}
+	if !IsWritableDir(dir) {
+		return fmt.Errorf("%s cannot be written", dir)
+	}
+
+	return nil
+}
+
+// platform returns the name of the directory holding the binaries
+// of the current operating system and architecture
+func platform() string {
+	return fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
+}
+
 // LocalDownloadDir return the path to where kuberlr saves
 // the kubectl binaries downloaded from kubernetes' upstream mirror
 func LocalDownloadDir() string {
-	platform := fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
-
 	return filepath.Join(
-		HomeDir(),
-		".kuberlr",
-		platform,
+		KuberlrDir(),
+		platform(),
 	)
 }
+
+// QuarantineDir returns the path to the directory where kuberlr moves
+// the binaries that cannot be trusted
+func QuarantineDir() string {
+	return filepath.Join(KuberlrDir(), "quarantine")
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsWritableDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-writable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if !IsWritableDir(filepath.Join(dir, "to-be-created")) {
+		t.Error("Expected directory to be writable")
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if IsWritableDir(filepath.Join(file, "child")) {
+		t.Error("A directory cannot be created inside of a file")
+	}
+}
+
+func TestEnsurePrivateDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ensurePrivateDir(filepath.Join(dir, "private")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ensurePrivateDir(file); err == nil {
+		t.Error("Expected files to be refused")
+	}
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TempDownloadPrefix is the prefix of the temporary files used while
+// downloading kubectl binaries
+const TempDownloadPrefix = "kuberlr-kubectl-"
+
+// DefaultStaleAge is the age after which a temporary file left behind by
+// kuberlr is considered stale
+const DefaultStaleAge = 24 * time.Hour
+
+// GarbageCollector removes the temporary files left behind by the runs of
+// kuberlr that crashed or have been interrupted
+type GarbageCollector struct {
+	// Patterns are the glob patterns matching the temporary files
+	Patterns []string
+	// MaxAge is the age after which a temporary file is considered stale.
+	// It's also the interval between two collections
+	MaxAge time.Duration
+	// StampFile records when the last collection happened
+	StampFile string
+}
+
+// NewGarbageCollector returns a GarbageCollector looking for the temporary
+// files created by kuberlr inside of the system temporary directory and
+// of the given directories
+func NewGarbageCollector(dirs ...string) *GarbageCollector {
+	patterns := []string{filepath.Join(os.TempDir(), TempDownloadPrefix+"*")}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		patterns = append(patterns,
+			filepath.Join(dir, ".kuberlr-*"),
+			filepath.Join(dir, "*.partial"))
+	}
+
+	return &GarbageCollector{
+		Patterns:  patterns,
+		MaxAge:    DefaultStaleAge,
+		StampFile: filepath.Join(KuberlrDir(), "last-gc"),
+	}
+}
+
+// Run removes the stale temporary files, unless a collection already
+// happened during the last MaxAge. The removed files are returned
+func (gc *GarbageCollector) Run(now time.Time) ([]string, error) {
+	removed := []string{}
+
+	if info, err := os.Stat(gc.StampFile); err == nil && now.Sub(info.ModTime()) < gc.MaxAge {
+		return removed, nil
+	}
+
+	for _, pattern := range gc.Patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return removed, err
+		}
+		for _, m := range matches {
+			info, err := os.Lstat(m)
+			if err != nil || info.IsDir() || now.Sub(info.ModTime()) < gc.MaxAge {
+				continue
+			}
+			// files owned by other users cannot be removed, that's fine
+			if err := os.Remove(m); err == nil {
+				removed = append(removed, m)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(gc.StampFile), 0755); err != nil {
+		return removed, err
+	}
+	if err := ioutil.WriteFile(gc.StampFile, []byte{}, 0644); err != nil {
+		return removed, err
+	}
+	return removed, os.Chtimes(gc.StampFile, now, now)
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGarbageCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-gc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	old := now.Add(-2 * DefaultStaleAge)
+
+	files := map[string]time.Time{
+		".kuberlr-write-test-1": old,
+		".kuberlr-usage-2":      now,
+		"kubectl1.20.1.partial": old,
+		"kubectl1.20.1":         old,
+	}
+	for name, mtime := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gc := &GarbageCollector{
+		Patterns:  []string{filepath.Join(dir, ".kuberlr-*"), filepath.Join(dir, "*.partial")},
+		MaxAge:    DefaultStaleAge,
+		StampFile: filepath.Join(dir, "stamp", "last-gc"),
+	}
+	removed, err := gc.Run(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("Expected 2 files to be removed, got %v", removed)
+	}
+	for _, name := range []string{".kuberlr-usage-2", "kubectl1.20.1"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s should not have been removed", name)
+		}
+	}
+
+	// the collection doesn't happen again until MaxAge passes
+	if err := ioutil.WriteFile(filepath.Join(dir, "other.partial"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(filepath.Join(dir, "other.partial"), old, old)
+	removed, err = gc.Run(now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected no collection, got %v", removed)
+	}
+}
@@ -0,0 +1,42 @@
+package common
+
+import "fmt"
+
+type insufficientSpace interface {
+	InsufficientSpace() bool
+}
+
+// InsufficientSpaceError error is raised when there's not enough free
+// space to save a kubectl binary
+type InsufficientSpaceError struct {
+	Path      string
+	Required  uint64
+	Available uint64
+	// Reclaimable is the amount of bytes freed by ReclaimCommand, which
+	// removes the binaries that are no longer needed
+	Reclaimable    uint64
+	ReclaimCommand string
+}
+
+// Error returns a human description of the error
+func (e *InsufficientSpaceError) Error() string {
+	msg := fmt.Sprintf(
+		"Not enough free space in %s: %d bytes required, %d bytes available",
+		e.Path, e.Required, e.Available)
+	if e.Reclaimable > 0 && e.ReclaimCommand != "" {
+		msg += fmt.Sprintf(", `%s` would free %d bytes", e.ReclaimCommand, e.Reclaimable)
+	}
+	return msg
+}
+
+// InsufficientSpace returns true if the error is a InsufficientSpaceError instance
+func (e *InsufficientSpaceError) InsufficientSpace() bool {
+	return true
+}
+
+// IsInsufficientSpace returns true when the given error is of type
+// InsufficientSpaceError
+func IsInsufficientSpace(err error) bool {
+	t, ok := err.(insufficientSpace)
+	return ok && t.InsufficientSpace()
+}
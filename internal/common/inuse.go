@@ -0,0 +1,147 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// BinaryLock marks a kubectl binary as being in use. Many processes can
+// use a binary at the same time, a binary can be removed only when
+// nobody is using it
+type BinaryLock struct {
+	file *os.File
+}
+
+// LockFile returns the path to the file used to mark the given binary
+// as being in use. It sits next to the metadata of the binary and is
+// never removed
+func LockFile(binary string) string {
+	return filepath.Join(
+		filepath.Dir(binary),
+		MetadataDirName,
+		filepath.Base(binary)+".lock")
+}
+
+// DownloadLockFile returns the path to the file held by the process
+// downloading the given binary
+func DownloadLockFile(binary string) string {
+	return filepath.Join(
+		filepath.Dir(binary),
+		MetadataDirName,
+		filepath.Base(binary)+".download.lock")
+}
+
+func openLockFile(binary string) (*os.File, error) {
+	return createLockFile(LockFile(binary))
+}
+
+func createLockFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0664)
+}
+
+// LockInUse marks the given binary as being in use, waiting for the
+// removal of the binary to be completed when one is in progress
+func LockInUse(binary string) (*BinaryLock, error) {
+	f, err := openLockFile(binary)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockShared(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &BinaryLock{file: f}, nil
+}
+
+// LockForRemoval makes sure nobody is using the given binary and prevents
+// others from starting to use it. The boolean is false when the binary
+// is in use
+func LockForRemoval(binary string) (*BinaryLock, bool, error) {
+	f, err := openLockFile(binary)
+	if err != nil {
+		return nil, false, err
+	}
+	locked, err := tryLockExclusive(f)
+	if err != nil || !locked {
+		f.Close()
+		return nil, false, err
+	}
+	return &BinaryLock{file: f}, true, nil
+}
+
+// LockDownload makes sure only one process at a time downloads the given
+// binary. When another process is downloading it, onWait is invoked and
+// the call blocks until the download is over; the boolean is then true,
+// the binary downloaded by the other process can be reused
+func LockDownload(binary string, onWait func()) (*BinaryLock, bool, error) {
+	f, err := createLockFile(DownloadLockFile(binary))
+	if err != nil {
+		return nil, false, err
+	}
+	locked, err := tryLockExclusive(f)
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	if locked {
+		return &BinaryLock{file: f}, false, nil
+	}
+
+	if onWait != nil {
+		onWait()
+	}
+	if err := lockExclusive(f); err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	return &BinaryLock{file: f}, true, nil
+}
+
+// LockState serializes the updates made to the given state file by
+// concurrent processes, the call blocks until the lock is acquired. The
+// lock file sits next to the state file and is never removed
+func LockState(path string) (*BinaryLock, error) {
+	f, err := createLockFile(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	if err := lockExclusive(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &BinaryLock{file: f}, nil
+}
+
+// KeepAcrossExec makes the lock survive the exec of the binary, the binary
+// stays marked as in use until it terminates
+func (l *BinaryLock) KeepAcrossExec() error {
+	return keepAcrossExec(l.file)
+}
+
+// Release marks the binary as no longer in use
+func (l *BinaryLock) Release() error {
+	return l.file.Close()
+}
+
+// RemoveBinary removes a kubectl binary, together with its metadata. The
+// lock files are left in place: other processes could be waiting on them,
+// removing them would let two processes hold the lock of the same binary.
+// The boolean is false when the binary is in use and has been left untouched
+func RemoveBinary(binary string) (bool, error) {
+	lock, locked, err := LockForRemoval(binary)
+	if err != nil || !locked {
+		return false, err
+	}
+	defer lock.Release()
+
+	if err := os.Remove(binary); err != nil {
+		return false, err
+	}
+	if err := os.Remove(MetadataFile(binary)); err != nil && !os.IsNotExist(err) {
+		return true, err
+	}
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveBinary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-inuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "kubectl1.20.4")
+	if err := ioutil.WriteFile(binary, []byte("kubectl"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveMetadata(binary, Metadata{Version: "1.20.4"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := LockInUse(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := RemoveBinary(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed {
+		t.Fatal("A binary in use should not be removed")
+	}
+	if _, err := os.Stat(binary); err != nil {
+		t.Errorf("The binary should still exist: %v", err)
+	}
+
+	lock.Release()
+	removed, err = RemoveBinary(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !removed {
+		t.Fatal("The binary should have been removed")
+	}
+	for _, f := range []string{binary, MetadataFile(binary)} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", f)
+		}
+	}
+	if _, err := os.Stat(LockFile(binary)); err != nil {
+		t.Errorf("The lock file should be left in place: %v", err)
+	}
+}
+
+func TestLockDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-inuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "kubectl1.20.4")
+	lock, waited, err := LockDownload(binary, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited {
+		t.Error("Nobody else is downloading the binary")
+	}
+
+	waiting := make(chan struct{})
+	done := make(chan bool)
+	go func() {
+		other, waited, err := LockDownload(binary, func() { close(waiting) })
+		if err != nil {
+			t.Error(err)
+			done <- false
+			return
+		}
+		other.Release()
+		done <- waited
+	}()
+
+	<-waiting
+	lock.Release()
+	if waited := <-done; !waited {
+		t.Error("The second download should have waited for the first one")
+	}
+}
@@ -0,0 +1,58 @@
+package common
+
+import "strings"
+
+// kubectlValueFlags are the global flags of kubectl followed by a value
+// when it's not given via the "--flag=value" form
+var kubectlValueFlags = map[string]bool{
+	"--as":                    true,
+	"--as-group":              true,
+	"--as-uid":                true,
+	"--cache-dir":             true,
+	"--certificate-authority": true,
+	"--client-certificate":    true,
+	"--client-key":            true,
+	"--cluster":               true,
+	"--context":               true,
+	"--kubeconfig":            true,
+	"--log-backtrace-at":      true,
+	"--log-dir":               true,
+	"--log-file":              true,
+	"--log-file-max-size":     true,
+	"--log-flush-frequency":   true,
+	"-n":                      true,
+	"--namespace":             true,
+	"--password":              true,
+	"--profile":               true,
+	"--profile-output":        true,
+	"--request-timeout":       true,
+	"-s":                      true,
+	"--server":                true,
+	"--stderrthreshold":       true,
+	"--tls-server-name":       true,
+	"--token":                 true,
+	"--user":                  true,
+	"--username":              true,
+	"-v":                      true,
+	"--v":                     true,
+	"--vmodule":               true,
+}
+
+// CommandIndex returns the index of the kubectl command, or of the plugin,
+// inside of the given arguments: the first one that is neither a global
+// flag nor the value of one. -1 is returned when there's none
+func CommandIndex(args []string) int {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return -1
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return i
+		}
+		if kubectlValueFlags[arg] {
+			i++
+		}
+	}
+	return -1
+}
@@ -0,0 +1,23 @@
+package common
+
+import "testing"
+
+func TestCommandIndex(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{"get", "pods"}, 0},
+		{[]string{"-n", "kube-system", "ctx"}, 2},
+		{[]string{"--context", "prod", "-v", "6", "ns"}, 4},
+		{[]string{"--namespace=kube-system", "ctx"}, 1},
+		{[]string{"--context", "prod"}, -1},
+		{[]string{"--", "ctx"}, -1},
+		{[]string{}, -1},
+	}
+	for _, test := range tests {
+		if actual := CommandIndex(test.args); actual != test.expected {
+			t.Errorf("%v: got %d instead of %d", test.args, actual, test.expected)
+		}
+	}
+}
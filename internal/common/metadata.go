@@ -0,0 +1,114 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MetadataDirName is the name of the directory holding the metadata of the
+// binaries stored inside of its parent directory. Using a dedicated
+// directory ensures the metadata files are never mistaken for binaries
+const MetadataDirName = ".metadata"
+
+// Metadata describes where a kubectl binary downloaded by kuberlr
+// comes from
+type Metadata struct {
+	// Version is the version of kubectl that has been requested
+	Version string `json:"version"`
+	// SourceURL is where the binary has been downloaded from
+	SourceURL string `json:"sourceURL"`
+	// SHA256 is the checksum of the binary
+	SHA256 string `json:"sha256"`
+	// InstalledAt is when the binary has been installed
+	InstalledAt time.Time `json:"installedAt"`
+	// Context is the kubernetes context in use when the binary
+	// has been installed
+	Context string `json:"context,omitempty"`
+	// QuarantineReason explains why the binary has been moved to the
+	// quarantine directory
+	QuarantineReason string `json:"quarantineReason,omitempty"`
+	// QuarantinedAt is when the binary has been quarantined
+	QuarantinedAt time.Time `json:"quarantinedAt,omitempty"`
+}
+
+// Source returns a short description of where the binary comes from:
+// "upstream", "mirror", "plugin" or the scheme of its source URL
+func (m Metadata) Source() string {
+	u, err := url.Parse(m.SourceURL)
+	if err != nil || m.SourceURL == "" {
+		return "unknown"
+	}
+	switch u.Scheme {
+	case "https", "http":
+		if strings.HasPrefix(m.SourceURL, "https://storage.googleapis.com/kubernetes-release/") {
+			return "upstream"
+		}
+		return "mirror"
+	case "exec":
+		return "plugin"
+	default:
+		return u.Scheme
+	}
+}
+
+// MetadataFile returns the path to the file holding the metadata of the
+// given binary
+func MetadataFile(binary string) string {
+	return filepath.Join(
+		filepath.Dir(binary),
+		MetadataDirName,
+		filepath.Base(binary)+".json")
+}
+
+// SaveMetadata records the metadata of the given binary
+func SaveMetadata(binary string, m Metadata) error {
+	path := MetadataFile(binary)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// LoadMetadata returns the metadata of the given binary. The boolean is
+// false when the binary doesn't have any metadata, like the binaries
+// downloaded by older releases of kuberlr
+func LoadMetadata(binary string) (Metadata, bool, error) {
+	data, err := ioutil.ReadFile(MetadataFile(binary))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Metadata{}, false, nil
+		}
+		return Metadata{}, false, err
+	}
+
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Metadata{}, false, err
+	}
+	return m, true, nil
+}
+
+// MoveMetadata moves the metadata of a binary that is being moved to a
+// new location. Nothing is done when the binary doesn't have metadata
+func MoveMetadata(from, to string) error {
+	src := MetadataFile(from)
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+
+	dst := MetadataFile(to)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
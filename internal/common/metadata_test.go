@@ -0,0 +1,68 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "kubectl1.20.1")
+	if _, found, err := LoadMetadata(binary); err != nil || found {
+		t.Fatalf("Expected no metadata, got found=%v err=%v", found, err)
+	}
+
+	expected := Metadata{
+		Version:     "1.20.1",
+		SourceURL:   "https://example.com/kubectl",
+		SHA256:      "abc",
+		InstalledAt: time.Now().UTC().Truncate(time.Second),
+		Context:     "prod",
+	}
+	if err := SaveMetadata(binary, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	moved := filepath.Join(dir, "kubectl1.20.2")
+	if err := MoveMetadata(binary, moved); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := LoadMetadata(binary); found {
+		t.Error("Metadata has not been moved")
+	}
+
+	actual, found, err := LoadMetadata(moved)
+	if err != nil || !found {
+		t.Fatalf("Expected metadata, got found=%v err=%v", found, err)
+	}
+	if !actual.InstalledAt.Equal(expected.InstalledAt) {
+		t.Errorf("Got %v instead of %v", actual.InstalledAt, expected.InstalledAt)
+	}
+	actual.InstalledAt = expected.InstalledAt
+	if actual != expected {
+		t.Errorf("Got %+v instead of %+v", actual, expected)
+	}
+}
+
+func TestMetadataSource(t *testing.T) {
+	tests := map[string]string{
+		"": "unknown",
+		"https://storage.googleapis.com/kubernetes-release/release/v1.20.4/bin/linux/amd64/kubectl": "upstream",
+		"https://mirror.corp/release/v1.20.4/bin/linux/amd64/kubectl":                               "mirror",
+		"exec:///usr/local/bin/fetch-kubectl":                                                       "plugin",
+		"oci://registry.corp/kubectl:1.20.4":                                                        "oci",
+	}
+	for sourceURL, expected := range tests {
+		if actual := (Metadata{SourceURL: sourceURL}).Source(); actual != expected {
+			t.Errorf("%q: got %s instead of %s", sourceURL, actual, expected)
+		}
+	}
+}
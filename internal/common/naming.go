@@ -1,7 +1,13 @@
 package common
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
 	"github.com/flavio/kuberlr/internal/osexec"
 
 	"github.com/blang/semver/v4"
@@ -15,10 +21,175 @@ const KubectlLocalNamingScheme = "kubectl%d.%d.%d"
 // installed system-wide
 const KubectlSystemNamingScheme = "kubectl%d.%d"
 
+// KubectlVersionEnvKey is the name of the environment variable used to
+// force the version of kubectl. When it's set, kuberlr uses the given
+// version instead of picking one
+const KubectlVersionEnvKey = "KUBERLR_KUBECTL_VERSION"
+
+// ResolvedVersionEnvKey is the name of the environment variable used to
+// expose the version of kubectl chosen by kuberlr to its child processes.
+// It's never read by kuberlr: the kubectl invocations made by the child
+// processes pick their own version, e.g. against another context
+const ResolvedVersionEnvKey = "KUBERLR_RESOLVED_VERSION"
+
+// DefaultLocalNamingTemplate is the template used to name the kubectl binaries
+// downloaded by kuberlr, it matches KubectlLocalNamingScheme
+const DefaultLocalNamingTemplate = "kubectl{{.Major}}.{{.Minor}}.{{.Patch}}{{.Ext}}"
+
+// localNaming holds the template used to name the downloaded binaries and
+// the regular expression that matches the names it produces
+type localNaming struct {
+	tmpl   *template.Template
+	regexp *regexp.Regexp
+}
+
+var currentLocalNaming = mustLocalNaming(DefaultLocalNamingTemplate)
+
+// placeholders are used to turn the naming template into a regular
+// expression. The prerelease, like "-rc.1", follows the patch number
+var placeholders = map[string]string{
+	"Major":   "(?P<major>[0-9]+)",
+	"Minor":   "(?P<minor>[0-9]+)",
+	"Patch":   "(?P<patch>[0-9]+)(?P<pre>-[0-9A-Za-z.-]+)?",
+	"Version": "(?P<version>[0-9]+\\.[0-9]+\\.[0-9]+(?:-[0-9A-Za-z.-]+)?)",
+}
+
+var namedGroup = regexp.MustCompile(`\(\?P<[a-z]+>`)
+
+func newLocalNaming(text string) (localNaming, error) {
+	tmpl, err := template.New("naming").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return localNaming{}, fmt.Errorf("Invalid naming template %q: %v", text, err)
+	}
+
+	data := map[string]string{"Ext": osexec.Ext}
+	for key := range placeholders {
+		data[key] = "\x00" + key + "\x00"
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return localNaming{}, fmt.Errorf("Invalid naming template %q: %v", text, err)
+	}
+
+	// the regular expression couldn't tell where a placeholder ends and
+	// the next one starts
+	if strings.Contains(buf.String(), "\x00\x00") {
+		return localNaming{}, fmt.Errorf(
+			"Invalid naming template %q: the fields must be separated, like in {{.Major}}.{{.Minor}}",
+			text)
+	}
+
+	// only the first occurrence of a placeholder is captured, the name
+	// is built again once parsed to make sure the others match it
+	expr := regexp.QuoteMeta(buf.String())
+	for key, group := range placeholders {
+		marker := "\x00" + key + "\x00"
+		expr = strings.Replace(expr, marker, group, 1)
+		expr = strings.ReplaceAll(expr, marker, namedGroup.ReplaceAllString(group, "(?:"))
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return localNaming{}, fmt.Errorf("Invalid naming template %q: %v", text, err)
+	}
+
+	names := strings.Join(re.SubexpNames(), " ")
+	hasVersion := strings.Contains(names, "version")
+	hasParts := strings.Contains(names, "major") &&
+		strings.Contains(names, "minor") &&
+		strings.Contains(names, "patch")
+	if !hasVersion && !hasParts {
+		return localNaming{}, fmt.Errorf(
+			"Invalid naming template %q: it must contain either {{.Version}} or {{.Major}}, {{.Minor}} and {{.Patch}}",
+			text)
+	}
+
+	return localNaming{tmpl: tmpl, regexp: re}, nil
+}
+
+func mustLocalNaming(text string) localNaming {
+	n, err := newLocalNaming(text)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// SetLocalNamingTemplate changes the template used to name the kubectl
+// binaries downloaded by kuberlr. The template can reference these fields:
+// Major, Minor, Patch, Version and Ext
+func SetLocalNamingTemplate(text string) error {
+	if text == "" {
+		text = DefaultLocalNamingTemplate
+	}
+
+	n, err := newLocalNaming(text)
+	if err != nil {
+		return err
+	}
+	currentLocalNaming = n
+	return nil
+}
+
 // BuildKubectlNameForLocalBin returns how kuberlr will name the kubectl binary
-// with the specified version when downloading that to the user home
+// with the specified version when downloading that to the user home. The
+// prerelease, if any, follows the patch number: "kubectl1.31.0-rc.1"
 func BuildKubectlNameForLocalBin(v semver.Version) string {
-	return fmt.Sprintf(KubectlLocalNamingScheme+osexec.Ext, v.Major, v.Minor, v.Patch)
+	pre := ""
+	if len(v.Pre) > 0 {
+		ids := []string{}
+		for _, id := range v.Pre {
+			ids = append(ids, id.String())
+		}
+		pre = "-" + strings.Join(ids, ".")
+	}
+	data := map[string]string{
+		"Major":   fmt.Sprintf("%d", v.Major),
+		"Minor":   fmt.Sprintf("%d", v.Minor),
+		"Patch":   fmt.Sprintf("%d%s", v.Patch, pre),
+		"Version": fmt.Sprintf("%d.%d.%d%s", v.Major, v.Minor, v.Patch, pre),
+		"Ext":     osexec.Ext,
+	}
+
+	var buf bytes.Buffer
+	if err := currentLocalNaming.tmpl.Execute(&buf, data); err != nil {
+		// cannot happen, the template has been validated by SetLocalNamingTemplate
+		panic(err)
+	}
+	return buf.String()
+}
+
+// ParseKubectlNameForLocalBin returns the version of a kubectl binary named
+// using the current naming template
+func ParseKubectlNameForLocalBin(filename string) (semver.Version, error) {
+	re := currentLocalNaming.regexp
+	match := re.FindStringSubmatch(filename)
+	if match == nil {
+		return semver.Version{}, errors.New("Not parsable")
+	}
+
+	parts := make(map[string]string)
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			parts[name] = match[i]
+		}
+	}
+
+	var version semver.Version
+	var err error
+	if text, found := parts["version"]; found {
+		version, err = semver.Parse(text)
+	} else {
+		version, err = semver.Parse(fmt.Sprintf("%s.%s.%s%s", parts["major"], parts["minor"], parts["patch"], parts["pre"]))
+	}
+	if err != nil {
+		return semver.Version{}, err
+	}
+	// the fields used more than once, or both via Version and on their
+	// own, must be consistent
+	if BuildKubectlNameForLocalBin(version) != filename {
+		return semver.Version{}, errors.New("Not parsable")
+	}
+	return version, nil
 }
 
 // BuildKubectlNameForSystemBin returns how kuberlr expects system-wide
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+func TestNamingTemplate(t *testing.T) {
+	defer SetLocalNamingTemplate("")
+
+	v := semver.MustParse("1.20.3")
+
+	if name := BuildKubectlNameForLocalBin(v); name != "kubectl1.20.3"+osexec.Ext {
+		t.Errorf("Unexpected default name %s", name)
+	}
+
+	if err := SetLocalNamingTemplate("kubectl-{{.Major}}.{{.Minor}}.{{.Patch}}{{.Ext}}"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	name := BuildKubectlNameForLocalBin(v)
+	if name != "kubectl-1.20.3"+osexec.Ext {
+		t.Errorf("Unexpected name %s", name)
+	}
+	actual, err := ParseKubectlNameForLocalBin(name)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !actual.Equals(v) {
+		t.Errorf("Got %v instead of %v", actual, v)
+	}
+
+	if err := SetLocalNamingTemplate("kubectl_v{{.Version}}{{.Ext}}"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	actual, err = ParseKubectlNameForLocalBin("kubectl_v1.20.3" + osexec.Ext)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !actual.Equals(v) {
+		t.Errorf("Got %v instead of %v", actual, v)
+	}
+
+	if _, err := ParseKubectlNameForLocalBin("kubectl1.20.3.json"); err == nil {
+		t.Error("Expected name not matching the template to be refused")
+	}
+}
+
+func TestInvalidNamingTemplate(t *testing.T) {
+	defer SetLocalNamingTemplate("")
+
+	for _, tmpl := range []string{
+		"kubectl{{.Major}}.{{.Minor}}",
+		"kubectl{{.Major",
+		"kubectl{{.Unknown}}",
+		"kubectl{{.Major}}{{.Minor}}.{{.Patch}}",
+	} {
+		if err := SetLocalNamingTemplate(tmpl); err == nil {
+			t.Errorf("Expected template %q to be refused", tmpl)
+		}
+	}
+}
+
+func TestNamingTemplatePrerelease(t *testing.T) {
+	defer SetLocalNamingTemplate("")
+
+	v := semver.MustParse("1.31.0-rc.1")
+	for _, text := range []string{"", "kubectl_v{{.Version}}{{.Ext}}"} {
+		if err := SetLocalNamingTemplate(text); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		name := BuildKubectlNameForLocalBin(v)
+		if text == "" && name != "kubectl1.31.0-rc.1"+osexec.Ext {
+			t.Errorf("Unexpected default name %s", name)
+		}
+		actual, err := ParseKubectlNameForLocalBin(name)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		} else if !actual.Equals(v) {
+			t.Errorf("%s: got %v instead of %v", name, actual, v)
+		}
+	}
+}
+
+func TestNamingTemplateRepeatedPlaceholder(t *testing.T) {
+	defer SetLocalNamingTemplate("")
+
+	if err := SetLocalNamingTemplate("kubectl{{.Major}}.{{.Minor}}.{{.Patch}}-{{.Major}}{{.Ext}}"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	v := semver.MustParse("1.20.3")
+	name := BuildKubectlNameForLocalBin(v)
+	if name != "kubectl1.20.3-1"+osexec.Ext {
+		t.Errorf("Unexpected name %s", name)
+	}
+	actual, err := ParseKubectlNameForLocalBin(name)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	} else if !actual.Equals(v) {
+		t.Errorf("Got %v instead of %v", actual, v)
+	}
+
+	if _, err := ParseKubectlNameForLocalBin("kubectl1.20.3-2" + osexec.Ext); err == nil {
+		t.Error("Expected name with inconsistent fields to be refused")
+	}
+}
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// UsePureGoResolver forces the usage of the DNS resolver written in Go, even
+// when kuberlr has been built with cgo support. This ensures names are
+// resolved in the same way regardless of the C library available on the host
+// (glibc, musl,...)
+func UsePureGoResolver() {
+	net.DefaultResolver.PreferGo = true
+}
+
+// proxy chooses the proxy of each request made by kuberlr, nil when the
+// environment variables are honored
+var proxy func(*http.Request) (*url.URL, error)
+
+// SetProxy makes kuberlr reach the API servers and the mirrors through the
+// given proxy, ignoring the HTTP_PROXY and HTTPS_PROXY environment
+// variables. The hosts matching noProxy, which uses the format of the
+// NO_PROXY environment variable, are reached directly. Empty values keep
+// the ones set by the environment
+func SetProxy(proxyURL, noProxy string) error {
+	if proxyURL == "" && noProxy == "" {
+		return nil
+	}
+
+	cfg := httpproxy.FromEnvironment()
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("Invalid proxy URL %q", proxyURL)
+		}
+		cfg.HTTPProxy = proxyURL
+		cfg.HTTPSProxy = proxyURL
+	}
+	if noProxy != "" {
+		cfg.NoProxy = noProxy
+	}
+
+	proxyFunc := cfg.ProxyFunc()
+	proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+	// the clients built on top of the default transport, like
+	// http.DefaultClient, use the proxy too
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.Proxy = proxy
+	}
+	return nil
+}
+
+// Timeouts holds the time limits of the different phases of an HTTP
+// request. A zero value means there's no limit
+type Timeouts struct {
+	// Dial limits the time spent opening the connection, unroutable
+	// hosts are detected by this one
+	Dial time.Duration
+	// TLSHandshake limits the time spent negotiating TLS
+	TLSHandshake time.Duration
+	// ResponseHeader limits the time spent waiting for the headers of the
+	// response once the request has been sent
+	ResponseHeader time.Duration
+	// Overall limits the whole request, including the read of the body
+	Overall time.Duration
+	// Stall limits the time spent waiting for the next bytes of the body.
+	// It's enforced by the readers of the body, not by the client
+	Stall time.Duration
+}
+
+// Apply sets the connection timeouts, and the proxy configured via
+// SetProxy, on the given transport. The overall timeout is a property of
+// the client and is not handled here
+func (t Timeouts) Apply(transport *http.Transport) {
+	if proxy != nil {
+		transport.Proxy = proxy
+	}
+	dialer := &net.Dialer{
+		Timeout:   t.Dial,
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialContext = dialer.DialContext
+	if offline {
+		transport.Proxy = nil
+		transport.DialContext = offlineDial
+	}
+	transport.TLSHandshakeTimeout = t.TLSHandshake
+	transport.ResponseHeaderTimeout = t.ResponseHeader
+}
+
+// Client returns an HTTP client honoring the timeouts
+func (t Timeouts) Client() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	t.Apply(transport)
+	return &http.Client{
+		Transport: transport,
+		Timeout:   t.Overall,
+	}
+}
+
+// LoadCABundle returns the certificates trusted by the system together with
+// the ones found inside of the given PEM file, which is meant to hold the
+// private CAs signing the certificates of internal mirrors
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("No PEM encoded certificate found inside of %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,143 @@
+package common
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTimeoutsResponseHeader(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := Timeouts{ResponseHeader: 50 * time.Millisecond}.Client()
+	_, err := client.Get(server.URL)
+	if err == nil {
+		t.Fatal("Expected the request to time out")
+	}
+	if !os.IsTimeout(err) {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+}
+
+func TestTimeoutsNoLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	res, err := Timeouts{}.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestSetProxy(t *testing.T) {
+	defaultTransport := http.DefaultTransport.(*http.Transport)
+	previous, previousDefault := proxy, defaultTransport.Proxy
+	defer func() {
+		proxy, defaultTransport.Proxy = previous, previousDefault
+	}()
+
+	proxied := []string{}
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxyServer.Close()
+
+	if err := SetProxy("not a url", ""); err == nil {
+		t.Error("Expected invalid proxy URLs to be refused")
+	}
+	if err := SetProxy(proxyServer.URL, "internal.example.com,.corp"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	res, err := Timeouts{}.Client().Get("http://mirror.example.com/stable.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if len(proxied) != 1 || proxied[0] != "http://mirror.example.com/stable.txt" {
+		t.Errorf("Unexpected proxied requests %v", proxied)
+	}
+
+	for _, host := range []string{"internal.example.com", "api.corp"} {
+		req, _ := http.NewRequest("GET", "https://"+host, nil)
+		if u, err := proxy(req); err != nil || u != nil {
+			t.Errorf("%s should be reached directly, got %v %v", host, u, err)
+		}
+	}
+}
+
+func TestLoadCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(bundle, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := LoadCABundle(bundle)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("The certificate of the server is not trusted: %v", err)
+	}
+	resp.Body.Close()
+
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := ioutil.WriteFile(garbage, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCABundle(garbage); err == nil {
+		t.Error("Expected an error loading a file without certificates")
+	}
+}
+
+func TestSetOffline(t *testing.T) {
+	defaultTransport := http.DefaultTransport.(*http.Transport)
+	previousDial, previousProxy := defaultTransport.DialContext, defaultTransport.Proxy
+	defer func() {
+		offline = false
+		defaultTransport.DialContext, defaultTransport.Proxy = previousDial, previousProxy
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request %s", r.URL)
+	}))
+	defer server.Close()
+
+	SetOffline()
+	if !IsOffline() {
+		t.Error("kuberlr should be offline")
+	}
+	for _, client := range []*http.Client{Timeouts{}.Client(), http.DefaultClient} {
+		if _, err := client.Get(server.URL); !errors.Is(err, ErrOffline) {
+			t.Errorf("Expected the request to fail with %v, got %v", ErrOffline, err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ErrOffline is returned by every attempt to reach the network while
+// kuberlr is offline
+var ErrOffline = errors.New("kuberlr is offline, network access is disabled")
+
+// offline is true once SetOffline has been called
+var offline bool
+
+// SetOffline prevents kuberlr from reaching the network: the HTTP clients
+// built afterwards, and the ones built on top of the default transport,
+// fail every request with ErrOffline
+func SetOffline() {
+	offline = true
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.Proxy = nil
+		transport.DialContext = offlineDial
+	}
+}
+
+// IsOffline returns true when kuberlr must not reach the network
+func IsOffline() bool {
+	return offline
+}
+
+func offlineDial(ctx context.Context, network, addr string) (net.Conn, error) {
+	return nil, ErrOffline
+}
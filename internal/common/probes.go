@@ -0,0 +1,92 @@
+package common
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// MaxProbeSamples is the number of probes remembered for each context
+const MaxProbeSamples = 50
+
+// ProbeSample describes a request made to find the version of the
+// kubernetes API server of a context
+type ProbeSample struct {
+	At      time.Time     `json:"at"`
+	Latency time.Duration `json:"latency"`
+	Failed  bool          `json:"failed,omitempty"`
+}
+
+// Probes maps each kubernetes context to its most recent probes
+type Probes map[string][]ProbeSample
+
+// ProbeSummary describes the recent probes of a context
+type ProbeSummary struct {
+	Context     string        `json:"context"`
+	Samples     int           `json:"samples"`
+	AvgLatency  time.Duration `json:"avgLatency"`
+	MaxLatency  time.Duration `json:"maxLatency"`
+	FailureRate float64       `json:"failureRate"`
+	LastProbe   time.Time     `json:"lastProbe"`
+}
+
+// ProbesFile returns the path to the file recording the recent probes
+func ProbesFile() string {
+	return filepath.Join(KuberlrDir(), "probes.json")
+}
+
+// LoadProbes reads the recent probes, empty Probes are returned when
+// nothing has been recorded yet
+func LoadProbes(path string) (Probes, error) {
+	probes := Probes{}
+	err := loadStateFile(path, &probes)
+	return probes, err
+}
+
+// RecordProbe records a probe made against the API server of the given
+// context, only the last MaxProbeSamples probes of each context are kept
+func RecordProbe(path, context string, latency time.Duration, failed bool, now time.Time) error {
+	probes := Probes{}
+	return UpdateStateFile(path, &probes, func() {
+		samples := append(probes[context], ProbeSample{At: now, Latency: latency, Failed: failed})
+		if len(samples) > MaxProbeSamples {
+			samples = samples[len(samples)-MaxProbeSamples:]
+		}
+		probes[context] = samples
+	})
+}
+
+// Summaries returns the summary of the probes of each context, sorted
+// by context name
+func (p Probes) Summaries() []ProbeSummary {
+	summaries := []ProbeSummary{}
+	for context, samples := range p {
+		if len(samples) == 0 {
+			continue
+		}
+
+		s := ProbeSummary{Context: context, Samples: len(samples)}
+		var total time.Duration
+		failures := 0
+		for _, sample := range samples {
+			total += sample.Latency
+			if sample.Latency > s.MaxLatency {
+				s.MaxLatency = sample.Latency
+			}
+			if sample.Failed {
+				failures++
+			}
+			if sample.At.After(s.LastProbe) {
+				s.LastProbe = sample.At
+			}
+		}
+		s.AvgLatency = total / time.Duration(len(samples))
+		s.FailureRate = float64(failures) / float64(len(samples))
+		summaries = append(summaries, s)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Context < summaries[j].Context
+	})
+	return summaries
+}
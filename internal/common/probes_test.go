@@ -0,0 +1,52 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordProbe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-probes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "probes.json")
+	now := time.Now().UTC()
+
+	for i := 0; i < MaxProbeSamples+10; i++ {
+		if err := RecordProbe(path, "prod", 100*time.Millisecond, false, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordProbe(path, "dev", 100*time.Millisecond, false, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordProbe(path, "dev", 300*time.Millisecond, true, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	probes, err := LoadProbes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(probes["prod"]) != MaxProbeSamples {
+		t.Errorf("Expected %d samples, got %d", MaxProbeSamples, len(probes["prod"]))
+	}
+
+	summaries := probes.Summaries()
+	if len(summaries) != 2 || summaries[0].Context != "dev" {
+		t.Fatalf("Unexpected summaries %+v", summaries)
+	}
+	dev := summaries[0]
+	if dev.AvgLatency != 200*time.Millisecond || dev.MaxLatency != 300*time.Millisecond {
+		t.Errorf("Unexpected latencies %+v", dev)
+	}
+	if dev.FailureRate != 0.5 || !dev.LastProbe.Equal(now.Add(time.Minute)) {
+		t.Errorf("Unexpected summary %+v", dev)
+	}
+}
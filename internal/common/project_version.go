@@ -0,0 +1,67 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// ProjectVersionFileName is the name of the file pinning the version of
+// kubectl used inside of a project, like `.nvmrc` does for node
+const ProjectVersionFileName = ".kubectl-version"
+
+// FindProjectVersion looks for a .kubectl-version file inside of the given
+// directory and its parents. The version and the path of the file closest
+// to dir are returned, the boolean is false when there's none
+func FindProjectVersion(dir string) (semver.Version, string, bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return semver.Version{}, "", false, err
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectVersionFileName)
+		v, found, err := loadProjectVersion(path)
+		if err != nil {
+			return semver.Version{}, path, false, err
+		}
+		if found {
+			return v, path, true, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return semver.Version{}, "", false, nil
+		}
+		dir = parent
+	}
+}
+
+// loadProjectVersion reads the version from the first line of the given
+// file that is neither empty nor a comment
+func loadProjectVersion(path string) (semver.Version, bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return semver.Version{}, false, nil
+		}
+		return semver.Version{}, false, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		v, err := semver.ParseTolerant(line)
+		if err != nil {
+			return semver.Version{}, false, fmt.Errorf("Invalid kubectl version inside of %s: %v", path, err)
+		}
+		return v, true, nil
+	}
+	return semver.Version{}, false, fmt.Errorf("No kubectl version inside of %s", path)
+}
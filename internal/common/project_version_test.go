@@ -0,0 +1,55 @@
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestFindProjectVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-project-version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nested := filepath.Join(dir, "deploy", "overlays", "prod")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, found, err := common.FindProjectVersion(nested); err != nil || found {
+		t.Errorf("No version should be found, got %v %v", found, err)
+	}
+
+	path := filepath.Join(dir, common.ProjectVersionFileName)
+	if err := ioutil.WriteFile(path, []byte("# tested with\nv1.28.4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v, actualPath, found, err := common.FindProjectVersion(nested)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !found || v.String() != "1.28.4" || actualPath != path {
+		t.Errorf("Got %v %s %v instead of 1.28.4 from %s", found, v, actualPath, path)
+	}
+
+	// the closest file wins
+	closest := filepath.Join(dir, "deploy", common.ProjectVersionFileName)
+	if err := ioutil.WriteFile(closest, []byte("1.27.1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, _, _ := common.FindProjectVersion(nested); v.String() != "1.27.1" {
+		t.Errorf("Got %s instead of 1.27.1", v)
+	}
+
+	if err := ioutil.WriteFile(closest, []byte("latest"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := common.FindProjectVersion(nested); err == nil {
+		t.Error("An invalid version should be reported")
+	}
+}
@@ -0,0 +1,107 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// QuarantineRetention is for how long the quarantined binaries are kept
+const QuarantineRetention = 30 * 24 * time.Hour
+
+// QuarantineMaxBinaries is how many quarantined binaries are kept at most,
+// the oldest ones are removed first
+const QuarantineMaxBinaries = 20
+
+// Quarantine moves a binary that failed a verification to the given
+// quarantine directory, instead of deleting it, so that it can be
+// inspected later. Its metadata is moved as well and records the reason.
+// The binaries quarantined long ago are expired. The new path of the
+// binary is returned
+func Quarantine(binary, quarantineDir string, m Metadata, reason string, now time.Time) (string, error) {
+	if err := os.MkdirAll(quarantineDir, 0700); err != nil {
+		return "", err
+	}
+	destination := filepath.Join(
+		quarantineDir,
+		fmt.Sprintf("%s.%s", filepath.Base(binary), now.Format("20060102150405")))
+
+	if err := os.Rename(binary, destination); err != nil {
+		// the binary might be on another filesystem, like the
+		// temporary directory
+		data, readErr := ioutil.ReadFile(binary)
+		if readErr != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(destination, data, 0600); err != nil {
+			return "", err
+		}
+		os.Remove(binary)
+	}
+	// the binary cannot be trusted, nobody should run it by mistake
+	os.Chmod(destination, 0600)
+
+	if existing, found, err := LoadMetadata(binary); err == nil && found {
+		m = existing
+		os.Remove(MetadataFile(binary))
+	}
+	m.QuarantineReason = reason
+	m.QuarantinedAt = now.UTC()
+	if err := SaveMetadata(destination, m); err != nil {
+		return destination, err
+	}
+	if err := ExpireQuarantine(quarantineDir, now); err != nil {
+		klog.V(1).Infof("Cannot expire the binaries inside of %s: %v", quarantineDir, err)
+	}
+	return destination, nil
+}
+
+// ExpireQuarantine removes the binaries quarantined for longer than
+// QuarantineRetention, and the oldest ones when there are more than
+// QuarantineMaxBinaries
+func ExpireQuarantine(quarantineDir string, now time.Time) error {
+	entries, err := ioutil.ReadDir(quarantineDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	type quarantined struct {
+		path string
+		at   time.Time
+	}
+	binaries := []quarantined{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		b := quarantined{path: filepath.Join(quarantineDir, e.Name()), at: e.ModTime()}
+		if m, found, err := LoadMetadata(b.path); err == nil && found && !m.QuarantinedAt.IsZero() {
+			b.at = m.QuarantinedAt
+		}
+		binaries = append(binaries, b)
+	}
+	sort.Slice(binaries, func(i, j int) bool {
+		return binaries[i].at.After(binaries[j].at)
+	})
+
+	for i, b := range binaries {
+		if i < QuarantineMaxBinaries && now.Sub(b.at) <= QuarantineRetention {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Remove(MetadataFile(b.path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
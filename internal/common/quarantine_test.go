@@ -0,0 +1,94 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-quarantine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "store", "kubectl1.20.4")
+	if err := os.MkdirAll(filepath.Dir(binary), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(binary, []byte("tampered"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveMetadata(binary, Metadata{Version: "1.20.4", SourceURL: "https://mirror"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	quarantined, err := Quarantine(binary, filepath.Join(dir, "quarantine"), Metadata{}, "checksum mismatch", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(binary); !os.IsNotExist(err) {
+		t.Errorf("The binary should have been moved")
+	}
+	if _, err := os.Stat(MetadataFile(binary)); !os.IsNotExist(err) {
+		t.Errorf("The metadata should have been moved")
+	}
+	m, found, err := LoadMetadata(quarantined)
+	if err != nil || !found {
+		t.Fatalf("Cannot load metadata of quarantined binary: %v", err)
+	}
+	if m.SourceURL != "https://mirror" || m.QuarantineReason != "checksum mismatch" || !m.QuarantinedAt.Equal(now) {
+		t.Errorf("Unexpected metadata %+v", m)
+	}
+}
+
+func TestExpireQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-quarantine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	quarantineDir := filepath.Join(dir, "quarantine")
+	quarantined := []string{}
+	for i := 0; i < QuarantineMaxBinaries+2; i++ {
+		binary := filepath.Join(dir, fmt.Sprintf("kubectl1.20.%d", i))
+		if err := ioutil.WriteFile(binary, []byte("tampered"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		// the first binary has been quarantined long ago
+		at := now.Add(time.Duration(i) * time.Minute)
+		if i == 0 {
+			at = now.Add(-QuarantineRetention - time.Hour)
+		}
+		path, err := Quarantine(binary, quarantineDir, Metadata{}, "checksum mismatch", at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		quarantined = append(quarantined, path)
+	}
+	if err := ExpireQuarantine(quarantineDir, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, path := range quarantined {
+		_, err := os.Stat(path)
+		expired := i < 2
+		if expired && !os.IsNotExist(err) {
+			t.Errorf("%s should have been expired", path)
+		}
+		if !expired && err != nil {
+			t.Errorf("%s should have been kept: %v", path, err)
+		}
+		if _, err := os.Stat(MetadataFile(path)); expired && !os.IsNotExist(err) {
+			t.Errorf("The metadata of %s should have been removed", path)
+		}
+	}
+}
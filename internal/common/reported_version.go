@@ -0,0 +1,40 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// versionTimeout is the amount of time a kubectl binary is given to
+// report its version
+const versionTimeout = 10 * time.Second
+
+// kubectlVersionOutput is the output of `kubectl version --client -o json`
+type kubectlVersionOutput struct {
+	ClientVersion struct {
+		GitVersion string `json:"gitVersion"`
+	} `json:"clientVersion"`
+}
+
+// ReportedKubectlVersion executes the given kubectl binary and returns
+// the version it reports
+func ReportedKubectlVersion(path string) (semver.Version, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "version", "--client", "--output=json").Output()
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("Cannot execute %s: %v", path, err)
+	}
+
+	var info kubectlVersionOutput
+	if err := json.Unmarshal(out, &info); err != nil {
+		return semver.Version{}, fmt.Errorf("Cannot parse the version reported by %s: %v", path, err)
+	}
+	return semver.ParseTolerant(info.ClientVersion.GitVersion)
+}
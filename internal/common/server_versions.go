@@ -0,0 +1,58 @@
+package common
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// ServerVersion is the version of a kubernetes API server found at a
+// given time
+type ServerVersion struct {
+	Version   string    `json:"version"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// ServerVersions maps the address of each API server to its version
+type ServerVersions map[string]ServerVersion
+
+// ServerVersionsFile returns the path to the file caching the versions of
+// the API servers
+func ServerVersionsFile() string {
+	return filepath.Join(KuberlrDir(), "server-versions.json")
+}
+
+// LoadServerVersions reads the cached versions of the API servers, empty
+// ServerVersions are returned when nothing has been cached yet
+func LoadServerVersions(path string) (ServerVersions, error) {
+	versions := ServerVersions{}
+	err := loadStateFile(path, &versions)
+	return versions, err
+}
+
+// CachedServerVersion returns the version of the given API server when it
+// has been found less than ttl ago
+func CachedServerVersion(path, server string, now time.Time, ttl time.Duration) (semver.Version, bool) {
+	versions, err := LoadServerVersions(path)
+	if err != nil {
+		return semver.Version{}, false
+	}
+	cached, found := versions[server]
+	if !found || now.Sub(cached.CheckedAt) >= ttl || now.Before(cached.CheckedAt) {
+		return semver.Version{}, false
+	}
+	v, err := semver.Parse(cached.Version)
+	if err != nil {
+		return semver.Version{}, false
+	}
+	return v, true
+}
+
+// RecordServerVersion caches the version of the given API server
+func RecordServerVersion(path, server string, version semver.Version, now time.Time) error {
+	versions := ServerVersions{}
+	return UpdateStateFile(path, &versions, func() {
+		versions[server] = ServerVersion{Version: version.String(), CheckedAt: now}
+	})
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestCachedServerVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-server-versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server-versions.json")
+	now := time.Now().UTC()
+	server := "https://prod.example.com:6443"
+
+	if _, found := CachedServerVersion(path, server, now, time.Minute); found {
+		t.Error("Nothing should be cached yet")
+	}
+
+	if err := RecordServerVersion(path, server, semver.MustParse("1.28.7"), now); err != nil {
+		t.Fatal(err)
+	}
+	v, found := CachedServerVersion(path, server, now.Add(30*time.Second), time.Minute)
+	if !found || v.String() != "1.28.7" {
+		t.Errorf("Got %v %s instead of the cached 1.28.7", found, v)
+	}
+	if _, found := CachedServerVersion(path, "https://dev.example.com", now, time.Minute); found {
+		t.Error("The version of another server should not be returned")
+	}
+	if _, found := CachedServerVersion(path, server, now.Add(time.Minute), time.Minute); found {
+		t.Error("An expired version should not be returned")
+	}
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// SharedStore describes a directory holding the kubectl binaries downloaded
+// by kuberlr on behalf of all the users that are part of the same group
+type SharedStore struct {
+	// Path is the root of the shared store
+	Path string
+	// Group is the name of the group owning the binaries, when empty
+	// the primary group of the user doing the download is used
+	Group string
+}
+
+// Enabled returns true when a shared store has been configured
+func (s SharedStore) Enabled() bool {
+	return s.Path != ""
+}
+
+// Dir returns the directory holding the binaries of the current platform
+func (s SharedStore) Dir() string {
+	return filepath.Join(s.Path, platform())
+}
+
+// Prepare creates the directory of the current platform, making sure the
+// files created inside of it are owned by the group of the store
+func (s SharedStore) Prepare() error {
+	return prepareSharedDir(s.Dir(), s.Group)
+}
+
+// Usable returns true when the current user can save binaries
+// inside of the shared store
+func (s SharedStore) Usable() bool {
+	if !s.Enabled() {
+		return false
+	}
+	if err := s.Prepare(); err != nil {
+		return false
+	}
+	return IsWritableDir(s.Dir())
+}
+
+// Share makes the given file, and its metadata, usable by all the members
+// of the group of the store
+func (s SharedStore) Share(path string) error {
+	if err := shareFile(path, s.Group); err != nil {
+		return err
+	}
+
+	metadata := MetadataFile(path)
+	if _, err := os.Stat(metadata); err != nil {
+		return nil
+	}
+	if err := prepareSharedDir(filepath.Dir(metadata), s.Group); err != nil {
+		return err
+	}
+	return shareFile(metadata, s.Group)
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSharedStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if (SharedStore{}).Enabled() {
+		t.Error("Store without path should be disabled")
+	}
+
+	store := SharedStore{Path: dir}
+	if !store.Usable() {
+		t.Fatal("Expected store to be usable")
+	}
+
+	bin := filepath.Join(store.Dir(), "kubectl1.20.0")
+	if err := ioutil.WriteFile(bin, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Share(bin); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	info, err := os.Stat(store.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSetgid == 0 {
+		t.Errorf("Expected setgid directory, got %v", info.Mode())
+	}
+
+	info, err = os.Stat(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0775 {
+		t.Errorf("Expected group writable file, got %v", info.Mode())
+	}
+}
@@ -0,0 +1,66 @@
+//go:build unix
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+func lookupGid(group string) (int, error) {
+	if group == "" {
+		return -1, nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return -1, err
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return -1, fmt.Errorf("Invalid gid %s for group %s", g.Gid, group)
+	}
+	return gid, nil
+}
+
+// prepareSharedDir creates a group writable directory with the setgid bit
+// set, files created inside of it inherit the group of the directory
+func prepareSharedDir(dir, group string) error {
+	gid, err := lookupGid(group)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(dir)
+	if err == nil && info.Mode()&os.ModeSetgid != 0 {
+		// already prepared, possibly by another user
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return err
+	}
+	if gid != -1 {
+		if err := os.Chown(dir, -1, gid); err != nil {
+			return err
+		}
+	}
+	// chmod is required, the umask could have removed the group bits
+	return os.Chmod(dir, 0775|os.ModeSetgid)
+}
+
+// shareFile makes the file readable, writable and executable by the group
+func shareFile(path, group string) error {
+	gid, err := lookupGid(group)
+	if err != nil {
+		return err
+	}
+	if gid != -1 {
+		if err := os.Chown(path, -1, gid); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(path, 0775)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package common
+
+import "os"
+
+// prepareSharedDir creates the directory. Windows doesn't have the concept
+// of setgid directories, access is controlled via the ACLs inherited from
+// the parent directory
+func prepareSharedDir(dir, group string) error {
+	return os.MkdirAll(dir, 0775)
+}
+
+// shareFile does nothing, the ACLs of the file are inherited from
+// the shared directory
+func shareFile(path, group string) error {
+	return nil
+}
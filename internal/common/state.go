@@ -0,0 +1,61 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+// loadStateFile decodes the JSON document stored inside of the given file
+// into state, which must be a pointer. state is left untouched when the
+// file doesn't exist or cannot be decoded
+func loadStateFile(path string, state interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	decoded := reflect.New(reflect.TypeOf(state).Elem())
+	if err := json.Unmarshal(data, decoded.Interface()); err != nil {
+		return err
+	}
+	// a "null" document doesn't replace the empty map given by the caller
+	if decoded.Elem().Kind() == reflect.Map && decoded.Elem().IsNil() {
+		return nil
+	}
+	reflect.ValueOf(state).Elem().Set(decoded.Elem())
+	return nil
+}
+
+// UpdateStateFile loads the state recorded inside of the given file, applies
+// the update and saves it back. The file is locked meanwhile, concurrent
+// invocations of kuberlr would lose each other's changes otherwise. state
+// must point to an empty value, which is used when the file is corrupted:
+// the recorded state is not worth a failure
+func UpdateStateFile(path string, state interface{}, update func()) error {
+	lock, err := LockState(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if err := loadStateFile(path, state); err != nil {
+		klog.V(1).Infof("Ignoring the state recorded inside of %s: %v", path, err)
+	}
+	update()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return WriteFileAtomic(path, data, ".kuberlr-"+name+"-")
+}
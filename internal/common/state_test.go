@@ -0,0 +1,63 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateStateFileConcurrently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "usage.json")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			binary := fmt.Sprintf("/bin/kubectl1.20.%d", i)
+			if err := RecordUsage(path, binary, "prod", time.Now()); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	usage, err := LoadUsage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 20 {
+		t.Errorf("Expected the usage of 20 binaries, got %d", len(usage))
+	}
+}
+
+func TestUpdateCorruptedStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, contents := range []string{"{not json", "null"} {
+		path := filepath.Join(dir, "usage.json")
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := RecordUsage(path, "/bin/kubectl1.20.4", "prod", time.Now()); err != nil {
+			t.Fatalf("%q: unexpected error %v", contents, err)
+		}
+		usage, err := LoadUsage(path)
+		if err != nil || len(usage) != 1 {
+			t.Errorf("%q: got %v, %v", contents, usage, err)
+		}
+	}
+}
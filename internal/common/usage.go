@@ -0,0 +1,63 @@
+package common
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// Usage maps the path of each kubectl binary to the kubernetes contexts
+// it has been used with, and when that happened for the last time
+type Usage map[string]map[string]time.Time
+
+// UsageFile returns the path to the file recording when the kubectl
+// binaries have been used
+func UsageFile() string {
+	return filepath.Join(KuberlrDir(), "usage.json")
+}
+
+// LoadUsage reads the usage of the kubectl binaries, an empty Usage is
+// returned when nothing has been recorded yet
+func LoadUsage(path string) (Usage, error) {
+	usage := Usage{}
+	err := loadStateFile(path, &usage)
+	return usage, err
+}
+
+// RecordUsage records the given binary has just been used with the
+// given context
+func RecordUsage(path, binary, context string, now time.Time) error {
+	usage := Usage{}
+	return UpdateStateFile(path, &usage, func() {
+		if _, found := usage[binary]; !found {
+			usage[binary] = map[string]time.Time{}
+		}
+		usage[binary][context] = now
+	})
+}
+
+// LastUsed returns when the given binary has been used for the last time,
+// regardless of the context. The boolean is false when the binary has
+// never been used
+func (u Usage) LastUsed(binary string) (time.Time, bool) {
+	var last time.Time
+	for _, t := range u[binary] {
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last, !last.IsZero()
+}
+
+// LastUsedWith returns the binary used for the last time with the given
+// context. The boolean is false when no binary has been used with it
+func (u Usage) LastUsedWith(context string) (string, bool) {
+	var binary string
+	var last time.Time
+	for b, contexts := range u {
+		if t, found := contexts[context]; found && t.After(last) {
+			binary = b
+			last = t
+		}
+	}
+	return binary, binary != ""
+}
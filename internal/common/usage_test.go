@@ -0,0 +1,62 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "usage.json")
+	first := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	if err := RecordUsage(path, "/bin/kubectl1.20.1", "prod", first); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordUsage(path, "/bin/kubectl1.20.1", "staging", second); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := LoadUsage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage["/bin/kubectl1.20.1"]) != 2 {
+		t.Errorf("Expected 2 contexts, got %+v", usage)
+	}
+
+	last, found := usage.LastUsed("/bin/kubectl1.20.1")
+	if !found || !last.Equal(second) {
+		t.Errorf("Got %v instead of %v", last, second)
+	}
+	if _, found := usage.LastUsed("/bin/kubectl1.19.0"); found {
+		t.Error("Binary never used")
+	}
+}
+
+func TestUsageLastUsedWith(t *testing.T) {
+	first := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	usage := Usage{
+		"/bin/kubectl1.19.3": {"prod": first, "staging": first.Add(2 * time.Hour)},
+		"/bin/kubectl1.20.1": {"prod": first.Add(time.Hour)},
+	}
+
+	if binary, found := usage.LastUsedWith("prod"); !found || binary != "/bin/kubectl1.20.1" {
+		t.Errorf("Got %q instead of /bin/kubectl1.20.1", binary)
+	}
+	if binary, found := usage.LastUsedWith("staging"); !found || binary != "/bin/kubectl1.19.3" {
+		t.Errorf("Got %q instead of /bin/kubectl1.19.3", binary)
+	}
+	if _, found := usage.LastUsedWith("dev"); found {
+		t.Error("No binary has been used with the dev context")
+	}
+}
@@ -27,8 +27,65 @@ func NewCfg() *Cfg {
 func (c *Cfg) Load() (*viper.Viper, error) {
 	v := viper.New()
 	v.SetDefault("AllowDownload", true)
+	v.SetDefault("Offline", false)
 	v.SetDefault("SystemPath", common.SystemPath)
 	v.SetDefault("Timeout", 5)
+	v.SetDefault("APITimeout", "")
+	v.SetDefault("ServerVersionCacheTTL", "0s")
+	v.SetDefault("DownloadTimeout", "")
+	v.SetDefault("PreferSystem", false)
+	v.SetDefault("FallbackPreference", "")
+	v.SetDefault("Fallback", []string{})
+	v.SetDefault("VersionMatch", "skew")
+	v.SetDefault("WarningInterval", "24h")
+	v.SetDefault("SilencedWarnings", []string{})
+	v.SetDefault("PureGoResolver", false)
+	v.SetDefault("NamingTemplate", common.DefaultLocalNamingTemplate)
+	v.SetDefault("SharedStore", "")
+	v.SetDefault("SharedGroup", "")
+	v.SetDefault("PluginCheck", "warn")
+	v.SetDefault("PluginKubectlVersions", map[string]string{})
+	v.SetDefault("ContextVersions", map[string]string{})
+	v.SetDefault("LinkStrategy", "auto")
+	v.SetDefault("ProgressStyle", "detailed")
+	v.SetDefault("GitHubToken", "")
+	v.SetDefault("EOLCheck", "warn")
+	v.SetDefault("AutoUpgrade", "off")
+	v.SetDefault("SourcePlugin", "")
+	v.SetDefault("PolicyURL", "")
+	v.SetDefault("PolicyPublicKey", "")
+	v.SetDefault("PruneKeepLast", 0)
+	v.SetDefault("PruneUnusedFor", "")
+	v.SetDefault("AutoPrune", "off")
+	v.SetDefault("KustomizeCheck", "off")
+	v.SetDefault("KustomizeVersion", "")
+	v.SetDefault("DownloadNotification", "off")
+	v.SetDefault("PostDownloadCommand", []string{})
+	v.SetDefault("DefaultArgs", map[string][]string{})
+	v.SetDefault("DownloadURL", "")
+	v.SetDefault("DownloadURLTemplate", "")
+	v.SetDefault("DownloadProxy", "")
+	v.SetDefault("DownloadMirrors", []string{})
+	v.SetDefault("VerifySignatures", false)
+	v.SetDefault("CosignPath", "cosign")
+	v.SetDefault("SanityCheck", true)
+	v.SetDefault("DownloadRetries", 2)
+	v.SetDefault("DownloadRetryDelay", "1s")
+	v.SetDefault("ProxyURL", "")
+	v.SetDefault("NoProxy", "")
+	v.SetDefault("MaxDownloadRate", "")
+	v.SetDefault("OCIRepository", "")
+	v.SetDefault("DownloadCAFile", "")
+	v.SetDefault("DownloadAuthHeader", "")
+	v.SetDefault("DownloadNetrc", false)
+	v.SetDefault("StableVersionCacheTTL", "24h")
+	for _, client := range []string{"ProbeTimeouts", "DownloadTimeouts"} {
+		v.SetDefault(client+".Dial", "30s")
+		v.SetDefault(client+".TLSHandshake", "10s")
+		v.SetDefault(client+".ResponseHeader", "0s")
+		v.SetDefault(client+".Overall", "0s")
+	}
+	v.SetDefault("DownloadTimeouts.Stall", "30s")
 
 	v.SetConfigType("toml")
 
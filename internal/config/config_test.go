@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 type testData struct {
@@ -159,3 +160,40 @@ AllowDownload = true
 			v.GetString("SystemPath"), "global")
 	}
 }
+
+func TestPartialTimeoutsTable(t *testing.T) {
+	td, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer teardown(td)
+
+	homeCfg := `
+[DownloadTimeouts]
+Overall = "10m"
+`
+	err = writeConfig(td.FakeHome, homeCfg)
+	if err != nil {
+		t.Error(err)
+	}
+
+	c := Cfg{
+		Paths: []string{td.FakeUsrEtc, td.FakeEtc, td.FakeHome},
+	}
+
+	v, err := c.Load()
+	if err != nil {
+		t.Errorf("Unexpected error loading config: %v", err)
+	}
+
+	if v.GetDuration("DownloadTimeouts.Overall") != 10*time.Minute {
+		t.Errorf(
+			"Wrong value for DownloadTimeouts.Overall: got %v instead of %v",
+			v.GetDuration("DownloadTimeouts.Overall"), 10*time.Minute)
+	}
+	if v.GetDuration("DownloadTimeouts.Dial") != 30*time.Second {
+		t.Errorf(
+			"Wrong value for DownloadTimeouts.Dial: got %v instead of %v",
+			v.GetDuration("DownloadTimeouts.Dial"), 30*time.Second)
+	}
+}
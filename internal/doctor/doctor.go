@@ -0,0 +1,231 @@
+package doctor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+// Severity describes how serious a Finding is
+type Severity string
+
+const (
+	// SeverityOK is used when the check didn't spot any problem
+	SeverityOK Severity = "ok"
+	// SeverityWarning is used when kuberlr works, but not in the best way
+	SeverityWarning Severity = "warning"
+	// SeverityError is used when kuberlr is not going to work
+	SeverityError Severity = "error"
+)
+
+// Finding is the outcome of a check performed by the doctor
+type Finding struct {
+	ID          string   `json:"id"`
+	Severity    Severity `json:"severity"`
+	Message     string   `json:"message"`
+	Remediation string   `json:"remediation,omitempty"`
+}
+
+// Findings is a list of Finding objects
+type Findings []Finding
+
+// HasErrors returns true when at least one of the findings is an error
+func (f Findings) HasErrors() bool {
+	for _, finding := range f {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckConfig reports about the outcome of loading kuberlr's configuration
+func CheckConfig(loadErr error) Finding {
+	if loadErr != nil {
+		return Finding{
+			ID:          "config",
+			Severity:    SeverityError,
+			Message:     fmt.Sprintf("Cannot load configuration: %v", loadErr),
+			Remediation: "Fix the syntax of the kuberlr.conf files",
+		}
+	}
+	return Finding{
+		ID:       "config",
+		Severity: SeverityOK,
+		Message:  "Configuration loaded",
+	}
+}
+
+// CheckPolicy reports about the outcome of loading the policy of the
+// organization
+func CheckPolicy(loadErr error) Finding {
+	if loadErr != nil {
+		return Finding{
+			ID:          "policy",
+			Severity:    SeverityError,
+			Message:     loadErr.Error(),
+			Remediation: "Make sure PolicyURL can be reached and PolicyPublicKey matches the key signing the policy",
+		}
+	}
+	return Finding{
+		ID:       "policy",
+		Severity: SeverityOK,
+		Message:  "Policy loaded, if any",
+	}
+}
+
+// CheckLocalDir ensures the directory where kuberlr saves the downloaded
+// binaries can be written
+func CheckLocalDir(path string) Finding {
+	dir := path
+	// the directory is created on demand, check the closest parent
+	// that exists
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".kuberlr-doctor-")
+	if err != nil {
+		return Finding{
+			ID:          "local-dir",
+			Severity:    SeverityError,
+			Message:     fmt.Sprintf("Cannot write inside of %s: %v", dir, err),
+			Remediation: fmt.Sprintf("Make sure %s is writable by the current user", path),
+		}
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+
+	return Finding{
+		ID:       "local-dir",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("%s is writable", path),
+	}
+}
+
+// CheckSystemPath ensures the directory holding system-wide kubectl binaries
+// exists
+func CheckSystemPath(path string) Finding {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return Finding{
+			ID:          "system-path",
+			Severity:    SeverityWarning,
+			Message:     fmt.Sprintf("System path %s is not a directory", path),
+			Remediation: "Point SystemPath to the directory holding the kubectl binaries installed system-wide",
+		}
+	}
+	return Finding{
+		ID:       "system-path",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("System path %s exists", path),
+	}
+}
+
+// CheckBinaries ensures kuberlr has a kubectl binary to use
+func CheckBinaries(bins finder.KubectlBinaries, allowDownload bool) Finding {
+	if len(bins) > 0 {
+		return Finding{
+			ID:       "binaries",
+			Severity: SeverityOK,
+			Message:  fmt.Sprintf("%d kubectl binaries available", len(bins)),
+		}
+	}
+	if allowDownload {
+		return Finding{
+			ID:       "binaries",
+			Severity: SeverityWarning,
+			Message:  "No kubectl binary available, it will be downloaded on first use",
+		}
+	}
+	return Finding{
+		ID:          "binaries",
+		Severity:    SeverityError,
+		Message:     "No kubectl binary available and downloads are disabled",
+		Remediation: "Install a kubectl binary system-wide or set AllowDownload = true",
+	}
+}
+
+// CheckKubectlLink ensures the `kubectl` found inside of the PATH is kuberlr
+func CheckKubectlLink() Finding {
+	kubectl, err := exec.LookPath("kubectl" + osexec.Ext)
+	if err != nil {
+		return Finding{
+			ID:          "kubectl-link",
+			Severity:    SeverityWarning,
+			Message:     "No kubectl found inside of PATH",
+			Remediation: "Create a symlink named kubectl pointing to kuberlr inside of a directory that is part of the PATH",
+		}
+	}
+
+	self, err := os.Executable()
+	if err == nil {
+		self, err = filepath.EvalSymlinks(self)
+	}
+	if err != nil {
+		return Finding{
+			ID:       "kubectl-link",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Cannot find the location of kuberlr: %v", err),
+		}
+	}
+
+	if !sameFile(kubectl, self) {
+		return Finding{
+			ID:          "kubectl-link",
+			Severity:    SeverityWarning,
+			Message:     fmt.Sprintf("%s is not handled by kuberlr", kubectl),
+			Remediation: fmt.Sprintf("Replace %s with a symlink pointing to %s", kubectl, self),
+		}
+	}
+
+	return Finding{
+		ID:       "kubectl-link",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("%s is handled by kuberlr", kubectl),
+	}
+}
+
+// CheckAPIServer reports about the outcome of the kubernetes API server
+// version probe
+func CheckAPIServer(version string, probeErr error) Finding {
+	if probeErr != nil {
+		return Finding{
+			ID:          "api-server",
+			Severity:    SeverityWarning,
+			Message:     fmt.Sprintf("Cannot find the version of the kubernetes API server: %v", probeErr),
+			Remediation: "Check the kubeconfig file in use, or increase the Timeout value",
+		}
+	}
+	return Finding{
+		ID:       "api-server",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("Kubernetes API server is running version %s", version),
+	}
+}
+
+// sameFile returns true when both paths lead to the same file, either
+// via symbolic or hard links
+func sameFile(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,61 @@
+package doctor
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+func TestCheckLocalDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the directory doesn't have to exist yet
+	f := CheckLocalDir(filepath.Join(dir, "not", "created"))
+	if f.Severity != SeverityOK {
+		t.Errorf("Unexpected finding %+v", f)
+	}
+}
+
+func TestCheckBinaries(t *testing.T) {
+	bins := finder.KubectlBinaries{
+		{Path: "kubectl1.20.0", Version: semver.MustParse("1.20.0")},
+	}
+
+	if f := CheckBinaries(bins, false); f.Severity != SeverityOK {
+		t.Errorf("Unexpected finding %+v", f)
+	}
+	if f := CheckBinaries(finder.KubectlBinaries{}, true); f.Severity != SeverityWarning {
+		t.Errorf("Unexpected finding %+v", f)
+	}
+	if f := CheckBinaries(finder.KubectlBinaries{}, false); f.Severity != SeverityError {
+		t.Errorf("Unexpected finding %+v", f)
+	}
+}
+
+func TestHasErrors(t *testing.T) {
+	findings := Findings{
+		CheckConfig(nil),
+		CheckAPIServer("", errors.New("boom")),
+	}
+	if findings.HasErrors() {
+		t.Error("Warnings should not be reported as errors")
+	}
+
+	findings = append(findings, CheckConfig(errors.New("boom")))
+	if !findings.HasErrors() {
+		t.Error("Error not detected")
+	}
+	if findings[2].Remediation == "" {
+		t.Error("Errors should provide a remediation")
+	}
+}
@@ -0,0 +1,322 @@
+package downloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+const (
+	// tarGzArchive is the format of the kubernetes-client-*.tar.gz
+	// archives released upstream
+	tarGzArchive = "tar.gz"
+	// zipArchive is the format used by some mirrors for Windows
+	zipArchive = "zip"
+)
+
+// maxExtractedSize caps the size of the kubectl binary extracted from an
+// archive, this protects against decompression bombs
+const maxExtractedSize = 1024 * 1024 * 1024
+
+// archiveFormat returns the format of the archive served at the given URL,
+// judging from its extension or from its first bytes. An empty string is
+// returned when the URL serves a plain binary
+func archiveFormat(urlToGet string, head []byte) string {
+	if u, err := url.Parse(urlToGet); err == nil {
+		switch p := strings.ToLower(u.Path); {
+		case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+			return tarGzArchive
+		case strings.HasSuffix(p, ".zip"):
+			return zipArchive
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return zipArchive
+	case bytes.HasPrefix(head, []byte("\x1f\x8b")):
+		return tarGzArchive
+	}
+	return ""
+}
+
+// isArchiveURL returns true when the given URL path has the extension of
+// an archive
+func isArchiveURL(urlPath string) bool {
+	return archiveFormat("file://"+urlPath, nil) != ""
+}
+
+// isKubectlEntry returns true when the given entry of an archive is the
+// kubectl binary of the given OS
+func isKubectlEntry(name, goos string) bool {
+	return path.Base(filepath.ToSlash(name)) == "kubectl"+executableExt(goos)
+}
+
+// maxManifestSize caps the size of the checksum manifests read from an
+// archive
+const maxManifestSize = 1024 * 1024
+
+// bundleManifestFile is the manifest of the bundles created by
+// `kuberlr export`, it lists the checksums of the binaries
+const bundleManifestFile = "manifest.json"
+
+// checksumManifests are the names of the files listing the checksums of
+// the contents of an archive, in the format of sha256sum
+var checksumManifests = map[string]bool{
+	"SHA256SUMS":     true,
+	"sha256sums":     true,
+	"sha256sums.txt": true,
+	"checksums.txt":  true,
+}
+
+// isManifestEntry returns true when the given entry of an archive holds
+// the checksum of the kubectl binary of the given OS
+func isManifestEntry(name, goos string) bool {
+	base := path.Base(filepath.ToSlash(name))
+	return checksumManifests[base] ||
+		base == bundleManifestFile ||
+		base == "kubectl"+executableExt(goos)+".sha256"
+}
+
+// cleanEntry returns the path of an entry of an archive, relative to its
+// root and using slashes as separator
+func cleanEntry(name string) string {
+	return path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "/"))
+}
+
+// archiveContents describes what has been found inside of an archive
+type archiveContents struct {
+	// kubectl is the path of the kubectl binary
+	kubectl string
+	// manifests maps the path of the checksum manifests to their contents
+	manifests map[string][]byte
+}
+
+// expectedChecksum returns the checksum of the kubectl binary listed by
+// the manifests of the archive. The boolean is false when there's none
+func (c archiveContents) expectedChecksum() (string, bool) {
+	names := []string{}
+	for name := range c.manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		dir := path.Dir(name)
+		data := c.manifests[name]
+
+		switch base := path.Base(name); {
+		case base == bundleManifestFile:
+			var m struct {
+				Binaries []struct {
+					Path   string `json:"path"`
+					SHA256 string `json:"sha256"`
+				} `json:"binaries"`
+			}
+			if err := json.Unmarshal(data, &m); err != nil {
+				continue
+			}
+			for _, b := range m.Binaries {
+				if path.Join(dir, b.Path) == c.kubectl {
+					return b.SHA256, true
+				}
+			}
+		case strings.HasSuffix(base, ".sha256"):
+			// the checksum of the binary sitting next to it
+			fields := strings.Fields(string(data))
+			if len(fields) > 0 && path.Join(dir, strings.TrimSuffix(base, ".sha256")) == c.kubectl {
+				return fields[0], true
+			}
+		default:
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 2 && path.Join(dir, strings.TrimPrefix(fields[1], "*")) == c.kubectl {
+					return fields[0], true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// extractKubectl extracts the kubectl binary found inside of the given
+// archive into a temporary file created next to it, the path to the
+// temporary file is returned together with the contents of the archive.
+// The progress of the extraction is written to the writer returned by
+// progress, which is invoked with the size of the binary
+func extractKubectl(format, archive, goos string, progress func(total int64) io.Writer) (string, archiveContents, error) {
+	contents := archiveContents{manifests: map[string][]byte{}}
+	out, err := ioutil.TempFile(filepath.Dir(archive), common.TempDownloadPrefix)
+	if err != nil {
+		return "", contents, err
+	}
+	defer out.Close()
+
+	switch format {
+	case tarGzArchive:
+		err = extractFromTarGz(archive, goos, out, progress, &contents)
+	case zipArchive:
+		err = extractFromZip(archive, goos, out, progress, &contents)
+	default:
+		err = fmt.Errorf("Unsupported archive format %s", format)
+	}
+	if err == nil && contents.kubectl == "" {
+		err = fmt.Errorf("There's no kubectl%s inside of the archive", executableExt(goos))
+	}
+	if err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", contents, err
+	}
+	return out.Name(), contents, nil
+}
+
+// copyEntry copies the extracted kubectl binary, refusing the suspiciously
+// big ones
+func copyEntry(out io.Writer, entry io.Reader, size int64, progress func(total int64) io.Writer) error {
+	if progress != nil {
+		out = io.MultiWriter(out, progress(size))
+	}
+	n, err := io.Copy(out, io.LimitReader(entry, maxExtractedSize+1))
+	if err != nil {
+		return err
+	}
+	if n > maxExtractedSize {
+		return fmt.Errorf("kubectl is bigger than %d bytes", int64(maxExtractedSize))
+	}
+	return nil
+}
+
+// readManifest reads a checksum manifest, refusing the suspiciously big
+// ones
+func readManifest(entry io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(entry, maxManifestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("The checksum manifest is bigger than %d bytes", maxManifestSize)
+	}
+	return data, nil
+}
+
+func extractFromTarGz(archive, goos string, out io.Writer, progress func(total int64) io.Writer, contents *archiveContents) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("Cannot read the archive: %v", err)
+	}
+	defer gz.Close()
+
+	// the manifests can follow the binary, the whole archive is read
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Cannot read the archive: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		switch {
+		case contents.kubectl == "" && isKubectlEntry(hdr.Name, goos):
+			if err := copyEntry(out, tr, hdr.Size, progress); err != nil {
+				return err
+			}
+			contents.kubectl = cleanEntry(hdr.Name)
+		case isManifestEntry(hdr.Name, goos):
+			data, err := readManifest(tr)
+			if err != nil {
+				return err
+			}
+			contents.manifests[cleanEntry(hdr.Name)] = data
+		}
+	}
+}
+
+func extractFromZip(archive, goos string, out io.Writer, progress func(total int64) io.Writer, contents *archiveContents) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("Cannot read the archive: %v", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		kubectl := contents.kubectl == "" && isKubectlEntry(f.Name, goos)
+		if !kubectl && !isManifestEntry(f.Name, goos) {
+			continue
+		}
+
+		entry, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("Cannot read the archive: %v", err)
+		}
+		if kubectl {
+			err = copyEntry(out, entry, int64(f.UncompressedSize64), progress)
+			contents.kubectl = cleanEntry(f.Name)
+		} else {
+			var data []byte
+			if data, err = readManifest(entry); err == nil {
+				contents.manifests[cleanEntry(f.Name)] = data
+			}
+		}
+		entry.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractArchive extracts kubectl from the archive downloaded from the
+// given URL and makes sure it's an executable of the given OS. When the
+// archive lists the checksum of kubectl, the extracted binary is verified
+// against it. The path to the binary and its checksum are returned
+func extractArchive(format, urlToGet, goos, archive string, progress func(total int64) io.Writer) (string, string, error) {
+	extracted, contents, err := extractKubectl(format, archive, goos, progress)
+	if err != nil {
+		return "", "", fmt.Errorf("Cannot extract kubectl from %s: %v", urlToGet, err)
+	}
+	if err := checkExecutableFile(urlToGet, goos, extracted); err != nil {
+		os.Remove(extracted)
+		return "", "", err
+	}
+
+	hasher := sha256.New()
+	if err := hashFile(hasher, extracted); err != nil {
+		os.Remove(extracted)
+		return "", "", err
+	}
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	if expected, found := contents.expectedChecksum(); found && !strings.EqualFold(expected, checksum) {
+		os.Remove(extracted)
+		return "", "", &common.ShaMismatchError{URL: urlToGet + "#" + contents.kubectl, ShaExpected: expected, ShaActual: checksum}
+	}
+	return extracted, checksum, nil
+}
@@ -0,0 +1,209 @@
+package downloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func newTarGz(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, contents := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(contents)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newZip(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveFormat(t *testing.T) {
+	tests := []struct {
+		url      string
+		head     []byte
+		expected string
+	}{
+		{"https://mirror/kubernetes-client-linux-amd64.tar.gz", nil, tarGzArchive},
+		{"https://mirror/kubectl.tgz", nil, tarGzArchive},
+		{"https://mirror/kubectl.zip", nil, zipArchive},
+		{"https://mirror/kubectl", []byte("PK\x03\x04"), zipArchive},
+		{"https://mirror/kubectl", []byte("\x1f\x8b\x08"), tarGzArchive},
+		{"https://mirror/kubectl", []byte("\x7fELF"), ""},
+		{"https://mirror/kubectl.gz", []byte("\x7fELF"), ""},
+	}
+	for _, tt := range tests {
+		if actual := archiveFormat(tt.url, tt.head); actual != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.url, tt.expected, actual)
+		}
+	}
+}
+
+func TestDownloadArchive(t *testing.T) {
+	kubectl := fakeKubectl()
+	entry := "kubernetes/client/bin/kubectl" + executableExt(runtime.GOOS)
+	files := map[string][]byte{
+		"kubernetes/client/bin/README": []byte("not kubectl"),
+		entry:                          kubectl,
+	}
+	archives := map[string][]byte{
+		"/kubernetes-client.tar.gz": newTarGz(t, files),
+		"/kubernetes-client.zip":    newZip(t, files),
+		"/kubectl-unknown":          newTarGz(t, files),
+	}
+
+	mux := http.NewServeMux()
+	for name, contents := range archives {
+		contents := contents
+		hash := sha256.Sum256(contents)
+		mux.HandleFunc(name, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(contents)
+		})
+		mux.HandleFunc(name+".sha256", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(hex.EncodeToString(hash[:]) + "  archive\n"))
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binaryHash := sha256.Sum256(kubectl)
+	for name := range archives {
+		d := Downloder{}
+		destination := filepath.Join(dir, "bin"+filepath.Base(name), "kubectl")
+		if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+			t.Fatal(err)
+		}
+		checksum, err := d.download("kubectl", server.URL+name, destination, 0755)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if checksum != hex.EncodeToString(binaryHash[:]) {
+			t.Errorf("%s: the checksum of the extracted binary is expected, got %s", name, checksum)
+		}
+		actual, err := ioutil.ReadFile(destination)
+		if err != nil || !bytes.Equal(actual, kubectl) {
+			t.Errorf("%s: kubectl has not been extracted: %v", name, err)
+		}
+
+		// only the installed binary is left behind
+		leftovers, _ := ioutil.ReadDir(filepath.Dir(destination))
+		if len(leftovers) != 1 {
+			t.Errorf("%s: expected only kubectl, found %d files", name, len(leftovers))
+		}
+	}
+}
+
+func TestArchiveExpectedChecksum(t *testing.T) {
+	entry := "kubernetes/client/bin/kubectl"
+	tests := []struct {
+		manifests map[string][]byte
+		expected  string
+	}{
+		{map[string][]byte{}, ""},
+		{map[string][]byte{"SHA256SUMS": []byte("aaa  kubernetes/client/bin/kubectl\nbbb  kubernetes/client/bin/README\n")}, "aaa"},
+		{map[string][]byte{"kubernetes/sha256sums.txt": []byte("ccc *client/bin/kubectl\n")}, "ccc"},
+		{map[string][]byte{"kubernetes/client/bin/kubectl.sha256": []byte("ddd\n")}, "ddd"},
+		{map[string][]byte{"manifest.json": []byte(`{"binaries":[{"path":"kubernetes/client/bin/kubectl","sha256":"eee"}]}`)}, "eee"},
+		{map[string][]byte{"SHA256SUMS": []byte("fff  other/kubectl\n")}, ""},
+	}
+	for _, tt := range tests {
+		actual, found := archiveContents{kubectl: entry, manifests: tt.manifests}.expectedChecksum()
+		if actual != tt.expected || found != (tt.expected != "") {
+			t.Errorf("%v: expected %q, got %q %v", tt.manifests, tt.expected, actual, found)
+		}
+	}
+}
+
+func TestDownloadArchiveChecksumManifest(t *testing.T) {
+	kubectl := fakeKubectl()
+	entry := "kubernetes/client/bin/kubectl" + executableExt(runtime.GOOS)
+	binaryHash := sha256.Sum256(kubectl)
+	archives := map[string][]byte{
+		"/good.tar.gz": newTarGz(t, map[string][]byte{
+			entry:        kubectl,
+			"SHA256SUMS": []byte(hex.EncodeToString(binaryHash[:]) + "  " + entry + "\n"),
+		}),
+		"/tampered.zip": newZip(t, map[string][]byte{
+			entry:        kubectl,
+			"SHA256SUMS": []byte("0000  " + entry + "\n"),
+		}),
+	}
+
+	mux := http.NewServeMux()
+	for name, contents := range archives {
+		contents := contents
+		hash := sha256.Sum256(contents)
+		mux.HandleFunc(name, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(contents)
+		})
+		mux.HandleFunc(name+".sha256", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(hex.EncodeToString(hash[:])))
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{}
+	if _, err := d.download("kubectl", server.URL+"/good.tar.gz", filepath.Join(dir, "good"), 0755); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	tampered := filepath.Join(dir, "tampered")
+	if _, err := d.download("kubectl", server.URL+"/tampered.zip", tampered, 0755); !common.IsShaMismatch(err) {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(tampered); !os.IsNotExist(err) {
+		t.Error("The tampered binary has been installed")
+	}
+}
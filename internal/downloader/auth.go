@@ -0,0 +1,188 @@
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// netrcCredentials are the credentials of a machine found inside of a
+// netrc file
+type netrcCredentials struct {
+	Login    string
+	Password string
+}
+
+// DefaultNetrc returns the location of the netrc file of the user, which
+// can be changed via the NETRC environment variable
+func DefaultNetrc() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+	return filepath.Join(common.HomeDir(), name)
+}
+
+// parseNetrc returns the credentials of each machine defined inside of
+// the given netrc file, the ones of the "default" entry are stored with
+// an empty name
+func parseNetrc(data string) map[string]netrcCredentials {
+	machines := map[string]netrcCredentials{}
+
+	var lines []string
+	inMacro := false
+	for _, line := range strings.Split(data, "\n") {
+		// macro definitions end with an empty line
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		fields := strings.Fields(line)
+		for i, f := range fields {
+			if f == "macdef" {
+				fields = fields[:i]
+				inMacro = true
+				break
+			}
+		}
+		lines = append(lines, fields...)
+	}
+
+	machine := ""
+	current := false
+	for i := 0; i < len(lines); i++ {
+		switch lines[i] {
+		case "default":
+			machine, current = "", true
+		case "machine":
+			if i+1 < len(lines) {
+				i++
+				machine, current = lines[i], true
+			}
+		case "login", "password", "account":
+			if i+1 >= len(lines) || !current {
+				continue
+			}
+			i++
+			creds := machines[machine]
+			if lines[i-1] == "login" {
+				creds.Login = lines[i]
+			} else if lines[i-1] == "password" {
+				creds.Password = lines[i]
+			}
+			machines[machine] = creds
+		}
+	}
+	return machines
+}
+
+// loadNetrc reads the given netrc file, a missing file has no credentials
+func loadNetrc(path string) map[string]netrcCredentials {
+	if path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.V(1).Infof("Cannot read %s: %v", path, err)
+		}
+		return nil
+	}
+	return parseNetrc(string(data))
+}
+
+// ParseAuthHeader parses a header in the "Name: value" form, like
+// "X-JFrog-Art-Api: <key>" or "Authorization: Bearer <token>"
+func ParseAuthHeader(text string) (http.Header, error) {
+	parts := strings.SplitN(text, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return nil, fmt.Errorf("Invalid header %q, expected the \"Name: value\" form", text)
+	}
+	header := http.Header{}
+	header.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	return header, nil
+}
+
+// authTransport adds the credentials of the mirror to the requests made
+// against it. The header is sent only to the hosts of the mirror, so that
+// it's not leaked when the mirror redirects to another host, while the
+// netrc credentials are sent to the machine they belong to. The ones of
+// the netrc "default" entry are sent only to the configured mirrors
+type authTransport struct {
+	next    http.RoundTripper
+	header  http.Header
+	hosts   map[string]bool
+	mirrors map[string]bool
+	netrc   map[string]netrcCredentials
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the requests carrying their own credentials, like the ones made
+	// against the GitHub API or the OCI registries, are left untouched
+	if req.Header.Get("Authorization") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	if len(t.header) > 0 && t.hosts[req.URL.Host] {
+		req = req.Clone(req.Context())
+		for name, values := range t.header {
+			req.Header[name] = values
+		}
+		return t.next.RoundTrip(req)
+	}
+
+	creds, found := t.netrc[req.URL.Hostname()]
+	if !found && t.mirrors[req.URL.Host] {
+		creds, found = t.netrc[""]
+	}
+	if found && creds.Login != "" {
+		req = req.Clone(req.Context())
+		req.SetBasicAuth(creds.Login, creds.Password)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// mirrorHosts returns the hosts the kubectl binaries are downloaded from
+func (d *Downloder) mirrorHosts() map[string]bool {
+	hosts := map[string]bool{}
+	if u, err := url.Parse(d.releasesURL()); err == nil {
+		hosts[u.Host] = true
+	}
+	if d.URLTemplate != "" {
+		if rendered, err := renderURLTemplate(d.URLTemplate, semver.Version{}, runtime.GOOS, runtime.GOARCH); err == nil {
+			if u, err := url.Parse(rendered); err == nil {
+				hosts[u.Host] = true
+			}
+		}
+	}
+	return hosts
+}
+
+// configuredMirrorHosts returns the hosts of the mirrors set explicitly,
+// the main one and the fallback ones. The upstream bucket isn't part of
+// them
+func (d *Downloder) configuredMirrorHosts() map[string]bool {
+	hosts := map[string]bool{}
+	if d.BaseURL != "" || d.URLTemplate != "" {
+		hosts = d.mirrorHosts()
+	}
+	for _, mirror := range d.FallbackMirrors {
+		if u, err := url.Parse(mirror.URL); err == nil {
+			hosts[u.Host] = true
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	data := `machine mirror.corp login alice password s3cret
+macdef init
+	cd /pub
+
+machine
+  other.corp
+  login bob
+  account ignored
+  password hunter2
+default login anonymous password guest
+`
+	machines := parseNetrc(data)
+	expected := map[string]netrcCredentials{
+		"mirror.corp": {Login: "alice", Password: "s3cret"},
+		"other.corp":  {Login: "bob", Password: "hunter2"},
+		"":            {Login: "anonymous", Password: "guest"},
+	}
+	if len(machines) != len(expected) {
+		t.Errorf("Expected %d machines, got %v", len(expected), machines)
+	}
+	for machine, creds := range expected {
+		if machines[machine] != creds {
+			t.Errorf("Expected %v for %q, got %v", creds, machine, machines[machine])
+		}
+	}
+}
+
+func TestParseAuthHeader(t *testing.T) {
+	header, err := ParseAuthHeader("X-JFrog-Art-Api:  key ")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if header.Get("X-JFrog-Art-Api") != "key" {
+		t.Errorf("Unexpected header %v", header)
+	}
+
+	for _, text := range []string{"key", ": key", "X-JFrog-Art-Api:"} {
+		if _, err := ParseAuthHeader(text); err == nil {
+			t.Errorf("Expected an error parsing %q", text)
+		}
+	}
+}
+
+func TestAuthenticatedMirror(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.Write([]byte("v1.20.1"))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	dir, err := ioutil.TempDir("", "kuberlr-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	netrc := filepath.Join(dir, "netrc")
+	if err := ioutil.WriteFile(netrc, []byte("machine "+u.Hostname()+" login alice password s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := Downloder{BaseURL: server.URL, Netrc: netrc}
+	if _, err := d.UpstreamStableVersion(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user, password, ok := received.BasicAuth(); !ok || user != "alice" || password != "s3cret" {
+		t.Errorf("The netrc credentials have not been sent: %v", received.Header)
+	}
+
+	// the default entry is used only with the configured mirrors
+	if err := ioutil.WriteFile(netrc, []byte("default login bob password hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d = Downloder{BaseURL: server.URL, Netrc: netrc}
+	if _, err := d.UpstreamStableVersion(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user, _, ok := received.BasicAuth(); !ok || user != "bob" {
+		t.Errorf("The default netrc credentials have not been sent: %v", received.Header)
+	}
+	d = Downloder{BaseURL: "https://mirror.corp", Netrc: netrc}
+	if _, err := d.getContentsOfURL(server.URL); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, _, ok := received.BasicAuth(); ok {
+		t.Error("The default netrc credentials have been sent to a host that is not a mirror")
+	}
+
+	header, _ := ParseAuthHeader("X-JFrog-Art-Api: key")
+	d = Downloder{BaseURL: server.URL, AuthHeader: header}
+	if _, err := d.UpstreamStableVersion(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Header.Get("X-JFrog-Art-Api") != "key" {
+		t.Errorf("The header has not been sent: %v", received.Header)
+	}
+
+	// the header is not sent to other hosts
+	d = Downloder{BaseURL: "https://mirror.corp", AuthHeader: header}
+	if _, err := d.getContentsOfURL(server.URL); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Header.Get("X-JFrog-Art-Api") != "" {
+		t.Error("The header has been sent to a host that is not the mirror")
+	}
+}
@@ -0,0 +1,55 @@
+package downloader
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptedEncodings is sent to the mirrors to let them know kuberlr can
+// handle compressed artifacts
+const acceptedEncodings = "zstd, gzip"
+
+// contentEncoding returns the compression algorithm used by the given
+// response. Mirrors can either advertise that via the `Content-Encoding`
+// header, or by serving files with a well known extension
+func contentEncoding(resp *http.Response) string {
+	if enc := strings.ToLower(resp.Header.Get("Content-Encoding")); enc != "" && enc != "identity" {
+		return enc
+	}
+	// archives are extracted once downloaded, their checksum covers the
+	// compressed contents
+	if resp.Request != nil && resp.Request.URL != nil && !isArchiveURL(resp.Request.URL.Path) {
+		switch path := resp.Request.URL.Path; {
+		case strings.HasSuffix(path, ".gz"):
+			return "gzip"
+		case strings.HasSuffix(path, ".zst"):
+			return "zstd"
+		}
+	}
+	return ""
+}
+
+// decompress wraps the given reader with the decompressor
+// matching the encoding
+func decompress(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case "":
+		return ioutil.NopCloser(r), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "zstd":
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("Unsupported content encoding: %s", encoding)
+	}
+}
@@ -0,0 +1,69 @@
+package downloader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecompress(t *testing.T) {
+	expected := []byte("fake kubectl binary")
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write(expected)
+	gw.Close()
+
+	var zstded bytes.Buffer
+	zw, err := zstd.NewWriter(&zstded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write(expected)
+	zw.Close()
+
+	for encoding, data := range map[string][]byte{
+		"":     expected,
+		"gzip": gzipped.Bytes(),
+		"zstd": zstded.Bytes(),
+	} {
+		r, err := decompress(encoding, bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("Unexpected error with encoding %q: %v", encoding, err)
+			continue
+		}
+		actual, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Errorf("Unexpected error with encoding %q: %v", encoding, err)
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("Encoding %q: got %q instead of %q", encoding, actual, expected)
+		}
+	}
+
+	if _, err := decompress("brotli", bytes.NewReader(expected)); err == nil {
+		t.Error("Expected unsupported encoding to fail")
+	}
+}
+
+func TestContentEncoding(t *testing.T) {
+	u, _ := url.Parse("https://mirror.example.com/kubectl.zst")
+	resp := &http.Response{
+		Header:  http.Header{},
+		Request: &http.Request{URL: u},
+	}
+	if enc := contentEncoding(resp); enc != "zstd" {
+		t.Errorf("Got %q instead of zstd", enc)
+	}
+
+	resp.Header.Set("Content-Encoding", "gzip")
+	if enc := contentEncoding(resp); enc != "gzip" {
+		t.Errorf("Got %q instead of gzip", enc)
+	}
+}
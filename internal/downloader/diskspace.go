@@ -0,0 +1,24 @@
+package downloader
+
+import (
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// expectedKubectlSize is the space reserved for a kubectl binary whose
+// size is not known before downloading it. Recent releases weigh about
+// 50MiB
+const expectedKubectlSize = 64 * 1024 * 1024
+
+// ensureFreeSpace fails when the given directory cannot hold the amount of
+// bytes required, zero meaning the size is not known. The error tells how
+// much space can be reclaimed by removing the binaries no longer needed
+func (d *Downloder) ensureFreeSpace(dir string, required int64) error {
+	if required <= 0 {
+		required = expectedKubectlSize
+	}
+	err := common.EnsureFreeSpace(dir, uint64(required))
+	if spaceErr, ok := err.(*common.InsufficientSpaceError); ok && d.ReclaimableSpace != nil {
+		spaceErr.Reclaimable, spaceErr.ReclaimCommand = d.ReclaimableSpace()
+	}
+	return err
+}
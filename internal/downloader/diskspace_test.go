@@ -0,0 +1,36 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestEnsureFreeSpaceSuggestsPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-diskspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{
+		ReclaimableSpace: func() (uint64, string) {
+			return 1024, "kuberlr prune --keep-last 1"
+		},
+	}
+	if err := d.ensureFreeSpace(dir, 1); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err = d.ensureFreeSpace(dir, math.MaxInt64)
+	if !common.IsInsufficientSpace(err) {
+		t.Fatalf("Expected insufficient space error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "`kuberlr prune --keep-last 1` would free 1024 bytes") {
+		t.Errorf("The error doesn't suggest a cleanup: %v", err)
+	}
+}
@@ -1,11 +1,16 @@
 package downloader
 
 import (
+	"bufio"
+	"context"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
@@ -15,33 +20,183 @@ import (
 	"time"
 
 	"github.com/flavio/kuberlr/internal/common"
-	"github.com/flavio/kuberlr/internal/osexec"
+	"github.com/flavio/kuberlr/internal/progress"
 
 	"github.com/blang/semver/v4"
-	"github.com/schollz/progressbar/v3"
+	"k8s.io/klog"
 )
 
+// KubectlReleasesURL is the location of the kubectl binaries released
+// by the kubernetes community
+const KubectlReleasesURL = "https://storage.googleapis.com/kubernetes-release/release"
+
 // KubectlStableURL URL of the text file used by kubernetes community
 // to hold the latest stable version of kubernetes released
-const KubectlStableURL = "https://storage.googleapis.com/kubernetes-release/release/stable.txt"
+const KubectlStableURL = KubectlReleasesURL + "/stable.txt"
 
 // Downloder is a helper class that is used to interact with the
 // kubernetes infrastructure holding released binaries and release information
 type Downloder struct {
+	// ProgressStyle defines what is shown while downloading
+	ProgressStyle progress.Style
+	// Progress, when set, shows the progress of the downloads running in
+	// parallel. ProgressStyle is used otherwise
+	Progress *progress.Pool
+	// GitHubToken is used to authenticate against the GitHub API,
+	// anonymous requests are made when empty
+	GitHubToken string
+	// ReleasesCache is the file where the responses of the GitHub
+	// API are cached
+	ReleasesCache string
+	// StableCache is the file where the latest stable version of
+	// kubernetes is cached, nothing is cached when empty
+	StableCache string
+	// StableCacheTTL is for how long the cached stable version is used
+	// without asking the mirror whether it changed
+	StableCacheTTL time.Duration
+	// Context is the kubernetes context recorded inside of the metadata
+	// of the downloaded binaries
+	Context string
+	// Journal keeps track of the installs in progress, it's optional
+	Journal *Journal
+	// SourcePlugin is the executable providing the kubectl binaries,
+	// when empty binaries are downloaded from the upstream mirror
+	SourcePlugin string
+	// BaseURL is the location of a mirror of the kubernetes release
+	// bucket, KubectlReleasesURL is used when empty
+	BaseURL string
+	// Proxy is the proxy used to reach BaseURL and URLTemplate, see
+	// Mirror.Proxy
+	Proxy string
+	// FallbackMirrors are tried in order when the download from the mirror
+	// fails. They follow the layout of the upstream bucket, like BaseURL
+	FallbackMirrors []Mirror
+	// URLTemplate builds the URL of the kubectl binaries hosted by mirrors
+	// not following the layout of the upstream bucket, see urlTemplateData
+	// for the available fields. It takes precedence over BaseURL
+	URLTemplate string
+	// Registry is the OCI repository the kubectl binaries are pulled from,
+	// e.g. ghcr.io/org/kubectl. When set, it's used instead of the mirror
+	Registry string
+	// QuarantineDir is where the downloads failing the verification are
+	// moved to, they are deleted when empty
+	QuarantineDir string
+	// Timeouts limits the requests made against the mirror
+	Timeouts common.Timeouts
+	// RootCAs are the certificate authorities trusted when talking with
+	// the mirror over TLS, the ones of the system are used when nil
+	RootCAs *x509.CertPool
+	// AuthHeader is added to the requests made against the mirror, e.g.
+	// the API key of an Artifactory instance
+	AuthHeader http.Header
+	// Netrc is the netrc file holding the credentials of the mirrors, it's
+	// not read when empty. The credentials of its "default" entry are sent
+	// only to BaseURL, URLTemplate and FallbackMirrors
+	Netrc string
+	// VerifySignatures makes kuberlr verify the signatures of the binaries
+	// downloaded from the mirror, using cosign
+	VerifySignatures bool
+	// Cosign is the cosign executable, DefaultCosign is used when empty
+	Cosign string
+	// SanityCheck makes kuberlr execute the binaries downloaded for this
+	// host before installing them, the binaries that don't run or that
+	// report an unexpected version are discarded
+	SanityCheck bool
+	// Retries is how many times a download failing because of a transient
+	// error, like a connection reset or a server error, is tried again
+	Retries int
+	// RetryDelay is the delay before the first retry, it doubles at each
+	// retry. DefaultRetryDelay is used when zero
+	RetryDelay time.Duration
+	// MaxRate limits the bandwidth used by the downloads, in bytes per
+	// second. There's no limit when zero
+	MaxRate int64
+	// ReclaimableSpace returns how many bytes can be freed by removing the
+	// binaries that are no longer needed, together with the command doing
+	// that. It's used to suggest a cleanup when the disk is full, it's
+	// optional
+	ReclaimableSpace func() (uint64, string)
+	// PostDownloadCommand is executed after each successful download with
+	// the path to the binary and its version as extra arguments, e.g. to
+	// scan or to register the binary. It runs against the verified binary
+	// before it's installed, the binary is discarded when the command
+	// fails
+	PostDownloadCommand []string
+	// Verify, when set, is invoked against each downloaded binary before
+	// it's installed, e.g. to compare it with a recorded checksum. The
+	// binary is discarded when it fails
+	Verify func(version semver.Version, binary string) error
+	// OnCompletion is invoked after each successful install together with
+	// the path to the binary and the time it took, it's optional
+	OnCompletion func(version semver.Version, destination string, elapsed time.Duration)
+
+	httpClient *http.Client
+	// failover is true when there's another mirror to try after this one
+	failover bool
+}
+
+// newBar returns the Bar reporting the progress of a download
+func (d *Downloder) newBar(desc string, total int64) progress.Bar {
+	if d.Progress != nil {
+		return d.Progress.New(desc, total)
+	}
+	return progress.New(os.Stderr, desc, total, d.ProgressStyle)
+}
+
+// printf writes a message to stderr using the progress style, without
+// garbling the progress of the downloads running in parallel
+func (d *Downloder) printf(format string, a ...interface{}) {
+	if d.Progress != nil {
+		d.Progress.Printf(format, a...)
+		return
+	}
+	progress.Printf(os.Stderr, d.ProgressStyle, format, a...)
+}
+
+// client returns the HTTP client used to reach the mirror, it's created
+// on first use and then shared by all the requests
+func (d *Downloder) client() *http.Client {
+	if d.httpClient == nil {
+		d.httpClient = d.newClient(d.Proxy)
+	}
+	return d.httpClient
+}
+
+// newClient returns an HTTP client reaching the mirrors through the given
+// proxy, see Mirror.Proxy
+func (d *Downloder) newClient(proxy string) *http.Client {
+	client := d.Timeouts.Client()
+	transport := client.Transport.(*http.Transport)
+	if d.RootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: d.RootCAs}
+	}
+	if proxy != "" && !common.IsOffline() {
+		if f, err := proxyFunc(proxy); err == nil {
+			transport.Proxy = f
+		} else {
+			klog.Warningf("Ignoring the proxy of the mirror: %v", err)
+		}
+	}
+	if len(d.AuthHeader) > 0 || d.Netrc != "" {
+		client.Transport = &authTransport{
+			next:    client.Transport,
+			header:  d.AuthHeader,
+			hosts:   d.mirrorHosts(),
+			mirrors: d.configuredMirrorHosts(),
+			netrc:   loadNetrc(d.Netrc),
+		}
+	}
+	return client
 }
 
 func (d *Downloder) getContentsOfURL(url string) (string, error) {
-	res, err := http.Get(url)
+	res, err := d.client().Get(url)
 	if err != nil {
 		return "", err
 	}
 	if res.StatusCode != http.StatusOK {
-		return "",
-			fmt.Errorf(
-				"GET %s returned http status %s",
-				url,
-				res.Status,
-			)
+		res.Body.Close()
+		return "", &statusError{URL: url, Status: res.Status, Code: res.StatusCode}
 	}
 
 	v, err := ioutil.ReadAll(res.Body)
@@ -52,25 +207,85 @@ func (d *Downloder) getContentsOfURL(url string) (string, error) {
 	return string(v), nil
 }
 
-// UpstreamStableVersion returns the latest version of kubernetes that upstream
-// considers stable
-func (d *Downloder) UpstreamStableVersion() (semver.Version, error) {
-	v, err := d.getContentsOfURL(KubectlStableURL)
-	if err != nil {
-		return semver.Version{}, err
-	}
-	return semver.ParseTolerant(v)
-}
-
 // GetKubectlBinary downloads the kubectl binary identified by the given version
 // to the specified destination
 func (d *Downloder) GetKubectlBinary(version semver.Version, destination string) error {
-	var firstErr error
-	const maxNumTries = 3
-	const timeToSleepOnRetryPerIter = 10 // seconds
+	if common.IsOffline() {
+		return fmt.Errorf("Cannot download kubectl %s: %w", version, common.ErrOffline)
+	}
+	start := time.Now()
+
+	// deal with the installs interrupted by a crash before
+	// starting a new one
+	d.Journal.Recover()
+
+	// only one process downloads a given binary, the other ones wait
+	// and then reuse it
+	lock, waited, err := common.LockDownload(destination, func() {
+		d.printf("Waiting for another process downloading kubectl %s\n", version)
+	})
+	if err != nil {
+		klog.V(1).Infof("Cannot lock the download of %s: %v", destination, err)
+	} else {
+		defer lock.Release()
+	}
+	if _, err := os.Stat(destination); waited && err == nil {
+		return nil
+	}
+
+	// the binary being replaced must not be removed by a concurrent
+	// cleanup while the download is in progress
+	if lock, err := common.LockInUse(destination); err == nil {
+		defer lock.Release()
+	}
+
+	if d.SourcePlugin != "" {
+		if err := os.MkdirAll(filepath.Dir(destination), os.ModePerm); err != nil {
+			return err
+		}
+		checksum, err := d.runPlugin(version, destination, 0755)
+		if err != nil {
+			return err
+		}
+		d.saveMetadata(version, pluginSourceURL(d.SourcePlugin), destination, checksum)
+		return d.completed(version, destination, start)
+	}
+
+	arch, err := targetArch()
+	if err != nil {
+		return err
+	}
+	if d.VerifySignatures {
+		if err := checkSignedRelease(version); err != nil {
+			return err
+		}
+	}
+	if d.Registry != "" {
+		sourceURL, checksum, err := d.pullFromRegistry(version, runtime.GOOS, arch, destination, 0755, d.installChecks(version, destination))
+		if err != nil {
+			return err
+		}
+		d.saveMetadata(version, sourceURL, destination, checksum)
+		return d.completed(version, destination, start)
+	}
+	if err := checkUpstreamBuild(runtime.GOOS, arch, version); err != nil {
+		return err
+	}
+
+	err = d.fromMirrors(fmt.Sprintf("kubectl %s", version), func(m *Downloder) error {
+		return m.downloadWithRetries(version, arch, destination)
+	})
+	if err != nil {
+		return err
+	}
+	return d.completed(version, destination, start)
+}
 
-	for iter := 1; iter <= maxNumTries; iter++ {
-		downloadURL, err := d.kubectlDownloadURL(version)
+// downloadWithRetries downloads the kubectl binary from the mirror, trying
+// again when the download fails because of a transient error
+func (d *Downloder) downloadWithRetries(version semver.Version, arch, destination string) error {
+	for retry := 0; ; retry++ {
+		downloadURL, err := d.kubectlDownloadURL(version, runtime.GOOS, arch)
 		if err != nil {
 			return err
 		}
@@ -84,34 +299,117 @@ func (d *Downloder) GetKubectlBinary(version semver.Version, destination string)
 			}
 		}
 
-		err = d.download(fmt.Sprintf("kubectl%s%s", version, osexec.Ext), downloadURL, destination, 0755)
+		desc := fmt.Sprintf("kubectl v%s %s/%s", version, runtime.GOOS, arch)
+		checksum, err := d.downloadFor(runtime.GOOS, desc, downloadURL, destination, 0755, d.installChecks(version, destination))
 		if err == nil {
+			d.saveMetadata(version, downloadURL, destination, checksum)
 			return nil
 		}
-		if iter == 1 {
-			firstErr = err
+		if isNotFound(err) {
+			return fmt.Errorf("There's no build of kubectl %s for %s/%s: %v", version, runtime.GOOS, arch, err)
 		}
-		if common.IsShaMismatch(err) {
-			// Try downloading an older subversion
-			fmt.Fprintf(os.Stderr, "Error on download attempt #%d: %s\n", iter, err)
-			time.Sleep(time.Duration(iter*timeToSleepOnRetryPerIter) * time.Second)
-		} else {
-			break
+		// the transfers interrupted part-way are resumed from the next
+		// mirror right away, instead of insisting with a mirror that
+		// stalls or drops the connection
+		if _, statErr := os.Stat(partialFile(destination)); d.failover && statErr == nil {
+			return err
+		}
+		// a mirror being synced can serve a checksum not matching
+		// the binary for a while
+		if retry >= d.Retries || !(isTransient(err) || common.IsShaMismatch(err)) {
+			return err
 		}
+
+		delay := retryDelay(retry+1, d.RetryDelay, rand.Float64)
+		d.printf("Error on download attempt #%d: %s, retrying in %s\n", retry+1, err, delay.Round(time.Millisecond))
+		time.Sleep(delay)
 	}
-	return firstErr
 }
 
-func (d *Downloder) kubectlDownloadURL(v semver.Version) (string, error) {
+// FetchKubectlBinary downloads the kubectl binary built for the given
+// platform from the mirror, or the registry, the checksum of the binary
+// is returned. The binary is neither recorded nor meant to be used on
+// this host
+func (d *Downloder) FetchKubectlBinary(version semver.Version, goos, arch, destination string) (string, error) {
+	if common.IsOffline() {
+		return "", fmt.Errorf("Cannot download kubectl %s: %w", version, common.ErrOffline)
+	}
+	if d.VerifySignatures {
+		if err := checkSignedRelease(version); err != nil {
+			return "", err
+		}
+	}
+	if d.Registry != "" {
+		_, checksum, err := d.pullFromRegistry(version, goos, arch, destination, 0755, nil)
+		return checksum, err
+	}
+	if err := checkUpstreamBuild(goos, arch, version); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	checksum := ""
+	err := d.fromMirrors(fmt.Sprintf("kubectl %s", version), func(m *Downloder) error {
+		downloadURL, err := m.kubectlDownloadURL(version, goos, arch)
+		if err != nil {
+			return err
+		}
+		desc := fmt.Sprintf("kubectl v%s %s/%s", version, goos, arch)
+		checksum, err = m.downloadFor(goos, desc, downloadURL, destination, 0755, nil)
+		if isNotFound(err) {
+			return fmt.Errorf("There's no build of kubectl %s for %s/%s: %v", version, goos, arch, err)
+		}
+		return err
+	})
+	return checksum, err
+}
+
+// completed invokes the OnCompletion callback, if any
+func (d *Downloder) completed(version semver.Version, destination string, start time.Time) error {
+	if d.OnCompletion != nil {
+		d.OnCompletion(version, destination, time.Since(start))
+	}
+	return nil
+}
+
+// saveMetadata records where the binary comes from. Failing to do that
+// doesn't prevent the binary from being used
+func (d *Downloder) saveMetadata(version semver.Version, sourceURL, destination, checksum string) {
+	err := common.SaveMetadata(destination, common.Metadata{
+		Version:     version.String(),
+		SourceURL:   sourceURL,
+		SHA256:      checksum,
+		InstalledAt: time.Now().UTC(),
+		Context:     d.Context,
+	})
+	if err != nil {
+		klog.V(1).Infof("Cannot save metadata of %s: %v", destination, err)
+	}
+}
+
+// releasesURL returns the location of the kubectl binaries
+func (d *Downloder) releasesURL() string {
+	if d.BaseURL != "" {
+		return strings.TrimRight(d.BaseURL, "/")
+	}
+	return KubectlReleasesURL
+}
+
+func (d *Downloder) kubectlDownloadURL(v semver.Version, goos, arch string) (string, error) {
+	if d.URLTemplate != "" {
+		return renderURLTemplate(d.URLTemplate, v, goos, arch)
+	}
+
 	// Example: https://storage.googleapis.com/kubernetes-release/release/v1.18.0/bin/linux/amd64/kubectlI
 	u, err := url.Parse(fmt.Sprintf(
-		"https://storage.googleapis.com/kubernetes-release/release/v%d.%d.%d/bin/%s/%s/kubectl%s",
-		v.Major,
-		v.Minor,
-		v.Patch,
-		runtime.GOOS,
-		runtime.GOARCH,
-		osexec.Ext,
+		"%s/v%s/bin/%s/%s/kubectl%s",
+		d.releasesURL(),
+		releaseVersion(v),
+		goos,
+		arch,
+		executableExt(goos),
 	))
 	if err != nil {
 		return "", err
@@ -120,66 +418,192 @@ func (d *Downloder) kubectlDownloadURL(v semver.Version) (string, error) {
 	return u.String(), nil
 }
 
-func (d *Downloder) download(desc, urlToGet, destination string, mode os.FileMode) error {
+// releaseVersion returns the version of the kubectl release, prerelease
+// included: "1.31.0-rc.1"
+func releaseVersion(v semver.Version) string {
+	v.Build = nil
+	return v.String()
+}
+
+// executableExt returns the extension of the executables of the given OS
+func executableExt(goos string) string {
+	if goos == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// download fetches the given URL into the destination, the checksum
+// of the downloaded file is returned
+func (d *Downloder) download(desc, urlToGet, destination string, mode os.FileMode) (string, error) {
+	return d.downloadFor(runtime.GOOS, desc, urlToGet, destination, mode, nil)
+}
+
+// downloadFor is like download, the file is expected to be an executable
+// of the given OS. The check, when not nil, is invoked against the verified
+// file before it's moved to the destination
+func (d *Downloder) downloadFor(goos, desc, urlToGet, destination string, mode os.FileMode, check func(string) error) (string, error) {
 	shaURLToGet := urlToGet + ".sha256"
 	shaExpected, err := d.getContentsOfURL(shaURLToGet)
 	if err != nil {
-		return fmt.Errorf("Error while trying to get contents of %s: %v", shaURLToGet, err)
+		if isNotFound(err) {
+			return "", err
+		}
+		return "", fmt.Errorf("Error while trying to get contents of %s: %w", shaURLToGet, err)
 	}
 	shaExpected = strings.TrimRight(shaExpected, "\n")
+	if err := checkNotWebPage(shaURLToGet, "", []byte(shaExpected)); err != nil {
+		return "", err
+	}
+	// mirrors hosting archives often publish the output of sha256sum,
+	// which is followed by the name of the file
+	if fields := strings.Fields(shaExpected); len(fields) == 2 {
+		shaExpected = fields[0]
+	}
 
-	req, err := http.NewRequest("GET", urlToGet, nil)
+	// the downloads interrupted by network errors are resumed
+	partial := partialFile(destination)
+	offset := resumeOffset(partial, urlToGet, goos)
+
+	// the context interrupts the transfers that stall
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", urlToGet, nil)
 	if err != nil {
-		return fmt.Errorf(
-			"Error while issuing GET request against %s: %v",
+		return "", fmt.Errorf(
+			"Error while issuing GET request against %s: %w",
 			urlToGet, err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	if offset > 0 {
+		// the partial file holds the decompressed contents, hence the
+		// rest of the file must not be compressed either
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("Accept-Encoding", "identity")
+	} else {
+		req.Header.Set("Accept-Encoding", acceptedEncodings)
+	}
+
+	resp, err := d.client().Do(req)
 	if err != nil {
-		return fmt.Errorf(
-			"Error while issuing GET request against %s: %v",
+		return "", fmt.Errorf(
+			"Error while issuing GET request against %s: %w",
 			urlToGet, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf(
-			"GET %s returned http status %s",
-			urlToGet,
-			resp.Status,
-		)
+	if offset > 0 {
+		switch err := checkResumedResponse(resp, offset); {
+		case err == errRangeIgnored:
+			// the mirror doesn't support range requests, start over
+			klog.V(1).Infof("%s doesn't support range requests, downloading it again", urlToGet)
+			offset = 0
+		case err != nil:
+			klog.V(1).Infof("Cannot resume the download of %s: %v", urlToGet, err)
+			resp.Body.Close()
+			os.Remove(partial)
+			return d.downloadFor(goos, desc, urlToGet, destination, mode, check)
+		}
 	}
-	temporaryDestinationFile, err := ioutil.TempFile(os.TempDir(), "kuberlr-kubectl-")
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return "", &statusError{URL: urlToGet, Status: resp.Status, Code: resp.StatusCode}
+	}
+
+	// fail early instead of leaving a truncated file behind. Compressed
+	// artifacts are going to take more space, the size of the transfer
+	// is still a good lower bound
+	if err := d.ensureFreeSpace(filepath.Dir(destination), resp.ContentLength); err != nil {
+		return "", err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	temporaryDestinationFile, err := os.OpenFile(partial, flags, 0644)
 	if err != nil {
-		return fmt.Errorf("Error trying to create temporary file in %s: %v", os.TempDir(), err)
+		return "", fmt.Errorf("Error trying to create temporary file %s: %v", partial, err)
 	}
 
 	tmpname := temporaryDestinationFile.Name()
-	defer os.Remove(tmpname)
+	// interrupted transfers keep the partial file around, everything else
+	// is removed
+	keepPartial := false
+	defer func() {
+		if !keepPartial {
+			os.Remove(tmpname)
+		}
+	}()
+
+	entry := journalEntry{
+		TempFile:  tmpname,
+		State:     journalDownloading,
+		Mode:      mode,
+		PID:       os.Getpid(),
+		Started:   time.Now().UTC(),
+		Resumable: true,
+	}
+	d.Journal.record(destination, &entry)
+	// only the installs interrupted by a crash are left inside of the
+	// journal, the failed ones are not
+	defer d.Journal.record(destination, nil)
+
+	hasher := sha256.New()
+	if offset > 0 {
+		if err := hashFile(hasher, tmpname); err != nil {
+			temporaryDestinationFile.Close()
+			return "", err
+		}
+	}
 
 	// write progress to stderr, writing to stdout would
 	// break bash/zsh/shell completion
-	fmt.Fprintf(os.Stderr, "Downloading %s\n", urlToGet)
-	bar := progressbar.NewOptions(
-		int(resp.ContentLength),
-		progressbar.OptionSetDescription(desc),
-		progressbar.OptionSetWriter(os.Stderr),
-		progressbar.OptionShowBytes(true),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionThrottle(10*time.Millisecond),
-		progressbar.OptionShowCount(),
-		progressbar.OptionOnCompletion(func() {
-			fmt.Fprintln(os.Stderr, " done.")
-		}),
-	)
-	hasher := sha256.New()
+	if offset > 0 {
+		d.printf("Resuming the download of %s from %s\n", urlToGet, progress.HumanizeBytes(offset))
+	} else {
+		d.printf("Downloading %s\n", urlToGet)
+	}
+	bar := d.newBar(desc, resp.ContentLength)
+
+	// the progress is computed against the bytes transferred, which
+	// are compressed when the mirror supports that
+	watched, stopWatching := watchStall(resp.Body, urlToGet, d.Timeouts.Stall, cancel)
+	defer stopWatching()
+	body, err := decompress(contentEncoding(resp), io.TeeReader(throttle(watched, d.MaxRate), bar))
+	if err != nil {
+		bar.Finish("failed.")
+		temporaryDestinationFile.Close()
+		return "", fmt.Errorf("Error while reading %s: %v", urlToGet, err)
+	}
+	defer body.Close()
+
+	// don't install the error page of a proxy as kubectl, the beginning
+	// of the resumed downloads has been checked already. The binaries
+	// extracted from archives are checked later
+	sniffer := bufio.NewReaderSize(body, sniffLength)
+	head, _ := sniffer.Peek(sniffLength)
+	archive := ""
+	if offset == 0 {
+		archive = archiveFormat(urlToGet, head)
+	}
+	err = checkNotWebPage(urlToGet, resp.Header.Get("Content-Type"), head)
+	if err == nil && offset == 0 && archive == "" {
+		err = checkExecutable(urlToGet, goos, head)
+	}
+	if err != nil {
+		bar.Finish("failed.")
+		temporaryDestinationFile.Close()
+		return "", err
+	}
 
-	_, err = io.Copy(io.MultiWriter(temporaryDestinationFile, bar, hasher), resp.Body)
+	_, err = io.Copy(io.MultiWriter(temporaryDestinationFile, hasher), sniffer)
 	if err != nil {
+		bar.Finish("failed.")
 		temporaryDestinationFile.Close()
-		return fmt.Errorf(
-			"Error while downloading text of %s into file %s: %v",
+		keepPartial = true
+		return "", fmt.Errorf(
+			"Error while downloading text of %s into file %s: %w",
 			urlToGet, tmpname, err)
 	}
 
@@ -187,26 +611,137 @@ func (d *Downloder) download(desc, urlToGet, destination string, mode os.FileMod
 	// open file handler) does not conflict with the rename.
 	temporaryDestinationFile.Close()
 
+	bar.Status("verifying...")
 	shaActual := hex.EncodeToString(hasher.Sum(nil))
 	if shaExpected != shaActual {
-		return &common.ShaMismatchError{URL: urlToGet, ShaExpected: shaExpected, ShaActual: shaActual}
+		bar.Finish("verification failed.")
+		d.quarantine(tmpname, urlToGet, shaActual, fmt.Sprintf("checksum mismatch, expected %s", shaExpected))
+		return "", &common.ShaMismatchError{URL: urlToGet, ShaExpected: shaExpected, ShaActual: shaActual}
 	}
-
-	err = os.Rename(tmpname, destination)
-	if err != nil {
-		linkErr, ok := err.(*os.LinkError)
-		if ok {
-			fmt.Fprintf(os.Stderr, "Cross-device error trying to rename a file: %s -- will do a full copy\n", linkErr)
-			var tempInput []byte
-			tempInput, err = ioutil.ReadFile(tmpname)
-			if err != nil {
-				return fmt.Errorf("Error reading temporary file %s: %v",
-					tmpname, err)
+	if d.VerifySignatures {
+		if err := d.verifySignature(urlToGet, tmpname); err != nil {
+			bar.Finish("verification failed.")
+			d.quarantine(tmpname, urlToGet, shaActual, "invalid signature")
+			return "", err
+		}
+	}
+	// from now on the binary extracted from the archive, if any, is what
+	// gets verified and installed
+	binary := tmpname
+	if archive != "" {
+		// the extraction gets a bar of its own, the size of kubectl is
+		// known once it's found inside of the archive
+		bar.Finish("verified.")
+		bar = nil
+		extracted, checksum, err := extractArchive(archive, urlToGet, goos, tmpname, func(total int64) io.Writer {
+			bar = d.newBar(desc+" (extracting)", total)
+			return bar
+		})
+		if bar == nil {
+			bar = d.newBar(desc+" (extracting)", 0)
+		}
+		if err != nil {
+			if common.IsShaMismatch(err) {
+				bar.Finish("verification failed.")
+				d.quarantine(tmpname, urlToGet, shaActual, err.Error())
+			} else {
+				bar.Finish("failed.")
 			}
-			err = ioutil.WriteFile(destination, tempInput, mode)
+			return "", err
 		}
-	} else {
-		err = os.Chmod(destination, mode)
+		defer os.Remove(extracted)
+		binary, shaActual = extracted, checksum
+		entry.TempFile = extracted
+	}
+	if check != nil {
+		bar.Status("testing...")
+		if err := check(binary); err != nil {
+			bar.Finish("verification failed.")
+			d.quarantine(binary, urlToGet, shaActual, err.Error())
+			return "", err
+		}
+	}
+	bar.Finish("verified, done.")
+
+	entry.State = journalVerified
+	d.Journal.record(destination, &entry)
+
+	if err := placeFile(binary, destination, mode); err != nil {
+		return "", err
+	}
+
+	return shaActual, nil
+}
+
+// quarantine keeps the download that failed the verification around, so
+// that what has been served by the mirror can be inspected
+func (d *Downloder) quarantine(tmpname, sourceURL, checksum, reason string) {
+	if d.QuarantineDir == "" {
+		return
+	}
+	m := common.Metadata{SourceURL: sourceURL, SHA256: checksum}
+	destination, err := common.Quarantine(tmpname, d.QuarantineDir, m, reason, time.Now())
+	if err != nil {
+		klog.V(1).Infof("Cannot quarantine the download of %s: %v", sourceURL, err)
+		return
+	}
+	d.printf("The download of %s has been moved to %s\n", sourceURL, destination)
+}
+
+// placeFile moves the temporary file to its destination. The destination
+// is replaced atomically: an interrupted install leaves either the previous
+// file or the new one behind, never a partially written one
+func placeFile(tmpname, destination string, mode os.FileMode) error {
+	if err := syncFile(tmpname, mode); err != nil {
+		return err
+	}
+	err := os.Rename(tmpname, destination)
+	if linkErr, ok := err.(*os.LinkError); ok {
+		fmt.Fprintf(os.Stderr, "Cross-device error trying to rename a file: %s -- will do a full copy\n", linkErr)
+		return copyIntoPlace(tmpname, destination, mode)
+	}
+	return err
+}
+
+// syncFile sets the mode of the given file and flushes it to disk, so that
+// a crash right after the rename doesn't leave an empty file behind
+func syncFile(path string, mode os.FileMode) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// copyIntoPlace copies the temporary file next to the destination, then
+// renames the copy into place
+func copyIntoPlace(tmpname, destination string, mode os.FileMode) error {
+	in, err := os.Open(tmpname)
+	if err != nil {
+		return fmt.Errorf("Error reading temporary file %s: %v", tmpname, err)
+	}
+	defer in.Close()
+
+	out, err := ioutil.TempFile(filepath.Dir(destination), common.TempDownloadPrefix)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = syncFile(out.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(out.Name(), destination)
+	}
+	if err != nil {
+		os.Remove(out.Name())
 	}
 	return err
 }
@@ -0,0 +1,163 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// fakeKubectl returns the contents of a fake kubectl binary built
+// for the current platform
+func fakeKubectl() []byte {
+	return append(append([]byte{}, executableMagics[runtime.GOOS][0]...), "fake kubectl binary"...)
+}
+
+func newFakeMirror(contents []byte, sha string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kubectl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(contents)
+	})
+	mux.HandleFunc("/kubectl.sha256", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sha + "\n"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestDownload(t *testing.T) {
+	contents := fakeKubectl()
+	hash := sha256.Sum256(contents)
+	server := newFakeMirror(contents, hex.EncodeToString(hash[:]))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{}
+	destination := filepath.Join(dir, "kubectl")
+	checksum, err := d.download("kubectl", server.URL+"/kubectl", destination, 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if checksum != hex.EncodeToString(hash[:]) {
+		t.Errorf("Got checksum %s instead of %s", checksum, hex.EncodeToString(hash[:]))
+	}
+
+	actual, err := ioutil.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != string(contents) {
+		t.Errorf("Got %q instead of %q", actual, contents)
+	}
+}
+
+func TestDownloadShaMismatch(t *testing.T) {
+	server := newFakeMirror(fakeKubectl(), "abc")
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{QuarantineDir: filepath.Join(dir, "quarantine")}
+	destination := filepath.Join(dir, "kubectl")
+	_, err = d.download("kubectl", server.URL+"/kubectl", destination, 0755)
+	if !common.IsShaMismatch(err) {
+		t.Errorf("Expected sha mismatch error, got %v", err)
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Error("Binary with wrong checksum should not be installed")
+	}
+
+	quarantined, err := filepath.Glob(filepath.Join(d.QuarantineDir, common.TempDownloadPrefix+"*"))
+	if err != nil || len(quarantined) != 1 {
+		t.Fatalf("Expected the download to be quarantined, got %v %v", quarantined, err)
+	}
+	m, found, err := common.LoadMetadata(quarantined[0])
+	if err != nil || !found {
+		t.Fatalf("Cannot load metadata of the quarantined download: %v", err)
+	}
+	if m.SourceURL != server.URL+"/kubectl" || m.QuarantineReason == "" {
+		t.Errorf("Unexpected metadata %+v", m)
+	}
+}
+
+func TestDownloadPrivateCA(t *testing.T) {
+	contents := fakeKubectl()
+	hash := sha256.Sum256(contents)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kubectl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(contents)
+	})
+	mux.HandleFunc("/kubectl.sha256", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(hex.EncodeToString(hash[:])))
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{}
+	if _, err := d.download("kubectl", server.URL+"/kubectl", filepath.Join(dir, "kubectl"), 0755); err == nil {
+		t.Fatal("The certificate of the mirror is signed by an unknown authority")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	d = Downloder{RootCAs: pool}
+	if _, err := d.download("kubectl", server.URL+"/kubectl", filepath.Join(dir, "kubectl"), 0755); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCopyIntoPlace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpname := filepath.Join(dir, "new")
+	destination := filepath.Join(dir, "kubectl")
+	if err := ioutil.WriteFile(tmpname, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(destination, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyIntoPlace(tmpname, destination, 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	actual, err := ioutil.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != "new" {
+		t.Errorf("The destination has not been replaced: %q", actual)
+	}
+
+	// only the source and the destination are left behind
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("Expected the temporary copy to be renamed, found %d files", len(files))
+	}
+}
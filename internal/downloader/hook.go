@@ -0,0 +1,69 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// postDownloadTimeout limits the time given to the post download command
+const postDownloadTimeout = 10 * time.Minute
+
+// installChecks returns the function checking a freshly downloaded binary
+// before it's moved to the destination: the Verify function, the sanity
+// check and the post download command are run against it. nil is returned
+// when there's nothing to check
+func (d *Downloder) installChecks(version semver.Version, destination string) func(binary string) error {
+	sanity := d.sanityCheck(version)
+	if d.Verify == nil && sanity == nil && len(d.PostDownloadCommand) == 0 {
+		return nil
+	}
+	return func(binary string) error {
+		if d.Verify != nil {
+			if err := d.Verify(version, binary); err != nil {
+				return err
+			}
+		}
+		if sanity != nil {
+			if err := sanity(binary); err != nil {
+				return err
+			}
+		}
+		return d.runPostDownloadCommand(version, binary, destination)
+	}
+}
+
+// runPostDownloadCommand executes the post download command, if any,
+// against the given binary, which is going to be installed at the
+// given destination
+func (d *Downloder) runPostDownloadCommand(version semver.Version, binary, destination string) error {
+	if len(d.PostDownloadCommand) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), postDownloadTimeout)
+	defer cancel()
+
+	args := append(append([]string{}, d.PostDownloadCommand[1:]...), binary, version.String())
+	cmd := exec.CommandContext(ctx, d.PostDownloadCommand[0], args...)
+	// stdout is reserved to kubectl, e.g. for shell completion
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"KUBERLR_KUBECTL_PATH="+binary,
+		"KUBERLR_KUBECTL_DESTINATION="+destination,
+		common.ResolvedVersionEnvKey+"="+version.String())
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Post download command %q failed on kubectl %s, the binary has been discarded: %v",
+			strings.Join(d.PostDownloadCommand, " "), version, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestPostDownloadCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake commands are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "kuberlr-hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	artifact := filepath.Join(dir, "artifact")
+	if err := ioutil.WriteFile(artifact, fakeKubectl(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plugin := writePlugin(t, dir, "plugin", fmt.Sprintf("cat > /dev/null; cat %s\n", artifact))
+
+	record := filepath.Join(dir, "record")
+	hook := writePlugin(t, dir, "hook", fmt.Sprintf(
+		"echo \"$1 $3\" \"$KUBERLR_KUBECTL_DESTINATION\" > %s; test -e \"$2\" && test ! -e \"$KUBERLR_KUBECTL_DESTINATION\"\n",
+		record))
+	completed := 0
+	d := Downloder{
+		SourcePlugin:        plugin,
+		PostDownloadCommand: []string{hook, "--strict"},
+		OnCompletion: func(version semver.Version, destination string, elapsed time.Duration) {
+			completed++
+		},
+	}
+	destination := filepath.Join(dir, "bin", "kubectl1.20.1")
+	if err := d.GetKubectlBinary(semver.MustParse("1.20.1"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	args, err := ioutil.ReadFile(record)
+	if err != nil {
+		t.Fatalf("The command has not been executed: %v", err)
+	}
+	// the command runs against the binary before it's installed
+	if expected := "--strict 1.20.1 " + destination; strings.TrimSpace(string(args)) != expected {
+		t.Errorf("Expected the arguments %q, got %q", expected, args)
+	}
+	if _, err := os.Stat(destination); err != nil {
+		t.Errorf("The binary has not been installed: %v", err)
+	}
+	if completed != 1 {
+		t.Errorf("Expected one completion, got %d", completed)
+	}
+
+	// the binaries rejected by the command are removed
+	d.PostDownloadCommand = []string{writePlugin(t, dir, "rejecting", "exit 1\n")}
+	destination = filepath.Join(dir, "bin", "kubectl1.20.2")
+	if err := d.GetKubectlBinary(semver.MustParse("1.20.2"), destination); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Error("The rejected binary has been installed")
+	}
+	if completed != 1 {
+		t.Errorf("The rejected binary has been reported as completed")
+	}
+}
+
+func TestVerifyBeforeInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake commands are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "kuberlr-hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	artifact := filepath.Join(dir, "artifact")
+	if err := ioutil.WriteFile(artifact, fakeKubectl(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	destination := filepath.Join(dir, "bin", "kubectl1.20.1")
+	d := Downloder{
+		SourcePlugin: writePlugin(t, dir, "plugin", fmt.Sprintf("cat > /dev/null; cat %s\n", artifact)),
+		Verify: func(version semver.Version, binary string) error {
+			if _, err := os.Stat(destination); !os.IsNotExist(err) {
+				t.Error("The binary has been installed before being verified")
+			}
+			return fmt.Errorf("checksum mismatch")
+		},
+	}
+	if err := d.GetKubectlBinary(semver.MustParse("1.20.1"), destination); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Error("The rejected binary has been installed")
+	}
+}
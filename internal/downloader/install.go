@@ -0,0 +1,74 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// fileSourceURL returns the URL recorded inside of the metadata of the
+// binaries installed from a local file
+func fileSourceURL(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
+
+// InstallFile installs the kubectl binary found at the given path, which
+// is copied to the destination once its checksum and its format have
+// been verified. The checksum is not verified when empty
+func (d *Downloder) InstallFile(version semver.Version, source, checksum, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(destination), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(destination), common.TempDownloadPrefix)
+	if err != nil {
+		return fmt.Errorf("Error trying to create temporary file in %s: %v", filepath.Dir(destination), err)
+	}
+	tmpname := tmp.Name()
+	defer os.Remove(tmpname)
+
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hasher), in)
+	tmp.Close()
+	if err != nil {
+		return fmt.Errorf("Cannot copy %s: %v", source, err)
+	}
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if checksum != "" && checksum != actual {
+		return &common.ShaMismatchError{URL: source, ShaExpected: checksum, ShaActual: actual}
+	}
+	if err := checkExecutableFile(source, runtime.GOOS, tmpname); err != nil {
+		return err
+	}
+	if check := d.sanityCheck(version); check != nil {
+		if err := check(tmpname); err != nil {
+			return err
+		}
+	}
+
+	if err := placeFile(tmpname, destination, 0755); err != nil {
+		return err
+	}
+	d.saveMetadata(version, fileSourceURL(source), destination, actual)
+	return nil
+}
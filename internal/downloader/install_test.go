@@ -0,0 +1,52 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestInstallFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "kubectl-v1.20.1-linux-amd64")
+	if err := ioutil.WriteFile(source, fakeKubectl(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(fakeKubectl())
+	checksum := hex.EncodeToString(hash[:])
+
+	d := Downloder{}
+	destination := filepath.Join(dir, "bin", "kubectl1.20.1")
+	if err := d.InstallFile(semver.MustParse("1.20.1"), source, checksum, destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	m, found, err := common.LoadMetadata(destination)
+	if err != nil || !found {
+		t.Fatalf("Metadata not found: %v", err)
+	}
+	if m.SHA256 != checksum || !strings.HasPrefix(m.SourceURL, "file://") || m.Source() != "file" {
+		t.Errorf("Unexpected metadata %+v", m)
+	}
+
+	other := filepath.Join(dir, "bin", "kubectl1.20.2")
+	err = d.InstallFile(semver.MustParse("1.20.2"), source, "abc", other)
+	if !common.IsShaMismatch(err) {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(other); !os.IsNotExist(err) {
+		t.Error("The binary should not be installed")
+	}
+}
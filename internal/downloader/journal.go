@@ -0,0 +1,170 @@
+package downloader
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+const (
+	// journalDownloading is the state of an install whose binary is
+	// still being downloaded
+	journalDownloading = "downloading"
+	// journalVerified is the state of an install whose binary has been
+	// downloaded and verified, but not yet moved to its destination
+	journalVerified = "verified"
+)
+
+// journalEntry describes an install in progress
+type journalEntry struct {
+	TempFile string      `json:"tempFile"`
+	State    string      `json:"state"`
+	Mode     os.FileMode `json:"mode"`
+	PID      int         `json:"pid"`
+	Started  time.Time   `json:"started"`
+	// Resumable is true when the temporary file can be used to resume
+	// the download
+	Resumable bool `json:"resumable,omitempty"`
+}
+
+// Journal keeps track of the installs in progress. After a crash, it allows
+// kuberlr to tell whether a binary has been fully verified and placed,
+// instead of trusting whatever file happens to exist
+type Journal struct {
+	// Path is the file holding the journal
+	Path string
+
+	// mu serializes the updates made by concurrent downloads, the ones
+	// made by other processes are serialized by the lock of the file
+	mu sync.Mutex
+	// active holds the installs in progress inside of this process
+	active map[string]bool
+}
+
+func (j *Journal) load() map[string]journalEntry {
+	entries := map[string]journalEntry{}
+
+	data, err := ioutil.ReadFile(j.Path)
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		klog.V(1).Infof("Ignoring malformed install journal %s: %v", j.Path, err)
+		return map[string]journalEntry{}
+	}
+	return entries
+}
+
+func (j *Journal) save(entries map[string]journalEntry) error {
+	if len(entries) == 0 {
+		err := os.Remove(j.Path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.Path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(j.Path), ".kuberlr-journal-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), j.Path)
+}
+
+// record updates the state of the install of the given destination,
+// a nil entry marks the install as completed
+func (j *Journal) record(destination string, entry *journalEntry) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if lock, err := common.LockState(j.Path); err == nil {
+		defer lock.Release()
+	}
+
+	if j.active == nil {
+		j.active = map[string]bool{}
+	}
+	entries := j.load()
+	if entry == nil {
+		delete(entries, destination)
+		delete(j.active, destination)
+	} else {
+		entries[destination] = *entry
+		j.active[destination] = true
+	}
+	if err := j.save(entries); err != nil {
+		klog.V(1).Infof("Cannot update install journal %s: %v", j.Path, err)
+	}
+}
+
+// Recover deals with the installs interrupted by a crash: the binaries that
+// have been verified are moved to their destination, the partial downloads
+// are kept to be resumed and the other temporary files are removed. The
+// destinations are never removed, they hold either the previous binary or
+// one installed later on. The installs of the processes that are still
+// running, including the other downloads of this process, are left
+// untouched
+func (j *Journal) Recover() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if lock, err := common.LockState(j.Path); err == nil {
+		defer lock.Release()
+	}
+
+	entries := j.load()
+	changed := false
+	for destination, entry := range entries {
+		if j.active[destination] || (entry.PID != os.Getpid() && processAlive(entry.PID)) {
+			continue
+		}
+		changed = true
+		delete(entries, destination)
+
+		if entry.State == journalVerified {
+			if err := placeFile(entry.TempFile, destination, entry.Mode); err == nil {
+				klog.V(1).Infof("Completed the interrupted install of %s", destination)
+				os.Remove(entry.TempFile)
+				continue
+			}
+		}
+
+		if entry.State != journalDownloading || !entry.Resumable {
+			klog.V(1).Infof("Removing the leftovers of the interrupted install of %s", destination)
+			os.Remove(entry.TempFile)
+		}
+	}
+
+	if changed {
+		if err := j.save(entries); err != nil {
+			klog.V(1).Infof("Cannot update install journal %s: %v", j.Path, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// deadPID is the pid of a process that is surely not running
+const deadPID = -1
+
+func TestJournalRecover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := &Journal{Path: filepath.Join(dir, "journal.json")}
+
+	// crashed while downloading: the temporary file must go away, the
+	// binary installed at the destination meanwhile must be kept
+	partialTmp := filepath.Join(dir, "partial.tmp")
+	partialDest := filepath.Join(dir, "kubectl1.19.0")
+	for _, f := range []string{partialTmp, partialDest} {
+		if err := ioutil.WriteFile(f, []byte(filepath.Base(f)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.record(partialDest, &journalEntry{TempFile: partialTmp, State: journalDownloading, PID: deadPID, Started: time.Now()})
+
+	// crashed after verification: the install is completed
+	verifiedTmp := filepath.Join(dir, "verified.tmp")
+	verifiedDest := filepath.Join(dir, "kubectl1.20.0")
+	if err := ioutil.WriteFile(verifiedTmp, []byte("verified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	j.record(verifiedDest, &journalEntry{TempFile: verifiedTmp, State: journalVerified, Mode: 0755, PID: deadPID, Started: time.Now()})
+
+	// the journal is recovered by the next run of kuberlr
+	j = &Journal{Path: j.Path}
+	j.Recover()
+
+	for _, f := range []string{partialTmp, verifiedTmp} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", f)
+		}
+	}
+	if data, err := ioutil.ReadFile(partialDest); err != nil || string(data) != filepath.Base(partialDest) {
+		t.Errorf("The installed binary should have been kept: %v", err)
+	}
+	if data, err := ioutil.ReadFile(verifiedDest); err != nil || string(data) != "verified" {
+		t.Errorf("Verified install not completed: %v", err)
+	}
+	if _, err := os.Stat(j.Path); !os.IsNotExist(err) {
+		t.Error("Journal should be empty")
+	}
+}
+
+func TestDownloadUpdatesJournal(t *testing.T) {
+	contents := fakeKubectl()
+	hash := sha256.Sum256(contents)
+	server := newFakeMirror(contents, hex.EncodeToString(hash[:]))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{Journal: &Journal{Path: filepath.Join(dir, "journal.json")}}
+	if _, err := d.download("kubectl", server.URL+"/kubectl", filepath.Join(dir, "kubectl"), 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entries := d.Journal.load(); len(entries) != 0 {
+		t.Errorf("Completed installs should not be part of the journal: %+v", entries)
+	}
+}
+
+func TestFailedDownloadLeavesJournal(t *testing.T) {
+	contents := fakeKubectl()
+	server := newFakeMirror(contents, "not-the-checksum")
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{Journal: &Journal{Path: filepath.Join(dir, "journal.json")}}
+	if _, err := d.download("kubectl", server.URL+"/kubectl", filepath.Join(dir, "kubectl"), 0755); err == nil {
+		t.Fatal("Expected the download to fail")
+	}
+	if entries := d.Journal.load(); len(entries) != 0 {
+		t.Errorf("Failed installs should not be part of the journal: %+v", entries)
+	}
+}
+
+func TestJournalRecoverSkipsActiveInstalls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// another download of this process is in progress
+	j := &Journal{Path: filepath.Join(dir, "journal.json")}
+	tmp := filepath.Join(dir, "downloading.tmp")
+	if err := ioutil.WriteFile(tmp, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	j.record(filepath.Join(dir, "kubectl1.20.0"), &journalEntry{TempFile: tmp, State: journalDownloading, PID: os.Getpid(), Started: time.Now()})
+
+	j.Recover()
+	if _, err := os.Stat(tmp); err != nil {
+		t.Errorf("The download in progress has been removed: %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package downloader
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// machine returns the hardware name reported by uname, like "armv7l"
+func machine() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return ""
+	}
+	return unix.ByteSliceToString(uts.Machine[:])
+}
@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package downloader
+
+// machine returns the hardware name of the host, Windows doesn't provide
+// uname and runs only on the architectures released by upstream
+func machine() string {
+	return ""
+}
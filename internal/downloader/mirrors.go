@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// mirrors returns the downloaders reaching each mirror, in the order they
+// are tried: the main one first, then the fallback ones
+func (d *Downloder) mirrors() []*Downloder {
+	mirrors := []*Downloder{d}
+	if len(d.FallbackMirrors) == 0 {
+		return mirrors
+	}
+	d.failover = true
+	// the clients are built by the main downloader: the credentials of the
+	// main mirror are never sent to the fallback ones
+	clients := map[string]*http.Client{d.Proxy: d.client()}
+	for _, mirror := range d.FallbackMirrors {
+		m := *d
+		// the fallback mirrors follow the layout of the upstream bucket
+		m.BaseURL = mirror.URL
+		m.URLTemplate = ""
+		m.Proxy = mirror.Proxy
+		m.FallbackMirrors = nil
+		if _, found := clients[mirror.Proxy]; !found {
+			clients[mirror.Proxy] = d.newClient(mirror.Proxy)
+		}
+		m.httpClient = clients[mirror.Proxy]
+		m.failover = true
+		mirrors = append(mirrors, &m)
+	}
+	mirrors[len(mirrors)-1].failover = false
+	return mirrors
+}
+
+// fromMirrors invokes fetch against each mirror until one of them succeeds,
+// what describes what is being fetched
+func (d *Downloder) fromMirrors(what string, fetch func(m *Downloder) error) error {
+	mirrors := d.mirrors()
+	failures := []string{}
+	for i, m := range mirrors {
+		err := fetch(m)
+		if err == nil {
+			return nil
+		}
+		if len(mirrors) == 1 {
+			return err
+		}
+
+		failures = append(failures, fmt.Sprintf("%s: %v", m.releasesURL(), err))
+		if i < len(mirrors)-1 {
+			d.printf("Cannot fetch %s from %s: %v, trying %s\n", what, m.releasesURL(), err, mirrors[i+1].releasesURL())
+		}
+	}
+	return fmt.Errorf("Cannot fetch %s from any mirror: %s", what, strings.Join(failures, "; "))
+}
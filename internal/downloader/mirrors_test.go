@@ -0,0 +1,201 @@
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestFallbackMirrors(t *testing.T) {
+	contents := fakeKubectl()
+	hash := sha256.Sum256(contents)
+	arch, err := targetArch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := "/v1.20.1/bin/" + runtime.GOOS + "/" + arch + "/kubectl" + executableExt(runtime.GOOS)
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer broken.Close()
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(contents)
+	})
+	mux.HandleFunc(prefix+".sha256", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(hex.EncodeToString(hash[:])))
+	})
+	mux.HandleFunc("/stable.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v1.20.1\n"))
+	})
+	working := httptest.NewServer(mux)
+	defer working.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-mirrors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{BaseURL: broken.URL, FallbackMirrors: []Mirror{{URL: working.URL}}}
+	stable, err := d.UpstreamStableVersion()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !stable.EQ(semver.MustParse("1.20.1")) {
+		t.Errorf("Unexpected stable version %s", stable)
+	}
+
+	destination := filepath.Join(dir, "kubectl1.20.1")
+	if err := d.GetKubectlBinary(semver.MustParse("1.20.1"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(destination); err != nil {
+		t.Errorf("The binary has not been downloaded: %v", err)
+	}
+
+	// the credentials of the main mirror are not sent to the fallback ones
+	var received http.Header
+	spy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		w.Write([]byte("v1.20.1\n"))
+	}))
+	defer spy.Close()
+	header, _ := ParseAuthHeader("X-JFrog-Art-Api: key")
+	d = Downloder{BaseURL: broken.URL, FallbackMirrors: []Mirror{{URL: spy.URL}}, AuthHeader: header}
+	if _, err := d.UpstreamStableVersion(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Get("X-JFrog-Art-Api") != "" {
+		t.Error("The header of the main mirror has been sent to a fallback one")
+	}
+
+	// all the mirrors are reported when none works
+	d = Downloder{BaseURL: broken.URL, FallbackMirrors: []Mirror{{URL: broken.URL + "/other"}}}
+	if _, err := d.UpstreamStableVersion(); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestMirrorProxy(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer broken.Close()
+
+	// the fallback mirror can be reached only through its proxy
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte("v1.20.2\n"))
+	}))
+	defer proxy.Close()
+
+	d := Downloder{
+		BaseURL:         broken.URL,
+		FallbackMirrors: []Mirror{{URL: "http://mirror.invalid/release", Proxy: proxy.URL}},
+	}
+	stable, err := d.UpstreamStableVersion()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !stable.EQ(semver.MustParse("1.20.2")) {
+		t.Errorf("Unexpected stable version %s", stable)
+	}
+	if proxied != "http://mirror.invalid/release/stable.txt" {
+		t.Errorf("Unexpected request made through the proxy: %q", proxied)
+	}
+
+	for _, text := range []string{DirectProxy, "http://proxy.corp:3128"} {
+		if err := CheckProxy(text); err != nil {
+			t.Errorf("%s: unexpected error: %v", text, err)
+		}
+	}
+	if err := CheckProxy("not a url"); err == nil {
+		t.Error("Expected invalid proxy to be refused")
+	}
+}
+
+func TestMirrorFailoverMidTransfer(t *testing.T) {
+	contents := append(fakeKubectl(), bytes.Repeat([]byte("x"), 4*sniffLength)...)
+	hash := sha256.Sum256(contents)
+	half := len(contents) / 2
+	arch, err := targetArch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := "/v1.20.1/bin/" + runtime.GOOS + "/" + arch + "/kubectl" + executableExt(runtime.GOOS)
+	checksum := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(hex.EncodeToString(hash[:])))
+	}
+
+	// the main mirror stops sending data half way through
+	stalled := 0
+	stalling := http.NewServeMux()
+	stalling.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		stalled++
+		w.Header().Set("Content-Length", fmt.Sprint(len(contents)))
+		w.Write(contents[:half])
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	stalling.HandleFunc(prefix+".sha256", checksum)
+	main := httptest.NewServer(stalling)
+	defer main.Close()
+
+	ranges := []string{}
+	working := http.NewServeMux()
+	working.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "kubectl", time.Time{}, bytes.NewReader(contents))
+	})
+	working.HandleFunc(prefix+".sha256", checksum)
+	fallback := httptest.NewServer(working)
+	defer fallback.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-mirrors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{
+		BaseURL:         main.URL,
+		FallbackMirrors: []Mirror{{URL: fallback.URL}},
+		Timeouts:        common.Timeouts{Stall: 200 * time.Millisecond},
+		Retries:         3,
+		RetryDelay:      time.Hour,
+	}
+	destination := filepath.Join(dir, "kubectl1.20.1")
+	if err := d.GetKubectlBinary(semver.MustParse("1.20.1"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if stalled != 1 {
+		t.Errorf("The stalled mirror has been tried %d times", stalled)
+	}
+	if expected := fmt.Sprintf("bytes=%d-", half); len(ranges) != 1 || ranges[0] != expected {
+		t.Errorf("Expected the download to be resumed with %q, got %q", expected, ranges)
+	}
+	installed, err := ioutil.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(installed, contents) {
+		t.Error("The binary has not been downloaded properly")
+	}
+}
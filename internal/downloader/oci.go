@@ -0,0 +1,390 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+const (
+	ociIndexType       = "application/vnd.oci.image.index.v1+json"
+	ociManifestType    = "application/vnd.oci.image.manifest.v1+json"
+	dockerListType     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	dockerManifestType = "application/vnd.docker.distribution.manifest.v2+json"
+	// ociTitleAnnotation holds the name of the file stored inside of a
+	// layer, it's set by oras
+	ociTitleAnnotation = "org.opencontainers.image.title"
+)
+
+// ociDescriptor references a manifest or a layer
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform,omitempty"`
+}
+
+// ociManifest is either an image index, listing the manifests of each
+// platform, or the manifest of an artifact
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// ociRepository identifies a repository of an OCI registry
+type ociRepository struct {
+	// Scheme is "https", unless the repository is prefixed by "http://"
+	Scheme     string
+	Registry   string
+	Repository string
+}
+
+// parseOCIRepository parses references like "ghcr.io/org/kubectl". The
+// "oci://" prefix is accepted, the "http://" one allows to use registries
+// not supporting TLS
+func parseOCIRepository(ref string) (ociRepository, error) {
+	repo := ociRepository{Scheme: "https"}
+	switch {
+	case strings.HasPrefix(ref, "http://"):
+		repo.Scheme = "http"
+		ref = strings.TrimPrefix(ref, "http://")
+	case strings.HasPrefix(ref, "oci://"):
+		ref = strings.TrimPrefix(ref, "oci://")
+	}
+
+	parts := strings.SplitN(strings.Trim(ref, "/"), "/", 2)
+	if len(parts) != 2 || parts[1] == "" || !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return repo, fmt.Errorf("Invalid OCI repository %q, the expected format is registry.example.com/path/to/kubectl", ref)
+	}
+	repo.Registry = parts[0]
+	repo.Repository = parts[1]
+	return repo, nil
+}
+
+// String returns the reference of the repository
+func (r ociRepository) String() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// registryAuth returns the credentials saved by `docker login` or
+// `oras login` for the given registry, encoded for basic authentication.
+// Credential helpers are not supported
+func registryAuth(registry string) string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		dir = filepath.Join(common.HomeDir(), ".docker")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return ""
+	}
+
+	var cfg struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ""
+	}
+	for _, key := range []string{registry, "https://" + registry, "http://" + registry} {
+		if auth, found := cfg.Auths[key]; found && auth.Auth != "" {
+			return auth.Auth
+		}
+	}
+	return ""
+}
+
+// parseChallenge parses the WWW-Authenticate header returned by the
+// registry, e.g. `Bearer realm="https://ghcr.io/token",scope="..."`
+func parseChallenge(header string) (string, map[string]string) {
+	params := map[string]string{}
+	fields := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(fields) == 2 {
+		for _, param := range strings.Split(fields[1], ",") {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 {
+				params[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+			}
+		}
+	}
+	return strings.ToLower(fields[0]), params
+}
+
+// registryClient talks to the distribution API of an OCI registry
+type registryClient struct {
+	client *http.Client
+	repo   ociRepository
+	// auth holds the credentials encoded for basic authentication
+	auth string
+	// authorization is sent with each request once the registry
+	// challenged the client
+	authorization string
+}
+
+// authenticate answers the challenge of the registry, anonymous tokens are
+// requested when there are no credentials
+func (c *registryClient) authenticate(challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if c.auth == "" {
+			return fmt.Errorf("%s requires credentials, log in with `docker login` or `oras login`", c.repo.Registry)
+		}
+		c.authorization = "Basic " + c.auth
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("Unsupported authentication scheme %q", scheme)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("Invalid authentication realm %q", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.auth != "" {
+		req.Header.Set("Authorization", "Basic "+c.auth)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Cannot authenticate against %s: %w", c.repo.Registry, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{URL: realm.String(), Status: resp.Status, Code: resp.StatusCode}
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("Cannot parse the token returned by %s: %v", realm.Host, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	c.authorization = "Bearer " + token.Token
+	return nil
+}
+
+// get issues a GET request against the given path of the repository,
+// authenticating when challenged by the registry
+func (c *registryClient) get(path, accept string) (*http.Response, error) {
+	u := fmt.Sprintf("%s://%s/v2/%s/%s", c.repo.Scheme, c.repo.Registry, c.repo.Repository, path)
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("Error while issuing GET request against %s: %w", u, err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := c.authenticate(challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, &statusError{URL: u, Status: resp.Status, Code: resp.StatusCode}
+		}
+		return resp, nil
+	}
+}
+
+// manifest fetches the manifest identified by the given tag or digest
+func (c *registryClient) manifest(reference string) (ociManifest, error) {
+	var m ociManifest
+	accept := strings.Join([]string{ociIndexType, ociManifestType, dockerListType, dockerManifestType}, ", ")
+	resp, err := c.get("manifests/"+reference, accept)
+	if err != nil {
+		return m, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return m, fmt.Errorf("Cannot parse the manifest of %s:%s: %v", c.repo, reference, err)
+	}
+	if m.MediaType == "" {
+		m.MediaType = resp.Header.Get("Content-Type")
+	}
+	return m, nil
+}
+
+// selectPlatform returns the manifest of the given platform listed by an
+// image index
+func selectPlatform(index ociManifest, goos, arch string) (ociDescriptor, bool) {
+	for _, m := range index.Manifests {
+		if m.Platform != nil && m.Platform.OS == goos && m.Platform.Architecture == arch {
+			return m, true
+		}
+	}
+	return ociDescriptor{}, false
+}
+
+// selectLayer returns the layer holding kubectl. Artifacts can either
+// ship one binary per platform, named like `kubectl-linux-amd64`, or be
+// specific to a platform and hold a binary named `kubectl`
+func selectLayer(m ociManifest, goos, arch string) (ociDescriptor, bool) {
+	ext := executableExt(goos)
+	for _, name := range []string{
+		fmt.Sprintf("kubectl-%s-%s%s", goos, arch, ext),
+		"kubectl" + ext,
+	} {
+		for _, layer := range m.Layers {
+			if layer.Annotations[ociTitleAnnotation] == name {
+				return layer, true
+			}
+		}
+	}
+	if len(m.Layers) == 1 {
+		return m.Layers[0], true
+	}
+	return ociDescriptor{}, false
+}
+
+// ociSourceURL identifies the artifact inside of the metadata of the
+// binaries pulled from a registry
+func ociSourceURL(repo ociRepository, tag string) string {
+	return "oci://" + repo.String() + ":" + tag
+}
+
+// pullFromRegistry pulls the kubectl binary built for the given platform
+// from the OCI repository, the artifact is expected to be tagged with the
+// version of kubectl prefixed by "v". When signatures are verified, the
+// binary must be the one released upstream. The check, when not nil, is
+// invoked against the verified binary. The source URL and the checksum of the
+// binary are returned
+func (d *Downloder) pullFromRegistry(version semver.Version, goos, arch, destination string, mode os.FileMode, check func(string) error) (string, string, error) {
+	repo, err := parseOCIRepository(d.Registry)
+	if err != nil {
+		return "", "", err
+	}
+	c := &registryClient{client: d.client(), repo: repo, auth: registryAuth(repo.Registry)}
+	tag := "v" + version.String()
+	sourceURL := ociSourceURL(repo, tag)
+
+	m, err := c.manifest(tag)
+	if err != nil {
+		return "", "", err
+	}
+	if m.MediaType == ociIndexType || m.MediaType == dockerListType || len(m.Manifests) > 0 {
+		desc, found := selectPlatform(m, goos, arch)
+		if !found {
+			return "", "", fmt.Errorf("There's no build of kubectl %s for %s/%s inside of %s", version, goos, arch, sourceURL)
+		}
+		if m, err = c.manifest(desc.Digest); err != nil {
+			return "", "", err
+		}
+	}
+	layer, found := selectLayer(m, goos, arch)
+	if !found {
+		return "", "", fmt.Errorf("There's no build of kubectl %s for %s/%s inside of %s", version, goos, arch, sourceURL)
+	}
+	if !strings.HasPrefix(layer.Digest, "sha256:") {
+		return "", "", fmt.Errorf("Unsupported digest %s of %s", layer.Digest, sourceURL)
+	}
+
+	resp, err := c.get("blobs/"+layer.Digest, "")
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(destination), os.ModePerm); err != nil {
+		return "", "", err
+	}
+	if err := d.ensureFreeSpace(filepath.Dir(destination), layer.Size); err != nil {
+		return "", "", err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(destination), common.TempDownloadPrefix)
+	if err != nil {
+		return "", "", err
+	}
+	tmpname := tmp.Name()
+	defer os.Remove(tmpname)
+
+	d.printf("Pulling %s\n", sourceURL)
+	bar := d.newBar(fmt.Sprintf("kubectl v%s %s/%s", version, goos, arch), layer.Size)
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hasher), io.TeeReader(throttle(resp.Body, d.MaxRate), bar))
+	tmp.Close()
+	if err != nil {
+		bar.Finish("failed.")
+		return "", "", fmt.Errorf("Error while pulling %s: %w", sourceURL, err)
+	}
+
+	bar.Status("verifying...")
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	if expected := strings.TrimPrefix(layer.Digest, "sha256:"); checksum != expected {
+		bar.Finish("verification failed.")
+		d.quarantine(tmpname, sourceURL, checksum, fmt.Sprintf("checksum mismatch, expected %s", expected))
+		return "", "", &common.ShaMismatchError{URL: sourceURL, ShaExpected: expected, ShaActual: checksum}
+	}
+	if err := checkExecutableFile(sourceURL, goos, tmpname); err != nil {
+		bar.Finish("failed.")
+		return "", "", err
+	}
+	if d.VerifySignatures {
+		if err := d.verifyUpstreamSignature(version, goos, arch, tmpname); err != nil {
+			bar.Finish("verification failed.")
+			d.quarantine(tmpname, sourceURL, checksum, "invalid signature")
+			return "", "", err
+		}
+	}
+	if check != nil {
+		bar.Status("testing...")
+		if err := check(tmpname); err != nil {
+			bar.Finish("verification failed.")
+			d.quarantine(tmpname, sourceURL, checksum, err.Error())
+			return "", "", err
+		}
+	}
+	bar.Finish("verified, done.")
+
+	if err := placeFile(tmpname, destination, mode); err != nil {
+		return "", "", err
+	}
+	return sourceURL, checksum, nil
+}
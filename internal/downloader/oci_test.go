@@ -0,0 +1,150 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestParseOCIRepository(t *testing.T) {
+	tests := []struct {
+		ref      string
+		valid    bool
+		expected ociRepository
+	}{
+		{"ghcr.io/org/kubectl", true, ociRepository{"https", "ghcr.io", "org/kubectl"}},
+		{"oci://harbor.corp:8443/tools/kubectl", true, ociRepository{"https", "harbor.corp:8443", "tools/kubectl"}},
+		{"http://localhost/kubectl", true, ociRepository{"http", "localhost", "kubectl"}},
+		{"org/kubectl", false, ociRepository{}},
+		{"ghcr.io", false, ociRepository{}},
+	}
+	for _, test := range tests {
+		repo, err := parseOCIRepository(test.ref)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: unexpected error %v", test.ref, err)
+			continue
+		}
+		if test.valid && repo != test.expected {
+			t.Errorf("%s: got %+v", test.ref, repo)
+		}
+	}
+}
+
+// newFakeRegistry serves kubectl as a multi platform artifact, anonymous
+// tokens are required to pull it
+func newFakeRegistry(t *testing.T, binary []byte) *httptest.Server {
+	hash := sha256.Sum256(binary)
+	blobDigest := "sha256:" + hex.EncodeToString(hash[:])
+
+	arch, err := targetArch()
+	if err != nil {
+		t.Skip(err)
+	}
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"mediaType": ociManifestType,
+		"layers": []map[string]interface{}{{
+			"mediaType":   "application/octet-stream",
+			"digest":      blobDigest,
+			"size":        len(binary),
+			"annotations": map[string]string{ociTitleAnnotation: "kubectl" + executableExt(runtime.GOOS)},
+		}},
+	})
+	index, _ := json.Marshal(map[string]interface{}{
+		"mediaType": ociIndexType,
+		"manifests": []map[string]interface{}{
+			{"digest": "sha256:other", "platform": map[string]string{"os": "plan9", "architecture": "386"}},
+			{"digest": "sha256:match", "platform": map[string]string{"os": runtime.GOOS, "architecture": arch}},
+		},
+	})
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"token":"secret"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:org/kubectl:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/org/kubectl/manifests/v1.20.1":
+			w.Header().Set("Content-Type", ociIndexType)
+			w.Write(index)
+		case "/v2/org/kubectl/manifests/sha256:match":
+			w.Header().Set("Content-Type", ociManifestType)
+			w.Write(manifest)
+		case "/v2/org/kubectl/blobs/" + blobDigest:
+			w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+func TestPullFromRegistry(t *testing.T) {
+	binary := fakeKubectl()
+	server := newFakeRegistry(t, binary)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-oci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("DOCKER_CONFIG", dir)
+	defer os.Unsetenv("DOCKER_CONFIG")
+
+	d := Downloder{Registry: "http://" + strings.TrimPrefix(server.URL, "http://") + "/org/kubectl"}
+	destination := filepath.Join(dir, "kubectl")
+	if err := d.GetKubectlBinary(semver.MustParse("1.20.1"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	actual, err := ioutil.ReadFile(destination)
+	if err != nil || string(actual) != string(binary) {
+		t.Errorf("Got %q, %v", actual, err)
+	}
+
+	if err := d.GetKubectlBinary(semver.MustParse("1.19.0"), destination); !isNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestPullFromRegistryRequiresSignedRelease(t *testing.T) {
+	server := newFakeRegistry(t, fakeKubectl())
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-oci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("DOCKER_CONFIG", dir)
+	defer os.Unsetenv("DOCKER_CONFIG")
+
+	d := Downloder{
+		Registry:         "http://" + strings.TrimPrefix(server.URL, "http://") + "/org/kubectl",
+		VerifySignatures: true,
+	}
+	destination := filepath.Join(dir, "kubectl")
+	if err := d.GetKubectlBinary(semver.MustParse("1.20.1"), destination); err == nil {
+		t.Error("kubectl 1.20.1 has not been signed, it must not be installed")
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Error("the binary should not be installed")
+	}
+}
@@ -0,0 +1,68 @@
+package downloader
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// firstDarwinArm64Release is the first release of kubectl built for
+// Apple silicon
+var firstDarwinArm64Release = semver.MustParse("1.21.0")
+
+// statusError is returned when the mirror answers with an unexpected
+// http status
+type statusError struct {
+	URL    string
+	Status string
+	Code   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("GET %s returned http status %s", e.URL, e.Status)
+}
+
+// isNotFound returns true when the mirror doesn't have the requested file
+func isNotFound(err error) bool {
+	e, ok := err.(*statusError)
+	return ok && e.Code == http.StatusNotFound
+}
+
+// upstreamArch returns the architecture of the upstream kubectl artifacts
+// able to run on this host, given the architecture kuberlr has been built
+// for and the hardware name reported by uname. Upstream builds 32 bit ARM
+// binaries only for ARMv7 and newer
+func upstreamArch(goarch, machine string) (string, error) {
+	if goarch != "arm" {
+		return goarch, nil
+	}
+
+	m := strings.ToLower(machine)
+	for _, old := range []string{"armv5", "armv6"} {
+		if strings.HasPrefix(m, old) {
+			return "", fmt.Errorf(
+				"There's no upstream build of kubectl for %s, only ARMv7 and newer processors are supported",
+				machine)
+		}
+	}
+	return goarch, nil
+}
+
+// targetArch returns the architecture of the kubectl binaries to download
+func targetArch() (string, error) {
+	return upstreamArch(runtime.GOARCH, machine())
+}
+
+// checkUpstreamBuild returns an error when upstream doesn't release the
+// given version of kubectl for the platform
+func checkUpstreamBuild(goos, arch string, version semver.Version) error {
+	if goos == "darwin" && arch == "arm64" && version.LT(firstDarwinArm64Release) {
+		return fmt.Errorf(
+			"There's no upstream build of kubectl %s for %s/%s, the first one is %s",
+			version, goos, arch, firstDarwinArm64Release)
+	}
+	return nil
+}
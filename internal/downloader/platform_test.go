@@ -0,0 +1,71 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestUpstreamArch(t *testing.T) {
+	tests := []struct {
+		goarch   string
+		machine  string
+		expected string
+	}{
+		{"amd64", "x86_64", "amd64"},
+		{"arm64", "aarch64", "arm64"},
+		{"arm", "armv7l", "arm"},
+		{"arm", "armv8l", "arm"},
+		{"arm", "aarch64", "arm"},
+		{"arm", "", "arm"},
+		{"arm", "armv6l", ""},
+		{"arm", "armv5tel", ""},
+	}
+	for _, test := range tests {
+		actual, err := upstreamArch(test.goarch, test.machine)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("%s/%s: expected an error", test.goarch, test.machine)
+			}
+			continue
+		}
+		if err != nil || actual != test.expected {
+			t.Errorf("%s/%s: got %q, %v instead of %q", test.goarch, test.machine, actual, err, test.expected)
+		}
+	}
+}
+
+func TestCheckUpstreamBuild(t *testing.T) {
+	if err := checkUpstreamBuild("darwin", "arm64", semver.MustParse("1.20.5")); err == nil {
+		t.Error("kubectl 1.20 has not been built for Apple silicon")
+	}
+	if err := checkUpstreamBuild("darwin", "arm64", semver.MustParse("1.21.0")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := checkUpstreamBuild("linux", "arm", semver.MustParse("1.10.0")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestGetKubectlBinaryMissingBuild(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{BaseURL: server.URL}
+	err = d.GetKubectlBinary(semver.MustParse("1.21.0"), filepath.Join(dir, "kubectl1.21.0"))
+	if err == nil || !strings.Contains(err.Error(), "There's no build of kubectl 1.21.0") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// PluginRequestKind identifies the requests sent to the download plugins
+const PluginRequestKind = "kuberlr.io/v1/DownloadRequest"
+
+// pluginPathPrefix is printed by the plugins that saved the artifact to a
+// local file, instead of writing it to stdout
+const pluginPathPrefix = "path:"
+
+// pluginTimeout is the amount of time a plugin is given to provide the
+// artifact
+const pluginTimeout = 10 * time.Minute
+
+// PluginRequest is written to the stdin of a download plugin. The plugin
+// answers by writing the kubectl binary to stdout or, when the binary is
+// already available on the local filesystem, by printing `path:` followed
+// by the path to the binary. Exiting with a non-zero code signals a
+// failure, the message printed to stderr is shown to the user
+type PluginRequest struct {
+	Kind    string `json:"kind"`
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+}
+
+// pluginSourceURL identifies the plugin inside of the metadata of the
+// binaries it provided
+func pluginSourceURL(plugin string) string {
+	return "exec://" + plugin
+}
+
+// runPlugin asks the download plugin for the given version of kubectl and
+// saves it to the destination. The checksum of the binary is returned
+func (d *Downloder) runPlugin(version semver.Version, destination string, mode os.FileMode) (string, error) {
+	// the plugin is asked for the build downloaded from the mirrors
+	arch, err := targetArch()
+	if err != nil {
+		return "", err
+	}
+	req, err := json.Marshal(PluginRequest{
+		Kind:    PluginRequestKind,
+		Version: version.String(),
+		OS:      runtime.GOOS,
+		Arch:    arch,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// the size of the binary provided by the plugin is not known
+	if err := d.ensureFreeSpace(filepath.Dir(destination), 0); err != nil {
+		return "", err
+	}
+	// the temporary file lives next to the destination, so that it can
+	// be renamed into place
+	tmp, err := ioutil.TempFile(filepath.Dir(destination), common.TempDownloadPrefix)
+	if err != nil {
+		return "", fmt.Errorf("Error trying to create temporary file in %s: %v", filepath.Dir(destination), err)
+	}
+	tmpname := tmp.Name()
+	defer os.Remove(tmpname)
+
+	entry := journalEntry{
+		TempFile: tmpname,
+		State:    journalDownloading,
+		Mode:     mode,
+		PID:      os.Getpid(),
+		Started:  time.Now().UTC(),
+	}
+	d.Journal.record(destination, &entry)
+	// only the installs interrupted by a crash are left inside of the
+	// journal, the failed ones are not
+	defer d.Journal.record(destination, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.SourcePlugin)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		tmp.Close()
+		return "", err
+	}
+
+	d.printf("Fetching kubectl %s from %s\n", version, d.SourcePlugin)
+	if err := cmd.Start(); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("Cannot start download plugin %s: %v", d.SourcePlugin, err)
+	}
+
+	hasher := sha256.New()
+	copyErr := copyPluginOutput(io.MultiWriter(tmp, hasher), stdout)
+	tmp.Close()
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("Download plugin %s failed: %v: %s", d.SourcePlugin, err, strings.TrimSpace(stderr.String()))
+	}
+	if copyErr != nil {
+		return "", fmt.Errorf("Cannot read the output of download plugin %s: %v", d.SourcePlugin, copyErr)
+	}
+	if err := checkExecutableFile(pluginSourceURL(d.SourcePlugin), runtime.GOOS, tmpname); err != nil {
+		return "", err
+	}
+	if check := d.installChecks(version, destination); check != nil {
+		if err := check(tmpname); err != nil {
+			return "", err
+		}
+	}
+
+	entry.State = journalVerified
+	d.Journal.record(destination, &entry)
+
+	if err := placeFile(tmpname, destination, mode); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// copyPluginOutput copies the artifact provided by the plugin, which is
+// either written to stdout or referenced by path
+func copyPluginOutput(dst io.Writer, stdout io.Reader) error {
+	out := bufio.NewReader(stdout)
+	prefix, _ := out.Peek(len(pluginPathPrefix))
+	if string(prefix) != pluginPathPrefix {
+		_, err := io.Copy(dst, out)
+		return err
+	}
+
+	data, err := ioutil.ReadAll(out)
+	if err != nil {
+		return err
+	}
+	path := strings.TrimSpace(strings.TrimPrefix(string(data), pluginPathPrefix))
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(dst, f)
+	return err
+}
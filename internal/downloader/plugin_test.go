@@ -0,0 +1,87 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSourcePlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake plugins are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "kuberlr-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	artifact := filepath.Join(dir, "artifact")
+	if err := ioutil.WriteFile(artifact, fakeKubectl(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(fakeKubectl())
+	arch, err := targetArch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugins := map[string]string{
+		// the request is checked, this ensures it's well formed and asks
+		// for the build downloaded from the mirrors
+		"stdout": fmt.Sprintf("grep '\"version\":\"1.20.1\"' | grep -q '\"arch\":\"%s\"' && cat %s\n", arch, artifact),
+		"path":   fmt.Sprintf("cat > /dev/null; echo 'path:%s'\n", artifact),
+	}
+	for name, script := range plugins {
+		completed := []semver.Version{}
+		d := Downloder{
+			SourcePlugin: writePlugin(t, dir, name, script),
+			OnCompletion: func(version semver.Version, destination string, elapsed time.Duration) {
+				completed = append(completed, version)
+			},
+		}
+		destination := filepath.Join(dir, "bin-"+name, "kubectl1.20.1")
+		if err := d.GetKubectlBinary(semver.MustParse("1.20.1"), destination); err != nil {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		if len(completed) != 1 || !completed[0].Equals(semver.MustParse("1.20.1")) {
+			t.Errorf("%s: unexpected completions %v", name, completed)
+		}
+
+		data, err := ioutil.ReadFile(destination)
+		if err != nil || string(data) != string(fakeKubectl()) {
+			t.Errorf("%s: got %q, %v", name, data, err)
+		}
+		m, found, err := common.LoadMetadata(destination)
+		if err != nil || !found {
+			t.Fatalf("%s: metadata not found: %v", name, err)
+		}
+		if m.SHA256 != hex.EncodeToString(hash[:]) || m.SourceURL != "exec://"+d.SourcePlugin {
+			t.Errorf("%s: unexpected metadata %+v", name, m)
+		}
+	}
+
+	d := Downloder{SourcePlugin: writePlugin(t, dir, "failing", "echo 'not found' >&2; exit 1\n")}
+	if err := d.GetKubectlBinary(semver.MustParse("1.20.1"), filepath.Join(dir, "kubectl")); err == nil {
+		t.Error("Expected an error")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package downloader
+
+import "syscall"
+
+// processAlive returns true when the process with the given pid is running
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
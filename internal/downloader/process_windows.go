@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package downloader
+
+import "os"
+
+// processAlive returns true when the process with the given pid is running.
+// On Windows looking up a process fails when it doesn't exist
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
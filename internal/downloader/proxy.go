@@ -0,0 +1,40 @@
+package downloader
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DirectProxy is the proxy setting of the mirrors reached without going
+// through any proxy
+const DirectProxy = "direct"
+
+// Mirror is a mirror of the kubernetes release bucket
+type Mirror struct {
+	// URL is the location of the mirror
+	URL string
+	// Proxy is the proxy used to reach the mirror, DirectProxy to reach
+	// it directly. The proxy configured for kuberlr is used when empty
+	Proxy string
+}
+
+// CheckProxy returns an error when the given proxy setting is not valid
+func CheckProxy(text string) error {
+	_, err := proxyFunc(text)
+	return err
+}
+
+// proxyFunc returns the function choosing the proxy of the requests made
+// against a mirror with the given proxy setting
+func proxyFunc(text string) (func(*http.Request) (*url.URL, error), error) {
+	if strings.EqualFold(text, DirectProxy) {
+		return nil, nil
+	}
+	u, err := url.Parse(text)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("Invalid proxy URL %q", text)
+	}
+	return http.ProxyURL(u), nil
+}
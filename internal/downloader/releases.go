@@ -0,0 +1,270 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog"
+)
+
+// GitHubReleasesURL URL of the GitHub API endpoint listing the releases
+// of kubernetes
+const GitHubReleasesURL = "https://api.github.com/repos/kubernetes/kubernetes/releases?per_page=100"
+
+var nextPageRe = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Draft   bool   `json:"draft"`
+}
+
+// releasesPage is a page of the GitHub releases API as stored
+// inside of the cache
+type releasesPage struct {
+	ETag     string   `json:"etag"`
+	Versions []string `json:"versions"`
+	Next     string   `json:"next,omitempty"`
+}
+
+// releasesCache maps the URL of each page to its contents
+type releasesCache map[string]releasesPage
+
+func loadReleasesCache(path string) releasesCache {
+	cache := releasesCache{}
+	if path == "" {
+		return cache
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.V(1).Infof("Cannot read %s: %v", path, err)
+		}
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		klog.V(1).Infof("Ignoring malformed releases cache %s: %v", path, err)
+		return releasesCache{}
+	}
+	return cache
+}
+
+func (c releasesCache) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// RemoteVersions returns all the versions of kubernetes released upstream,
+// sorted from the oldest to the newest one
+func (d *Downloder) RemoteVersions() (semver.Versions, error) {
+	return d.remoteVersions(GitHubReleasesURL)
+}
+
+func (d *Downloder) remoteVersions(firstPage string) (semver.Versions, error) {
+	cache := loadReleasesCache(d.ReleasesCache)
+	visited := map[string]bool{}
+	versions := semver.Versions{}
+
+	for pageURL := firstPage; pageURL != "" && !visited[pageURL]; {
+		visited[pageURL] = true
+
+		page, err := d.fetchReleasesPage(pageURL, cache[pageURL])
+		if err != nil {
+			return semver.Versions{}, err
+		}
+		cache[pageURL] = page
+
+		for _, tag := range page.Versions {
+			v, err := semver.ParseTolerant(tag)
+			if err != nil {
+				klog.V(1).Infof("Ignoring release %s: %v", tag, err)
+				continue
+			}
+			versions = append(versions, v)
+		}
+		pageURL = page.Next
+	}
+
+	if err := cache.save(d.ReleasesCache); err != nil {
+		klog.V(1).Infof("Cannot save releases cache: %v", err)
+	}
+
+	semver.Sort(versions)
+	return versions, nil
+}
+
+// fetchReleasesPage retrieves a page of the GitHub releases API. The
+// cached copy of the page is returned when it didn't change, these
+// requests do not count against the rate limit of GitHub
+func (d *Downloder) fetchReleasesPage(pageURL string, cached releasesPage) (releasesPage, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return releasesPage{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if d.GitHubToken != "" {
+		req.Header.Set("Authorization", "token "+d.GitHubToken)
+	}
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	res, err := d.client().Do(req)
+	if err != nil {
+		return releasesPage{}, fmt.Errorf("Error while issuing GET request against %s: %v", pageURL, err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		klog.V(4).Infof("%s did not change, using cached copy", pageURL)
+		return cached, nil
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if res.Header.Get("X-RateLimit-Remaining") == "0" {
+			hint := ""
+			if d.GitHubToken == "" {
+				hint = ", set the GITHUB_TOKEN environment variable or the GitHubToken configuration option to raise the limit"
+			}
+			return releasesPage{}, fmt.Errorf("GitHub API rate limit exceeded%s", hint)
+		}
+		fallthrough
+	default:
+		return releasesPage{}, fmt.Errorf("GET %s returned http status %s", pageURL, res.Status)
+	}
+
+	releases := []githubRelease{}
+	if err := json.NewDecoder(res.Body).Decode(&releases); err != nil {
+		return releasesPage{}, fmt.Errorf("Error while decoding response of %s: %v", pageURL, err)
+	}
+
+	page := releasesPage{
+		ETag:     res.Header.Get("ETag"),
+		Versions: []string{},
+	}
+	for _, r := range releases {
+		if !r.Draft {
+			page.Versions = append(page.Versions, r.TagName)
+		}
+	}
+	if m := nextPageRe.FindStringSubmatch(res.Header.Get("Link")); m != nil {
+		page.Next = m[1]
+	}
+
+	return page, nil
+}
+
+// VersionFilter narrows down a list of versions
+type VersionFilter struct {
+	// Minor keeps only the versions with this major and minor
+	Minor *semver.Version
+	// Since keeps only the versions greater or equal than this one
+	Since *semver.Version
+	// Limit keeps only the newest N versions, 0 means no limit
+	Limit int
+	// IncludePrerelease keeps alpha, beta and release candidate versions
+	IncludePrerelease bool
+}
+
+// Apply returns the versions matching the filter, sorted from the newest
+// to the oldest one
+func (f VersionFilter) Apply(versions semver.Versions) semver.Versions {
+	sorted := make(semver.Versions, len(versions))
+	copy(sorted, versions)
+	semver.Sort(sorted)
+
+	res := semver.Versions{}
+	for i := len(sorted) - 1; i >= 0; i-- {
+		v := sorted[i]
+		if f.Limit > 0 && len(res) == f.Limit {
+			break
+		}
+		if len(v.Pre) > 0 && !f.IncludePrerelease {
+			continue
+		}
+		if f.Minor != nil && (v.Major != f.Minor.Major || v.Minor != f.Minor.Minor) {
+			continue
+		}
+		if f.Since != nil && v.LT(*f.Since) {
+			continue
+		}
+		res = append(res, v)
+	}
+
+	return res
+}
+
+// LatestPatches returns the most recent patch release of the newest n
+// minor releases, prereleases are ignored. Versions are sorted from the
+// newest to the oldest one
+func LatestPatches(versions semver.Versions, n int) semver.Versions {
+	res := semver.Versions{}
+	for _, v := range (VersionFilter{}).Apply(versions) {
+		if len(res) == n {
+			break
+		}
+		if len(res) > 0 {
+			last := res[len(res)-1]
+			if last.Major == v.Major && last.Minor == v.Minor {
+				continue
+			}
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// LatestPatchOf returns the most recent patch release of the minor release
+// of the given version, prereleases are ignored
+func LatestPatchOf(versions semver.Versions, minor semver.Version) (semver.Version, bool) {
+	matching := VersionFilter{Minor: &minor, Limit: 1}.Apply(versions)
+	if len(matching) == 0 {
+		return semver.Version{}, false
+	}
+	return matching[0], true
+}
+
+// minorRangeExpr matches ranges of minor releases like "1.27-1.30"
+var minorRangeExpr = regexp.MustCompile(`^v?(\d+)\.(\d+)-v?(\d+)\.(\d+)$`)
+
+// ParseMinorRange parses ranges of minor releases like "1.27-1.30", found
+// is false when the given text is not a range. The minor releases within
+// the range are returned, the patch versions are zero
+func ParseMinorRange(text string) (semver.Versions, bool, error) {
+	m := minorRangeExpr.FindStringSubmatch(text)
+	if m == nil {
+		return nil, false, nil
+	}
+	from, err := semver.ParseTolerant(m[1] + "." + m[2])
+	if err != nil {
+		return nil, true, err
+	}
+	to, err := semver.ParseTolerant(m[3] + "." + m[4])
+	if err != nil {
+		return nil, true, err
+	}
+	if from.Major != to.Major || from.Minor > to.Minor {
+		return nil, true, fmt.Errorf("Invalid range %s, the minor releases must share the same major and be in ascending order", text)
+	}
+
+	minors := semver.Versions{}
+	for v := from; v.Minor <= to.Minor; v.Minor++ {
+		minors = append(minors, v)
+	}
+	return minors, true, nil
+}
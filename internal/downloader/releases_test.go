@@ -0,0 +1,175 @@
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestRemoteVersions(t *testing.T) {
+	var server *httptest.Server
+	requests := 0
+	notModified := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/releases", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "token secret" {
+			t.Errorf("Missing authorization header")
+		}
+
+		page := r.URL.Query().Get("page")
+		etag := fmt.Sprintf(`"page-%s"`, page)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		if page == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/releases?page=2>; rel="next", <%s/releases?page=2>; rel="last"`, server.URL, server.URL))
+			w.Write([]byte(`[{"tag_name": "v1.20.1"}, {"tag_name": "v1.21.0-beta.0"}, {"tag_name": "v1.22.0", "draft": true}]`))
+			return
+		}
+		w.Write([]byte(`[{"tag_name": "v1.19.4"}, {"tag_name": "not-a-version"}]`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{
+		GitHubToken:   "secret",
+		ReleasesCache: filepath.Join(dir, "releases.json"),
+	}
+
+	for i := 0; i < 2; i++ {
+		versions, err := d.remoteVersions(server.URL + "/releases")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"1.19.4", "1.20.1", "1.21.0-beta.0"}
+		if len(versions) != len(expected) {
+			t.Fatalf("Got %v instead of %v", versions, expected)
+		}
+		for j, v := range versions {
+			if v.String() != expected[j] {
+				t.Errorf("Got %s instead of %s", v, expected[j])
+			}
+		}
+	}
+
+	if requests != 4 {
+		t.Errorf("Expected 4 requests, got %d", requests)
+	}
+	if notModified != 2 {
+		t.Errorf("Expected the second listing to be served from cache, got %d cached pages", notModified)
+	}
+}
+
+func TestRemoteVersionsRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	d := Downloder{}
+	if _, err := d.remoteVersions(server.URL); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestVersionFilter(t *testing.T) {
+	versions := semver.Versions{}
+	for _, v := range []string{"1.19.4", "1.20.0", "1.20.1", "1.20.2-rc.0", "1.20.2", "1.21.0-beta.0", "1.21.0"} {
+		versions = append(versions, semver.MustParse(v))
+	}
+	minor := semver.MustParse("1.20.0")
+	since := semver.MustParse("1.20.1")
+
+	tests := []struct {
+		filter   VersionFilter
+		expected []string
+	}{
+		{VersionFilter{}, []string{"1.21.0", "1.20.2", "1.20.1", "1.20.0", "1.19.4"}},
+		{VersionFilter{Limit: 2}, []string{"1.21.0", "1.20.2"}},
+		{VersionFilter{Minor: &minor}, []string{"1.20.2", "1.20.1", "1.20.0"}},
+		{VersionFilter{Since: &since, IncludePrerelease: true}, []string{"1.21.0", "1.21.0-beta.0", "1.20.2", "1.20.2-rc.0", "1.20.1"}},
+		{VersionFilter{Minor: &minor, Since: &since, Limit: 1}, []string{"1.20.2"}},
+	}
+
+	for _, test := range tests {
+		actual := test.filter.Apply(versions)
+		if len(actual) != len(test.expected) {
+			t.Errorf("%+v: got %v instead of %v", test.filter, actual, test.expected)
+			continue
+		}
+		for i, v := range actual {
+			if v.String() != test.expected[i] {
+				t.Errorf("%+v: got %v instead of %v", test.filter, actual, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestLatestPatches(t *testing.T) {
+	versions := semver.Versions{}
+	for _, v := range []string{"1.19.4", "1.19.5", "1.20.0", "1.20.2", "1.20.1", "1.21.0-rc.1", "1.21.0-beta.0"} {
+		versions = append(versions, semver.MustParse(v))
+	}
+
+	actual := LatestPatches(versions, 3)
+	expected := []string{"1.20.2", "1.19.5"}
+	if len(actual) != len(expected) {
+		t.Fatalf("Got %v instead of %v", actual, expected)
+	}
+	for i, v := range actual {
+		if v.String() != expected[i] {
+			t.Errorf("Got %v instead of %v", actual, expected)
+		}
+	}
+}
+
+func TestLatestPatchOf(t *testing.T) {
+	versions := semver.Versions{}
+	for _, v := range []string{"1.19.4", "1.20.0", "1.20.2", "1.21.0-rc.1"} {
+		versions = append(versions, semver.MustParse(v))
+	}
+
+	if v, found := LatestPatchOf(versions, semver.MustParse("1.20.0")); !found || v.String() != "1.20.2" {
+		t.Errorf("Got %v, %v", v, found)
+	}
+	if v, found := LatestPatchOf(versions, semver.MustParse("1.21.0")); found {
+		t.Errorf("Prereleases should be ignored, got %v", v)
+	}
+}
+
+func TestParseMinorRange(t *testing.T) {
+	minors, found, err := ParseMinorRange("1.27-1.30")
+	if err != nil || !found {
+		t.Fatalf("Got %v, %v", found, err)
+	}
+	if len(minors) != 4 || minors[0].String() != "1.27.0" || minors[3].String() != "1.30.0" {
+		t.Errorf("Unexpected minor releases %v", minors)
+	}
+
+	if _, found, _ := ParseMinorRange("1.27.3"); found {
+		t.Error("A version is not a range")
+	}
+	if _, found, err := ParseMinorRange("1.30-1.27"); !found || err == nil {
+		t.Error("Expected descending ranges to be refused")
+	}
+}
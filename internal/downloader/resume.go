@@ -0,0 +1,87 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// errRangeIgnored is returned when the mirror answered a range request
+// with the whole file
+var errRangeIgnored = errors.New("The mirror doesn't support range requests")
+
+// partialFile returns where the binary being downloaded to the given
+// destination is written. The name must not be mistaken for a kubectl
+// binary, the file is removed by the garbage collector when the download
+// is never resumed
+func partialFile(destination string) string {
+	return filepath.Join(
+		filepath.Dir(destination),
+		common.TempDownloadPrefix+filepath.Base(destination)+".partial")
+}
+
+// resumeOffset returns how many bytes of the given URL have already been
+// downloaded into the partial file. Zero is returned, and the partial file
+// is removed, when the download cannot be resumed
+func resumeOffset(partial, urlToGet, goos string) int64 {
+	info, err := os.Stat(partial)
+	if err != nil {
+		return 0
+	}
+
+	// compressed artifacts are decompressed while being downloaded, the
+	// size of the partial file doesn't match the amount of bytes received
+	compressed := false
+	if u, err := url.Parse(urlToGet); err == nil {
+		compressed = strings.HasSuffix(u.Path, ".gz") || strings.HasSuffix(u.Path, ".zst")
+	}
+	if compressed || info.Size() < sniffLength || checkExecutableFile(urlToGet, goos, partial) != nil {
+		klog.V(1).Infof("Discarding the partial download %s", partial)
+		os.Remove(partial)
+		return 0
+	}
+	return info.Size()
+}
+
+// checkResumedResponse makes sure the mirror answered the range request
+// with the rest of the file
+func checkResumedResponse(resp *http.Response, offset int64) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return errRangeIgnored
+	case http.StatusPartialContent:
+	default:
+		return fmt.Errorf("GET %s returned http status %s", resp.Request.URL, resp.Status)
+	}
+
+	if enc := contentEncoding(resp); enc != "" {
+		return fmt.Errorf("The rest of the file is compressed with %s", enc)
+	}
+	expected := fmt.Sprintf("bytes %d-", offset)
+	if cr := resp.Header.Get("Content-Range"); !strings.HasPrefix(cr, expected) {
+		return fmt.Errorf("Unexpected Content-Range %q, expected it to start with %q", cr, expected)
+	}
+	return nil
+}
+
+// hashFile feeds the contents of the given file to the hasher
+func hashFile(hasher hash.Hash, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(hasher, f)
+	return err
+}
@@ -0,0 +1,95 @@
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestResumeDownload(t *testing.T) {
+	contents := append(fakeKubectl(), bytes.Repeat([]byte("x"), 2*sniffLength)...)
+	hash := sha256.Sum256(contents)
+
+	for _, supportsRange := range []bool{true, false} {
+		ranges := []string{}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/kubectl", func(w http.ResponseWriter, r *http.Request) {
+			ranges = append(ranges, r.Header.Get("Range"))
+			if !supportsRange {
+				w.Write(contents)
+				return
+			}
+			http.ServeContent(w, r, "kubectl", time.Time{}, bytes.NewReader(contents))
+		})
+		mux.HandleFunc("/kubectl.sha256", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(hex.EncodeToString(hash[:])))
+		})
+		server := httptest.NewServer(mux)
+
+		dir, err := ioutil.TempDir("", "kuberlr-resume")
+		if err != nil {
+			t.Fatal(err)
+		}
+		destination := filepath.Join(dir, "kubectl")
+		if err := ioutil.WriteFile(partialFile(destination), contents[:sniffLength+10], 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		d := Downloder{}
+		checksum, err := d.download("kubectl", server.URL+"/kubectl", destination, 0755)
+		server.Close()
+		if err != nil {
+			t.Fatalf("range support %v: unexpected error: %v", supportsRange, err)
+		}
+		if checksum != hex.EncodeToString(hash[:]) {
+			t.Errorf("range support %v: got checksum %s", supportsRange, checksum)
+		}
+		if len(ranges) != 1 || ranges[0] != "bytes=522-" {
+			t.Errorf("range support %v: unexpected requests %q", supportsRange, ranges)
+		}
+		if _, err := os.Stat(partialFile(destination)); !os.IsNotExist(err) {
+			t.Errorf("range support %v: the partial file has not been removed", supportsRange)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestResumeOffset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-resume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	partial := filepath.Join(dir, "partial")
+	head := append(fakeKubectl(), bytes.Repeat([]byte("x"), sniffLength)...)
+	tests := []struct {
+		contents []byte
+		url      string
+		offset   int64
+	}{
+		{head, "https://example.com/kubectl", int64(len(head))},
+		{head, "https://example.com/kubectl.gz", 0},
+		{head[:10], "https://example.com/kubectl", 0},
+		{bytes.Repeat([]byte("<html>"), sniffLength), "https://example.com/kubectl", 0},
+	}
+	for i, test := range tests {
+		if err := ioutil.WriteFile(partial, test.contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if offset := resumeOffset(partial, test.url, runtime.GOOS); offset != test.offset {
+			t.Errorf("%d: got offset %d instead of %d", i, offset, test.offset)
+		}
+		if _, err := os.Stat(partial); (err == nil) != (test.offset > 0) {
+			t.Errorf("%d: the partial file should be removed only when it cannot be used", i)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultRetryDelay is the delay before the first retry of a failed download
+const DefaultRetryDelay = time.Second
+
+// maxRetryDelay caps the delay between two retries
+const maxRetryDelay = 30 * time.Second
+
+// isTransient returns true when the given download error is likely to go
+// away by trying again: network failures, timeouts, truncated transfers
+// and the server errors of the mirror
+func isTransient(err error) bool {
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.Code >= 500 ||
+			status.Code == http.StatusTooManyRequests ||
+			status.Code == http.StatusRequestTimeout
+	}
+
+	var stall *stallError
+	if errors.As(err, &stall) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		// the hosts that don't exist are not going to appear
+		return !dnsErr.IsNotFound
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryDelay returns how long to wait before the given retry, starting
+// from 1. The delay doubles at each retry and a random jitter of up to
+// half of it is removed, this prevents many clients from hammering the
+// mirror at the same time
+func retryDelay(retry int, base time.Duration, random func() float64) time.Duration {
+	if base <= 0 {
+		base = DefaultRetryDelay
+	}
+	delay := base
+	for i := 1; i < retry && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay - time.Duration(random()*float64(delay)/2)
+}
@@ -0,0 +1,110 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{&statusError{Code: http.StatusServiceUnavailable}, true},
+		{&statusError{Code: http.StatusTooManyRequests}, true},
+		{&statusError{Code: http.StatusForbidden}, false},
+		{fmt.Errorf("Error while issuing GET request: %w", &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}), true},
+		{&net.DNSError{Err: "server misbehaving", IsTemporary: true}, true},
+		{&net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{fmt.Errorf("Error while downloading: %w", io.ErrUnexpectedEOF), true},
+		{errors.New("The mirror is serving a web page"), false},
+	}
+	for i, test := range tests {
+		if transient := isTransient(test.err); transient != test.transient {
+			t.Errorf("%d: %v is transient: %v", i, test.err, transient)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	noJitter := func() float64 { return 0 }
+	fullJitter := func() float64 { return 1 }
+
+	tests := []struct {
+		retry    int
+		random   func() float64
+		expected time.Duration
+	}{
+		{1, noJitter, time.Second},
+		{2, noJitter, 2 * time.Second},
+		{4, noJitter, 8 * time.Second},
+		{4, fullJitter, 4 * time.Second},
+		{20, noJitter, maxRetryDelay},
+	}
+	for _, test := range tests {
+		if delay := retryDelay(test.retry, time.Second, test.random); delay != test.expected {
+			t.Errorf("retry %d: got %s instead of %s", test.retry, delay, test.expected)
+		}
+	}
+}
+
+func TestGetKubectlBinaryRetries(t *testing.T) {
+	contents := fakeKubectl()
+	hash := sha256.Sum256(contents)
+	arch, err := targetArch()
+	if err != nil {
+		t.Skip(err)
+	}
+	binaryPath := fmt.Sprintf("/v1.20.1/bin/%s/%s/kubectl%s", runtime.GOOS, arch, executableExt(runtime.GOOS))
+
+	failures := 2
+	mux := http.NewServeMux()
+	mux.HandleFunc(binaryPath, func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(contents)
+	})
+	mux.HandleFunc(binaryPath+".sha256", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(hex.EncodeToString(hash[:])))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	version := semver.MustParse("1.20.1")
+	d := Downloder{BaseURL: server.URL, Retries: 1, RetryDelay: time.Millisecond}
+	if err := d.GetKubectlBinary(version, filepath.Join(dir, "kubectl")); err == nil {
+		t.Fatal("Expected the download to fail after one retry")
+	}
+
+	d.Retries = 2
+	failures = 2
+	if err := d.GetKubectlBinary(version, filepath.Join(dir, "kubectl")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failures != 0 {
+		t.Errorf("Expected all the failures to be retried, %d left", failures)
+	}
+}
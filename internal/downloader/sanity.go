@@ -0,0 +1,48 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// sanityCheck returns the function executing a freshly downloaded binary
+// to make sure it runs and reports the expected version, before it's
+// installed. nil is returned when the check is disabled
+func (d *Downloder) sanityCheck(version semver.Version) func(binary string) error {
+	if !d.SanityCheck {
+		return nil
+	}
+	return func(binary string) error {
+		return checkReportedVersion(binary, version)
+	}
+}
+
+// checkReportedVersion executes the given kubectl binary and makes sure
+// it reports the expected version
+func checkReportedVersion(binary string, expected semver.Version) error {
+	if err := os.Chmod(binary, 0755); err != nil {
+		return err
+	}
+	// windows executes only the files with a known extension
+	if ext := executableExt(runtime.GOOS); ext != "" {
+		if err := os.Rename(binary, binary+ext); err != nil {
+			return err
+		}
+		defer os.Rename(binary+ext, binary)
+		binary += ext
+	}
+
+	reported, err := common.ReportedKubectlVersion(binary)
+	if err != nil {
+		return fmt.Errorf("The downloaded kubectl %s doesn't work: %v", expected, err)
+	}
+	if !reported.EQ(expected) {
+		return fmt.Errorf("The downloaded kubectl %s reports version %s", expected, reported)
+	}
+	return nil
+}
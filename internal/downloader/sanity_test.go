@@ -0,0 +1,98 @@
+package downloader
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestCheckReportedVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake kubectl executables are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "kuberlr-sanity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	scripts := map[string]struct {
+		script string
+		valid  bool
+	}{
+		"expected version": {
+			script: `echo '{"clientVersion": {"gitVersion": "v1.20.1"}}'`,
+			valid:  true,
+		},
+		"other version": {
+			script: `echo '{"clientVersion": {"gitVersion": "v1.19.4"}}'`,
+			valid:  false,
+		},
+		"crash": {
+			script: "exit 1",
+			valid:  false,
+		},
+		"garbage": {
+			script: "echo hello",
+			valid:  false,
+		},
+	}
+
+	for name, tt := range scripts {
+		// the downloaded file isn't executable yet
+		binary := filepath.Join(dir, "kubectl")
+		if err := ioutil.WriteFile(binary, []byte("#!/bin/sh\n"+tt.script+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		err := checkReportedVersion(binary, semver.MustParse("1.20.1"))
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDownloadRunsCheck(t *testing.T) {
+	contents := fakeKubectl()
+	server := newFakeSignedMirror(contents)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-sanity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{QuarantineDir: filepath.Join(dir, "quarantine")}
+	destination := filepath.Join(dir, "kubectl")
+	broken := errors.New("broken binary")
+	checked := ""
+	check := func(binary string) error {
+		checked = binary
+		return broken
+	}
+
+	_, err = d.downloadFor(runtime.GOOS, "kubectl", server.URL+"/kubectl", destination, 0755, check)
+	if err != broken {
+		t.Fatalf("Expected the error of the check, got %v", err)
+	}
+	if checked == "" || checked == destination {
+		t.Errorf("The check must run against the temporary file, got %q", checked)
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Error("The binary failing the check has been installed")
+	}
+	quarantined, _ := ioutil.ReadDir(d.QuarantineDir)
+	if len(quarantined) == 0 {
+		t.Error("The binary failing the check has not been quarantined")
+	}
+}
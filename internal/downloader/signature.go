@@ -0,0 +1,100 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// DefaultCosign is the cosign executable used when none is configured,
+// it's looked up inside of the PATH
+const DefaultCosign = "cosign"
+
+// kubernetesSigningIdentity and kubernetesSigningIssuer identify the
+// keyless certificates used to sign the kubernetes release artifacts
+const (
+	kubernetesSigningIdentity = "krel-staging@k8s-releng-prod.iam.gserviceaccount.com"
+	kubernetesSigningIssuer   = "https://accounts.google.com"
+)
+
+// firstSignedRelease is the first release of kubernetes whose binaries
+// have been signed
+var firstSignedRelease = semver.MustParse("1.26.0")
+
+// checkSignedRelease returns an error when upstream didn't sign the
+// binaries of the given version
+func checkSignedRelease(version semver.Version) error {
+	if version.LT(firstSignedRelease) {
+		return fmt.Errorf(
+			"kubectl %s cannot be verified, only the binaries of kubernetes %s and later are signed",
+			version, firstSignedRelease)
+	}
+	return nil
+}
+
+// verifySignature checks the signature of the binary downloaded from
+// the given URL using cosign. The signature and the certificate are
+// published next to the binary
+func (d *Downloder) verifySignature(urlToGet, binary string) error {
+	cosign := d.Cosign
+	if cosign == "" {
+		cosign = DefaultCosign
+	}
+	if _, err := exec.LookPath(cosign); err != nil {
+		return fmt.Errorf("Cannot verify the signature of %s, cosign is not available: %v", urlToGet, err)
+	}
+
+	dir, err := ioutil.TempDir("", common.TempDownloadPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{}
+	for _, ext := range []string{".sig", ".cert"} {
+		contents, err := d.getContentsOfURL(urlToGet + ext)
+		if err != nil {
+			return fmt.Errorf("Cannot get the signature of %s: %v", urlToGet, err)
+		}
+		files[ext] = filepath.Join(dir, "kubectl"+ext)
+		if err := ioutil.WriteFile(files[ext], []byte(contents), 0600); err != nil {
+			return err
+		}
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(cosign, "verify-blob", binary,
+		"--signature", files[".sig"],
+		"--certificate", files[".cert"],
+		"--certificate-identity", kubernetesSigningIdentity,
+		"--certificate-oidc-issuer", kubernetesSigningIssuer)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("The signature of %s is not valid: %v: %s", urlToGet, err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// verifyUpstreamSignature checks the given binary, obtained from somewhere
+// else than the mirror, against the signature published upstream for the
+// given platform
+func (d *Downloder) verifyUpstreamSignature(version semver.Version, goos, arch, binary string) error {
+	upstream := *d
+	upstream.BaseURL = ""
+	upstream.URLTemplate = ""
+	upstream.FallbackMirrors = nil
+	urlToGet, err := upstream.kubectlDownloadURL(version, goos, arch)
+	if err != nil {
+		return err
+	}
+	return upstream.verifySignature(urlToGet, binary)
+}
@@ -0,0 +1,89 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func newFakeSignedMirror(contents []byte) *httptest.Server {
+	hash := sha256.Sum256(contents)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kubectl", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(contents)
+	})
+	mux.HandleFunc("/kubectl.sha256", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(hex.EncodeToString(hash[:])))
+	})
+	mux.HandleFunc("/kubectl.sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fake signature"))
+	})
+	mux.HandleFunc("/kubectl.cert", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fake certificate"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCheckSignedRelease(t *testing.T) {
+	if err := checkSignedRelease(semver.MustParse("1.25.9")); err == nil {
+		t.Error("kubectl 1.25 has not been signed")
+	}
+	if err := checkSignedRelease(semver.MustParse("1.26.0")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDownloadVerifiesSignature(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake cosign executables are shell scripts")
+	}
+
+	server := newFakeSignedMirror(fakeKubectl())
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-signature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cosigns := map[string]string{
+		// the signature and the certificate must be given to cosign
+		"valid":   `grep -q 'fake signature' "$4" && grep -q 'fake certificate' "$6" && [ "$8" = "` + kubernetesSigningIdentity + `" ]`,
+		"invalid": "echo 'error: invalid signature when validating ASN.1 encoded signature' >&2; exit 1",
+	}
+	for name, script := range cosigns {
+		d := Downloder{
+			VerifySignatures: true,
+			Cosign:           writePlugin(t, dir, "cosign-"+name, script+"\n"),
+			QuarantineDir:    filepath.Join(dir, "quarantine-"+name),
+		}
+		destination := filepath.Join(dir, "kubectl-"+name)
+		_, err := d.download("kubectl", server.URL+"/kubectl", destination, 0755)
+
+		if name == "valid" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), "invalid signature") {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+		if _, err := os.Stat(destination); !os.IsNotExist(err) {
+			t.Errorf("%s: the binary should not be installed", name)
+		}
+		if quarantined, _ := filepath.Glob(filepath.Join(d.QuarantineDir, "*")); len(quarantined) == 0 {
+			t.Errorf("%s: the binary should be quarantined", name)
+		}
+	}
+}
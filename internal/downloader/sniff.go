@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// sniffLength is the amount of bytes inspected to recognize the responses
+// that are not kubectl binaries
+const sniffLength = 512
+
+// shownLength is the amount of bytes of a suspicious response included
+// inside of the error message
+const shownLength = 64
+
+// webPageTypes are the media types of the pages served by proxies and
+// captive portals, both to humans and to API clients
+var webPageTypes = map[string]bool{
+	"text/html":             true,
+	"application/xhtml+xml": true,
+	"application/json":      true,
+	"text/xml":              true,
+	"application/xml":       true,
+}
+
+// checkNotWebPage returns an error when the content type, or the first
+// bytes of the response, look like a web page or a JSON document. This
+// happens when a proxy or a captive portal intercepts the request and
+// answers on behalf of the mirror
+func checkNotWebPage(url, contentType string, head []byte) error {
+	mediaType := ""
+	if contentType != "" {
+		if parsed, _, err := mime.ParseMediaType(contentType); err == nil {
+			mediaType = parsed
+		}
+	}
+
+	trimmed := bytes.TrimSpace(head)
+	sniffed := strings.SplitN(http.DetectContentType(trimmed), ";", 2)[0]
+	if !webPageTypes[mediaType] && !webPageTypes[sniffed] &&
+		!bytes.HasPrefix(trimmed, []byte("{")) && !bytes.HasPrefix(trimmed, []byte("<")) {
+		return nil
+	}
+
+	if len(trimmed) > shownLength {
+		trimmed = trimmed[:shownLength]
+	}
+	return fmt.Errorf(
+		"The response of %s looks like a page served by a proxy or a captive portal, not like kubectl. Check the network connection, the response starts with: %q",
+		url, trimmed)
+}
+
+// executableMagics are the headers of the executables of each platform
+var executableMagics = map[string][][]byte{
+	"linux": {[]byte("\x7fELF")},
+	"darwin": {
+		[]byte("\xcf\xfa\xed\xfe"), // 64 bit, little endian
+		[]byte("\xce\xfa\xed\xfe"), // 32 bit, little endian
+		[]byte("\xfe\xed\xfa\xcf"), // 64 bit, big endian
+		[]byte("\xfe\xed\xfa\xce"), // 32 bit, big endian
+		[]byte("\xca\xfe\xba\xbe"), // universal binary
+	},
+	"windows": {[]byte("MZ")},
+}
+
+// executableFormat returns the name of the platform the given header
+// belongs to, an empty string is returned when it's unknown
+func executableFormat(head []byte) string {
+	for goos, magics := range executableMagics {
+		for _, magic := range magics {
+			if bytes.HasPrefix(head, magic) {
+				return goos
+			}
+		}
+	}
+	return ""
+}
+
+// checkExecutable returns an error when the given header doesn't belong
+// to an executable of the given platform. Misconfigured mirrors can serve
+// the artifacts of another platform, their checksum is still valid
+func checkExecutable(url, goos string, head []byte) error {
+	if _, known := executableMagics[goos]; !known {
+		return nil
+	}
+
+	switch format := executableFormat(head); format {
+	case goos:
+		return nil
+	case "":
+		if len(head) > shownLength {
+			head = head[:shownLength]
+		}
+		return fmt.Errorf("%s is not a %s executable, it starts with: %q", url, goos, head)
+	default:
+		return fmt.Errorf("%s is a %s executable instead of a %s one, the mirror is serving the artifacts of another platform", url, format, goos)
+	}
+}
+
+// checkExecutableFile is like checkExecutable, but reads the header
+// from the given file
+func checkExecutableFile(url, goos, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	return checkExecutable(url, goos, head[:n])
+}
@@ -0,0 +1,85 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckNotWebPage(t *testing.T) {
+	tests := []struct {
+		contentType string
+		head        string
+		webPage     bool
+	}{
+		{"application/octet-stream", "\x7fELF\x02\x01\x01", false},
+		{"", "\x7fELF\x02\x01\x01", false},
+		{"", "3f4b52a8072013e4cd34c9ea07e3c4c4e0350b34bb3e2d8f19d8ddf12c5b2a4a", false},
+		{"text/html; charset=utf-8", "\x7fELF\x02\x01\x01", true},
+		{"application/octet-stream", "\n  <!DOCTYPE html><html><body>Sign in</body></html>", true},
+		{"", `{"error": "blocked by policy"}`, true},
+		{"application/json", "", true},
+	}
+	for _, test := range tests {
+		err := checkNotWebPage("https://mirror/kubectl", test.contentType, []byte(test.head))
+		if (err != nil) != test.webPage {
+			t.Errorf("%q %q: unexpected result %v", test.contentType, test.head, err)
+		}
+	}
+}
+
+func TestDownloadCaptivePortal(t *testing.T) {
+	page := []byte("<html><head><title>Guest WiFi</title></head><body>Accept the terms</body></html>")
+	hash := sha256.Sum256(page)
+	server := newFakeMirror(page, hex.EncodeToString(hash[:]))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{}
+	destination := filepath.Join(dir, "kubectl")
+	_, err = d.download("kubectl", server.URL+"/kubectl", destination, 0755)
+	if err == nil || !strings.Contains(err.Error(), "captive portal") || !strings.Contains(err.Error(), "Guest WiFi") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Error("The page should not be installed")
+	}
+}
+
+func TestCheckExecutable(t *testing.T) {
+	tests := []struct {
+		goos  string
+		head  string
+		valid bool
+	}{
+		{"linux", "\x7fELF\x02\x01\x01", true},
+		{"linux", "MZ\x90\x00", false},
+		{"linux", "fake kubectl binary", false},
+		{"darwin", "\xcf\xfa\xed\xfe\x07\x00", true},
+		{"darwin", "\x7fELF\x02\x01\x01", false},
+		{"windows", "MZ\x90\x00", true},
+		{"windows", "\xcf\xfa\xed\xfe\x07\x00", false},
+		// platforms without a known format are not checked
+		{"plan9", "anything", true},
+	}
+	for _, test := range tests {
+		err := checkExecutable("https://mirror/kubectl", test.goos, []byte(test.head))
+		if (err == nil) != test.valid {
+			t.Errorf("%s %q: unexpected result %v", test.goos, test.head, err)
+		}
+	}
+
+	err := checkExecutable("https://mirror/kubectl", "linux", []byte("MZ\x90\x00"))
+	if err == nil || !strings.Contains(err.Error(), "windows executable") {
+		t.Errorf("The error should name the platform of the artifact: %v", err)
+	}
+}
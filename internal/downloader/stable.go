@@ -0,0 +1,156 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// stableCache is the latest stable version of kubernetes as stored inside
+// of the cache, together with the validators returned by the mirror
+type stableCache struct {
+	URL          string    `json:"url"`
+	Version      string    `json:"version"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+func loadStableCache(path string) stableCache {
+	cache := stableCache{}
+	if path == "" {
+		return cache
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.V(1).Infof("Cannot read %s: %v", path, err)
+		}
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		klog.V(1).Infof("Ignoring malformed stable version cache %s: %v", path, err)
+		return stableCache{}
+	}
+	return cache
+}
+
+func (c stableCache) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// concurrent runs of kuberlr must never read a partially written cache
+	return common.WriteFileAtomic(path, data, ".kuberlr-stable-")
+}
+
+// UpstreamStableVersion returns the latest version of kubernetes that upstream
+// considers stable
+func (d *Downloder) UpstreamStableVersion() (semver.Version, error) {
+	if common.IsOffline() {
+		// only the version fetched while online can be used
+		cache := loadStableCache(d.StableCache)
+		if cache.Version == "" || cache.URL != d.releasesURL()+"/stable.txt" {
+			return semver.Version{}, fmt.Errorf("The stable version of kubectl has never been fetched: %w", common.ErrOffline)
+		}
+		return semver.ParseTolerant(cache.Version)
+	}
+	var v semver.Version
+	err := d.fromMirrors("the stable version", func(m *Downloder) error {
+		var err error
+		v, err = m.upstreamStableVersion(time.Now())
+		return err
+	})
+	return v, err
+}
+
+// upstreamStableVersion returns the cached stable version while it's fresh,
+// then asks the mirror whether it changed. The stale copy is used when the
+// mirror cannot be reached
+func (d *Downloder) upstreamStableVersion(now time.Time) (semver.Version, error) {
+	stableURL := d.releasesURL() + "/stable.txt"
+	cache := loadStableCache(d.StableCache)
+	if cache.URL != stableURL {
+		cache = stableCache{URL: stableURL}
+	}
+
+	if cache.Version != "" && now.Sub(cache.CheckedAt) < d.StableCacheTTL {
+		klog.V(4).Infof("Using the cached stable version %s", cache.Version)
+		return semver.ParseTolerant(cache.Version)
+	}
+
+	fresh, err := d.fetchStableVersion(cache)
+	if err != nil {
+		if cache.Version == "" {
+			return semver.Version{}, err
+		}
+		klog.V(1).Infof("Using the stale stable version %s: %v", cache.Version, err)
+		return semver.ParseTolerant(cache.Version)
+	}
+	v, err := semver.ParseTolerant(fresh.Version)
+	if err != nil {
+		return v, err
+	}
+
+	fresh.CheckedAt = now
+	if err := fresh.save(d.StableCache); err != nil {
+		klog.V(1).Infof("Cannot save stable version cache: %v", err)
+	}
+	return v, nil
+}
+
+// fetchStableVersion retrieves the stable version from the mirror, the
+// cached copy is returned when it didn't change
+func (d *Downloder) fetchStableVersion(cached stableCache) (stableCache, error) {
+	req, err := http.NewRequest("GET", cached.URL, nil)
+	if err != nil {
+		return stableCache{}, err
+	}
+	if cached.Version != "" {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	res, err := d.client().Do(req)
+	if err != nil {
+		return stableCache{}, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		klog.V(4).Infof("%s did not change, using cached copy", cached.URL)
+		return cached, nil
+	default:
+		return stableCache{}, &statusError{URL: cached.URL, Status: res.Status, Code: res.StatusCode}
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return stableCache{}, fmt.Errorf("Error while reading %s: %v", cached.URL, err)
+	}
+	return stableCache{
+		URL:          cached.URL,
+		Version:      strings.TrimSpace(string(data)),
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}, nil
+}
@@ -0,0 +1,73 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpstreamStableVersionCache(t *testing.T) {
+	stable := "v1.20.1"
+	requests := 0
+	conditional := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		etag := `"` + stable + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(stable + "\n"))
+	}))
+
+	dir, err := ioutil.TempDir("", "kuberlr-stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := Downloder{
+		BaseURL:        server.URL,
+		StableCache:    filepath.Join(dir, "stable-version.json"),
+		StableCacheTTL: time.Hour,
+	}
+	now := time.Now()
+
+	check := func(expected string, at time.Time, expectedRequests int) {
+		t.Helper()
+		v, err := d.upstreamStableVersion(at)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if "v"+v.String() != expected {
+			t.Errorf("Expected %s, got %s", expected, v)
+		}
+		if requests != expectedRequests {
+			t.Errorf("Expected %d requests, got %d", expectedRequests, requests)
+		}
+	}
+
+	check("v1.20.1", now, 1)
+	// the cached version is fresh
+	check("v1.20.1", now.Add(30*time.Minute), 1)
+	// the cached version is stale but didn't change
+	check("v1.20.1", now.Add(2*time.Hour), 2)
+	if conditional != 1 {
+		t.Errorf("Expected a conditional request, got %d", conditional)
+	}
+	// the check has been renewed
+	check("v1.20.1", now.Add(150*time.Minute), 2)
+
+	stable = "v1.21.0"
+	check("v1.21.0", now.Add(4*time.Hour), 3)
+
+	// the stale version is used while the mirror is unreachable
+	server.Close()
+	check("v1.21.0", now.Add(8*time.Hour), 3)
+}
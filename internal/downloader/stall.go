@@ -0,0 +1,56 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// stallError is returned when the mirror stopped sending the body of the
+// response for too long
+type stallError struct {
+	URL     string
+	Timeout time.Duration
+}
+
+func (e *stallError) Error() string {
+	return fmt.Sprintf("The transfer of %s stalled: no data received for %s", e.URL, e.Timeout)
+}
+
+// stallReader aborts the transfer of the response body when no data is
+// received for the given amount of time
+type stallReader struct {
+	r       io.Reader
+	url     string
+	timeout time.Duration
+	timer   *time.Timer
+	stalled int32
+}
+
+// watchStall returns a reader of the response body of urlToGet that
+// invokes abort, which must interrupt the pending reads, when no data is
+// received for timeout. r is returned as it is when timeout is not
+// positive. The returned function stops watching
+func watchStall(r io.Reader, urlToGet string, timeout time.Duration, abort func()) (io.Reader, func()) {
+	if timeout <= 0 {
+		return r, func() {}
+	}
+	s := &stallReader{r: r, url: urlToGet, timeout: timeout}
+	s.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&s.stalled, 1)
+		abort()
+	})
+	return s, func() { s.timer.Stop() }
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if atomic.LoadInt32(&s.stalled) == 1 {
+		return n, &stallError{URL: s.url, Timeout: s.timeout}
+	}
+	if n > 0 {
+		s.timer.Reset(s.timeout)
+	}
+	return n, err
+}
@@ -0,0 +1,46 @@
+package downloader
+
+import (
+	"io"
+	"time"
+)
+
+// throttledReader limits the rate at which the wrapped reader is consumed
+type throttledReader struct {
+	r io.Reader
+	// rate is the maximum amount of bytes read per second
+	rate  int64
+	start time.Time
+	read  int64
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// throttle returns a reader consuming r at no more than rate bytes per
+// second, r is returned as it is when rate is not positive
+func throttle(r io.Reader, rate int64) io.Reader {
+	if rate <= 0 {
+		return r
+	}
+	return &throttledReader{r: r, rate: rate, now: time.Now, sleep: time.Sleep}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if t.start.IsZero() {
+		t.start = t.now()
+	}
+	// small reads keep the transfer smooth, instead of having bursts
+	// followed by long pauses
+	if chunk := t.rate/10 + 1; int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := t.r.Read(p)
+	t.read += int64(n)
+
+	expected := time.Duration(float64(t.read) / float64(t.rate) * float64(time.Second))
+	if elapsed := t.now().Sub(t.start); expected > elapsed {
+		t.sleep(expected - elapsed)
+	}
+	return n, err
+}
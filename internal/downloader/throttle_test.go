@@ -0,0 +1,38 @@
+package downloader
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	contents := bytes.Repeat([]byte("x"), 4096)
+	if r := throttle(bytes.NewReader(contents), 0); r == nil {
+		t.Fatal("Expected the reader to be returned")
+	}
+
+	clock := time.Unix(0, 0)
+	slept := time.Duration(0)
+	r := &throttledReader{
+		r:    bytes.NewReader(contents),
+		rate: 1024,
+		now:  func() time.Time { return clock },
+		sleep: func(d time.Duration) {
+			slept += d
+			clock = clock.Add(d)
+		},
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, contents) {
+		t.Fatal("The contents have been altered")
+	}
+	if slept != 4*time.Second {
+		t.Errorf("Reading 4 KiB at 1 KiB/s took %s", slept)
+	}
+}
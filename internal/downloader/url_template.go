@@ -0,0 +1,60 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"text/template"
+
+	"github.com/blang/semver/v4"
+)
+
+// urlTemplateData holds the fields available to the URL templates
+type urlTemplateData struct {
+	// Version is the version of kubectl without the "v" prefix, the
+	// prerelease is included: "1.31.0-rc.1"
+	Version string
+	Major   uint64
+	Minor   uint64
+	Patch   uint64
+	Os      string
+	Arch    string
+	// Ext is the extension of the executables, ".exe" on Windows
+	Ext string
+}
+
+// CheckURLTemplate returns an error when the given URL template cannot be
+// rendered
+func CheckURLTemplate(text string) error {
+	_, err := renderURLTemplate(text, semver.MustParse("1.20.1"), "linux", "amd64")
+	return err
+}
+
+// renderURLTemplate returns the URL of the kubectl binary of the given
+// version and platform, according to the template
+func renderURLTemplate(text string, v semver.Version, goos, arch string) (string, error) {
+	tmpl, err := template.New("url").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("Invalid URL template %q: %v", text, err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, urlTemplateData{
+		Version: releaseVersion(v),
+		Major:   v.Major,
+		Minor:   v.Minor,
+		Patch:   v.Patch,
+		Os:      goos,
+		Arch:    arch,
+		Ext:     executableExt(goos),
+	})
+	if err != nil {
+		return "", fmt.Errorf("Invalid URL template %q: %v", text, err)
+	}
+
+	u, err := url.Parse(buf.String())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("The URL template %q doesn't produce an http or https URL: %s", text, buf.String())
+	}
+	return u.String(), nil
+}
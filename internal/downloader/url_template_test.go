@@ -0,0 +1,64 @@
+package downloader
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestRenderURLTemplate(t *testing.T) {
+	v := semver.MustParse("1.20.1")
+	tests := []struct {
+		template string
+		goos     string
+		expected string
+		valid    bool
+	}{
+		{
+			"https://mirror.corp/kubectl/{{.Version}}/{{.Os}}/{{.Arch}}/kubectl{{.Ext}}",
+			"windows",
+			"https://mirror.corp/kubectl/1.20.1/windows/amd64/kubectl.exe",
+			true,
+		},
+		{
+			"https://mirror.corp/tools/kubectl-v{{.Major}}.{{.Minor}}.{{.Patch}}-{{.Os}}-{{.Arch}}",
+			"linux",
+			"https://mirror.corp/tools/kubectl-v1.20.1-linux-amd64",
+			true,
+		},
+		{"https://mirror.corp/{{.Platform}}/kubectl", "linux", "", false},
+		{"https://mirror.corp/{{.Version", "linux", "", false},
+		{"/srv/kubectl/{{.Version}}", "linux", "", false},
+	}
+	for _, test := range tests {
+		actual, err := renderURLTemplate(test.template, v, test.goos, "amd64")
+		if (err == nil) != test.valid {
+			t.Errorf("%s: unexpected error %v", test.template, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("%s: got %s instead of %s", test.template, actual, test.expected)
+		}
+	}
+}
+
+func TestPrereleaseURL(t *testing.T) {
+	v := semver.MustParse("1.31.0-rc.1")
+
+	d := Downloder{}
+	actual, err := d.kubectlDownloadURL(v, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := KubectlReleasesURL + "/v1.31.0-rc.1/bin/linux/amd64/kubectl"; actual != expected {
+		t.Errorf("Got %s instead of %s", actual, expected)
+	}
+
+	actual, err = renderURLTemplate("https://mirror.corp/kubectl/{{.Version}}/kubectl", v, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "https://mirror.corp/kubectl/1.31.0-rc.1/kubectl"; actual != expected {
+		t.Errorf("Got %s instead of %s", actual, expected)
+	}
+}
@@ -0,0 +1,74 @@
+package eol
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// release describes when a minor release of kubernetes stops
+// receiving patches
+type release struct {
+	minor uint64
+	eol   string
+}
+
+// calendar holds the end of life dates of the kubernetes 1.x minor releases,
+// as published on https://kubernetes.io/releases/. It must be sorted by minor
+var calendar = []release{
+	{minor: 18, eol: "2021-06-18"},
+	{minor: 19, eol: "2021-10-28"},
+	{minor: 20, eol: "2022-02-28"},
+	{minor: 21, eol: "2022-06-28"},
+	{minor: 22, eol: "2022-10-28"},
+	{minor: 23, eol: "2023-02-28"},
+	{minor: 24, eol: "2023-07-28"},
+	{minor: 25, eol: "2023-10-28"},
+	{minor: 26, eol: "2024-02-28"},
+	{minor: 27, eol: "2024-06-28"},
+	{minor: 28, eol: "2024-10-28"},
+	{minor: 29, eol: "2025-02-28"},
+	{minor: 30, eol: "2025-06-28"},
+	{minor: 31, eol: "2025-10-28"},
+	{minor: 32, eol: "2026-02-28"},
+	{minor: 33, eol: "2026-06-28"},
+	{minor: 34, eol: "2026-10-27"},
+	{minor: 35, eol: "2027-02-28"},
+}
+
+// EndOfLife returns the date when the minor release of the given version
+// stops being supported. The boolean is false when the date is not known,
+// either because the release is too recent or because it predates the
+// calendar, in which case it's long past its end of life
+func EndOfLife(v semver.Version) (time.Time, bool) {
+	if v.Major != 1 {
+		return time.Time{}, false
+	}
+	for _, r := range calendar {
+		if r.minor == v.Minor {
+			eol, err := time.Parse("2006-01-02", r.eol)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return eol, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Check returns an error when the minor release of the given version is
+// past its end of life at the given time
+func Check(v semver.Version, now time.Time) error {
+	if v.Major == 1 && len(calendar) > 0 && v.Minor < calendar[0].minor {
+		return fmt.Errorf("kubernetes %d.%d is past its end of life", v.Major, v.Minor)
+	}
+
+	eol, found := EndOfLife(v)
+	if !found || now.Before(eol) {
+		return nil
+	}
+	return fmt.Errorf(
+		"kubernetes %d.%d reached its end of life on %s",
+		v.Major, v.Minor, eol.Format("2006-01-02"))
+}
@@ -0,0 +1,44 @@
+package eol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestCalendarIsSorted(t *testing.T) {
+	for i := 1; i < len(calendar); i++ {
+		if calendar[i].minor <= calendar[i-1].minor || calendar[i].eol <= calendar[i-1].eol {
+			t.Errorf("Calendar is not sorted at %+v", calendar[i])
+		}
+		if _, err := time.Parse("2006-01-02", calendar[i].eol); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		version string
+		eol     bool
+	}{
+		{"1.14.3", true},
+		{"1.20.15", true},
+		{"1.21.2", false},
+		{"1.99.0", false},
+		{"2.0.0", false},
+	}
+
+	for _, test := range tests {
+		err := Check(semver.MustParse(test.version), now)
+		if test.eol && err == nil {
+			t.Errorf("%s: expected to be past its end of life", test.version)
+		}
+		if !test.eol && err != nil {
+			t.Errorf("%s: unexpected error %v", test.version, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package finder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+const (
+	// FallbackDefault uses the version chosen via `kuberlr default`
+	FallbackDefault = "default"
+	// FallbackNewestLocal uses the most recent kubectl binary downloaded
+	// by kuberlr
+	FallbackNewestLocal = "newest-local"
+	// FallbackSystem uses the most recent kubectl binary installed
+	// system-wide
+	FallbackSystem = "system"
+	// FallbackStableRemote uses the latest stable release of kubectl
+	FallbackStableRemote = "stable-remote"
+)
+
+// DefaultFallback is the order in which the fallbacks are attempted when
+// the version of the kubernetes API server cannot be found
+var DefaultFallback = []string{FallbackDefault, FallbackNewestLocal, FallbackSystem, FallbackStableRemote}
+
+// ParseFallback validates the given list of fallbacks
+func ParseFallback(names []string) ([]string, error) {
+	fallback := []string{}
+	for _, name := range names {
+		switch n := strings.ToLower(name); n {
+		case FallbackDefault, FallbackNewestLocal, FallbackSystem, FallbackStableRemote:
+			fallback = append(fallback, n)
+		default:
+			return nil, fmt.Errorf("Unknown fallback: %s", name)
+		}
+	}
+	return fallback, nil
+}
+
+// FallbackFor returns the fallbacks attempted when the given one, either
+// FallbackNewestLocal or FallbackStableRemote, is preferred. DefaultFallback
+// is returned when there's no preference. Preferring FallbackNewestLocal
+// means never looking for binaries outside of the ones downloaded by kuberlr
+func FallbackFor(preferred string) ([]string, error) {
+	switch strings.ToLower(preferred) {
+	case "":
+		return DefaultFallback, nil
+	case FallbackNewestLocal:
+		return []string{FallbackDefault, FallbackNewestLocal, FallbackStableRemote}, nil
+	case FallbackStableRemote:
+		return []string{FallbackDefault, FallbackStableRemote, FallbackNewestLocal}, nil
+	default:
+		return nil, fmt.Errorf("Unknown fallback preference: %s", preferred)
+	}
+}
+
+// SetFallback sets the order in which the fallbacks are attempted when the
+// version of the kubernetes API server cannot be found
+func (v *Versioner) SetFallback(fallback []string) {
+	v.fallback = fallback
+}
+
+// SetSilentFallback makes the Versioner fall back to the newest binary
+// downloaded by kuberlr without warning, the user asked for that
+func (v *Versioner) SetSilentFallback(silent bool) {
+	v.silentFallback = silent
+}
+
+// fallbackVersion returns the kubectl version to use when the version of
+// the kubernetes API server cannot be found, the fallbacks are attempted in
+// order. Warnings of the given class are shown, unless the class is empty
+func (v *Versioner) fallbackVersion(class string) (semver.Version, error) {
+	fallback := v.fallback
+	if fallback == nil {
+		fallback = DefaultFallback
+	}
+
+	var lastErr error
+	for _, step := range fallback {
+		switch step {
+		case FallbackDefault:
+			if v.defaultVersion != nil {
+				klog.V(2).Infof("Using default kubectl version %s", v.defaultVersion)
+				return *v.defaultVersion, nil
+			}
+		case FallbackNewestLocal:
+			kubectl, found := v.newestDownloadedKubectl()
+			if found {
+				if !v.silentFallback {
+					v.warnFallback(class, "kubectl "+kubectl.Version.String())
+				}
+				return kubectl.Version, nil
+			}
+			klog.V(2).Info("No kubectl binary downloaded by kuberlr found")
+		case FallbackSystem:
+			kubectl, found := v.newestSystemKubectl()
+			if found {
+				v.warnFallback(class, "system kubectl "+kubectl.Version.String())
+				return kubectl.Version, nil
+			}
+			klog.V(2).Info("No system-wide kubectl binary found")
+		case FallbackStableRemote:
+			klog.V(2).Info("Fetching latest stable release version")
+			version, err := v.downloader.UpstreamStableVersion()
+			if err == nil {
+				v.warnFallback(class, "latest stable kubectl")
+				return version, nil
+			}
+			lastErr = err
+		}
+	}
+
+	if lastErr != nil {
+		return semver.Version{}, lastErr
+	}
+	return semver.Version{}, &common.NoVersionFoundError{}
+}
+
+// newestDownloadedKubectl returns the most recent kubectl binary downloaded
+// by kuberlr, either for the user or inside of the shared store, allowed by
+// the policy
+func (v *Versioner) newestDownloadedKubectl() (KubectlBinary, bool) {
+	bins, err := v.kFinder.LocalKubectlBinaries()
+	if err != nil {
+		klog.V(1).Infof("Cannot list the local kubectl binaries: %v", err)
+	}
+	shared, err := v.kFinder.SharedKubectlBinaries()
+	if err != nil {
+		klog.V(1).Infof("Cannot list the shared kubectl binaries: %v", err)
+	}
+	return v.newestAllowed(append(bins, shared...))
+}
+
+// newestSystemKubectl returns the most recent system-wide kubectl binary
+// allowed by the policy
+func (v *Versioner) newestSystemKubectl() (KubectlBinary, bool) {
+	bins, err := v.kFinder.SystemKubectlBinaries()
+	if err != nil {
+		return KubectlBinary{}, false
+	}
+	return v.newestAllowed(bins)
+}
+
+// newestAllowed returns the most recent of the given binaries allowed by
+// the policy
+func (v *Versioner) newestAllowed(bins KubectlBinaries) (KubectlBinary, bool) {
+	SortKubectlByVersion(bins, true)
+	for _, b := range bins {
+		if v.policy.Allows(b.Version) == nil {
+			return b, true
+		}
+	}
+	return KubectlBinary{}, false
+}
+
+func (v *Versioner) warnFallback(class, what string) {
+	if class != "" {
+		v.warn(class, "cannot find the version of the kubernetes server, falling back to %s", what)
+	}
+}
@@ -10,6 +10,8 @@ import (
 type KubectlBinary struct {
 	Path    string
 	Version semver.Version
+	// System is true for the binaries installed system-wide
+	System bool
 }
 
 // KubectlBinaries is a list of KubectlBinary objects
@@ -26,3 +28,17 @@ func SortKubectlByVersion(binaries KubectlBinaries, reverse bool) {
 		return binaries[i].Version.LT(binaries[j].Version)
 	})
 }
+
+// WithinSkewPolicy returns true when the given kubectl version can talk with
+// the given kubernetes API server according to the upstream version skew
+// policy: kubectl is supported within one minor version (older or newer)
+// of kube-apiserver
+func WithinSkewPolicy(kubectl, server semver.Version) bool {
+	if kubectl.Major != server.Major {
+		return false
+	}
+	if kubectl.Minor > server.Minor {
+		return kubectl.Minor-server.Minor <= 1
+	}
+	return server.Minor-kubectl.Minor <= 1
+}
@@ -7,8 +7,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/policy"
 
 	"github.com/blang/semver/v4"
 )
@@ -17,6 +19,20 @@ import (
 type KubectlFinder struct {
 	LocalBinaryPath string
 	SysBinaryPath   string
+	// ExtraSysBinaryPaths are other directories holding system-wide
+	// binaries, they're scanned after SysBinaryPath
+	ExtraSysBinaryPaths []string
+	// SharedBinaryPath is the directory holding the binaries downloaded by
+	// kuberlr on behalf of all the users, it's optional
+	SharedBinaryPath string
+	// PreferSystem makes system-wide binaries with the same minor version
+	// of the requested one win over the ones downloaded by kuberlr
+	PreferSystem bool
+	// Policy restricts the binaries that can be used, it's optional
+	Policy *policy.Policy
+	// Match defines which binaries are compatible with the requested
+	// version, MatchSkew is used when empty
+	Match VersionMatch
 }
 
 // NewKubectlFinder returns a properly initialized KubectlFinder object
@@ -37,7 +53,26 @@ func NewKubectlFinder(local, sys string) *KubectlFinder {
 // SystemKubectlBinaries returns the list of kubectl binaries that are
 // available to all the users of the system
 func (f *KubectlFinder) SystemKubectlBinaries() (KubectlBinaries, error) {
-	return findKubectlBinaries(f.SysBinaryPath)
+	bins, err := findKubectlBinaries(f.SysBinaryPath)
+	if err != nil {
+		return bins, err
+	}
+	for _, path := range f.ExtraSysBinaryPaths {
+		extra, err := findKubectlBinaries(path)
+		if err != nil {
+			return bins, err
+		}
+		bins = append(bins, extra...)
+	}
+	for i := range bins {
+		bins[i].System = true
+	}
+	return bins, nil
+}
+
+// SystemPaths returns the directories holding the system-wide binaries
+func (f *KubectlFinder) SystemPaths() []string {
+	return append([]string{f.SysBinaryPath}, f.ExtraSysBinaryPaths...)
 }
 
 // LocalKubectlBinaries returns the list of kubectl binaries that are
@@ -46,6 +81,15 @@ func (f *KubectlFinder) LocalKubectlBinaries() (KubectlBinaries, error) {
 	return findKubectlBinaries(f.LocalBinaryPath)
 }
 
+// SharedKubectlBinaries returns the list of kubectl binaries that have been
+// downloaded by kuberlr inside of the shared store
+func (f *KubectlFinder) SharedKubectlBinaries() (KubectlBinaries, error) {
+	if f.SharedBinaryPath == "" {
+		return KubectlBinaries{}, nil
+	}
+	return findKubectlBinaries(f.SharedBinaryPath)
+}
+
 // AllKubectlBinaries returns all the kubectl binaries available to the
 // user running kuberlr
 func (f *KubectlFinder) AllKubectlBinaries(reverseSort bool) KubectlBinaries {
@@ -56,6 +100,11 @@ func (f *KubectlFinder) AllKubectlBinaries(reverseSort bool) KubectlBinaries {
 		bins = append(bins, localBin...)
 	}
 
+	sharedBin, err := f.SharedKubectlBinaries()
+	if err == nil {
+		bins = append(bins, sharedBin...)
+	}
+
 	systemBin, err := f.SystemKubectlBinaries()
 	if err == nil {
 		bins = append(bins, systemBin...)
@@ -69,52 +118,87 @@ func (f *KubectlFinder) AllKubectlBinaries(reverseSort bool) KubectlBinaries {
 // FindCompatibleKubectl returns a kubectl binary compatible with the
 // version given via the `requestedVersion` parameter
 func (f *KubectlFinder) FindCompatibleKubectl(requestedVersion semver.Version) (KubectlBinary, error) {
+	match := f.Match
+	if match == MatchSkew || match == "" {
+		if f.Policy.StrictSkew() {
+			match = MatchMinor
+		}
+	}
+	return f.FindMatchingKubectl(requestedVersion, match)
+}
+
+// FindMatchingKubectl returns a kubectl binary accepted by the given
+// VersionMatch, like FindCompatibleKubectl does with the configured one
+func (f *KubectlFinder) FindMatchingKubectl(requestedVersion semver.Version, match VersionMatch) (KubectlBinary, error) {
+	if f.PreferSystem && match != MatchExact {
+		if b, found := f.findSameMinorSystemKubectl(requestedVersion); found {
+			return b, nil
+		}
+	}
+
 	bins := f.AllKubectlBinaries(true)
 	if len(bins) == 0 {
 		return KubectlBinary{}, &common.NoVersionFoundError{}
 	}
 
-	lowerBound := lowerBoundVersion(requestedVersion)
-	upperBound := upperBoundVersion(requestedVersion)
-	rangeRule := fmt.Sprintf(">=%s <%s", lowerBound.String(), upperBound.String())
+	for _, b := range bins {
+		if match.Accepts(b.Version, requestedVersion) && f.Policy.Allows(b.Version) == nil {
+			return b, nil
+		}
+	}
+
+	return KubectlBinary{}, &common.NoVersionFoundError{}
+}
 
-	validRange, err := semver.ParseRange(rangeRule)
+// findSameMinorSystemKubectl looks for a system-wide kubectl binary that
+// has the same major and minor version of the requested one
+func (f *KubectlFinder) findSameMinorSystemKubectl(requestedVersion semver.Version) (KubectlBinary, bool) {
+	bins, err := f.SystemKubectlBinaries()
 	if err != nil {
-		return KubectlBinary{}, err
+		return KubectlBinary{}, false
 	}
+	SortKubectlByVersion(bins, true)
 
 	for _, b := range bins {
-		if validRange(b.Version) {
-			return b, nil
+		if b.Version.Major == requestedVersion.Major && b.Version.Minor == requestedVersion.Minor && f.Policy.Allows(b.Version) == nil {
+			return b, true
 		}
 	}
 
-	return KubectlBinary{}, &common.NoVersionFoundError{}
+	return KubectlBinary{}, false
 }
 
 // MostRecentKubectlAvailable returns the most recent version of
 // kubectl available on the system. It could be something downloaded
 // by kuberlr or something already available on the system
 func (f *KubectlFinder) MostRecentKubectlAvailable() (KubectlBinary, error) {
-	bins := f.AllKubectlBinaries(true)
-
-	if len(bins) == 0 {
-		return KubectlBinary{}, &common.NoVersionFoundError{}
+	for _, b := range f.AllKubectlBinaries(true) {
+		if f.Policy.Allows(b.Version) == nil {
+			return b, nil
+		}
 	}
 
-	return bins[0], nil
+	return KubectlBinary{}, &common.NoVersionFoundError{}
 }
 
 func inferLocalKubectlVersion(filename string) (semver.Version, error) {
+	if sv, err := common.ParseKubectlNameForLocalBin(filename); err == nil {
+		return sv, nil
+	}
+
+	// binaries named using the legacy naming scheme are always recognized
 	var major, minor, patch uint64
+	name := osexec.TrimExt(filename)
 	n, err := fmt.Sscanf(
-		osexec.TrimExt(filename),
+		name,
 		common.KubectlLocalNamingScheme,
 		&major,
 		&minor,
 		&patch)
 
-	if n == 3 && err == nil {
+	// Sscanf ignores what follows the patch number, like the suffix of
+	// "kubectl1.31.0-rc.1", which doesn't belong to the legacy scheme
+	if n == 3 && err == nil && name == fmt.Sprintf(common.KubectlLocalNamingScheme, major, minor, patch) {
 		sv := semver.Version{
 			Major: major,
 			Minor: minor,
@@ -125,6 +209,18 @@ func inferLocalKubectlVersion(filename string) (semver.Version, error) {
 	return semver.Version{}, errors.New("Not parsable")
 }
 
+// alternativeNaming matches the names commonly given to the kubectl
+// binaries collected by hand, like "kubectl-v1.28.3" or "kubectl_1.28.3"
+var alternativeNaming = regexp.MustCompile(`^kubectl[-_]?v?([0-9]+\.[0-9]+\.[0-9]+(?:-(?:alpha|beta|rc)\.[0-9]+)?)$`)
+
+func inferAlternativeKubectlVersion(filename string) (semver.Version, error) {
+	match := alternativeNaming.FindStringSubmatch(osexec.TrimExt(filename))
+	if match == nil {
+		return semver.Version{}, errors.New("Not parsable")
+	}
+	return semver.Parse(match[1])
+}
+
 func inferSystemKubectlVersion(filename string) (semver.Version, error) {
 	var major, minor uint64
 	n, err := fmt.Sscanf(
@@ -156,10 +252,17 @@ func findKubectlBinaries(path string) (KubectlBinaries, error) {
 	}
 
 	for _, f := range kubectlBins {
+		if f.IsDir() {
+			continue
+		}
+
 		var sv semver.Version
 		var err error
 
 		sv, err = inferLocalKubectlVersion(f.Name())
+		if err != nil {
+			sv, err = inferAlternativeKubectlVersion(f.Name())
+		}
 		if err != nil {
 			sv, err = inferSystemKubectlVersion(f.Name())
 			if err != nil {
@@ -176,22 +279,3 @@ func findKubectlBinaries(path string) (KubectlBinaries, error) {
 
 	return binaries, nil
 }
-
-func lowerBoundVersion(v semver.Version) semver.Version {
-	res := v
-
-	res.Patch = 0
-	if v.Minor > 0 {
-		res.Minor = v.Minor - 1
-	}
-
-	return res
-}
-
-func upperBoundVersion(v semver.Version) semver.Version {
-	return semver.Version{
-		Major: v.Major,
-		Minor: v.Minor + 2,
-		Patch: 0,
-	}
-}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/blang/semver/v4"
 	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/osexec"
+	"github.com/flavio/kuberlr/internal/policy"
 )
 
 type localCacheTestData struct {
@@ -247,3 +250,296 @@ func TestFindCompatibleKubectlNoMatchFound(t *testing.T) {
 		t.Errorf("Expected error not found")
 	}
 }
+
+func TestFindCompatibleKubectlPreferSystem(t *testing.T) {
+	td, err := setupFilesystemTest()
+	if err != nil {
+		t.Errorf("Unexpeted failure: %v", err)
+	}
+	defer func() {
+		if err := teardownFilesystemTest(td); err != nil {
+			fmt.Printf("Error while tearing down test filesystem: %v\n", err)
+		}
+	}()
+	td.Finder.PreferSystem = true
+
+	localBins := fakeKubectlBinaries(
+		td.FakeHome,
+		[]string{"1.5.3", "1.6.1"},
+		&localKubectlNamer{})
+	if err := createFakeKubectlBinaries(localBins); err != nil {
+		t.Error(err)
+	}
+
+	systemBins := fakeKubectlBinaries(
+		td.FakeSysBinPath,
+		[]string{"1.4.2", "1.5.0"},
+		&systemKubectlNamer{})
+	if err := createFakeKubectlBinaries(systemBins); err != nil {
+		t.Error(err)
+	}
+
+	actual, err := td.Finder.FindCompatibleKubectl(semver.MustParse("1.5.7"))
+	if err != nil {
+		t.Errorf("Got unexpected error %v", err)
+	}
+	if actual.Path != systemBins[1].Path {
+		t.Errorf("Got %+v instead of %+v", actual.Path, systemBins[1].Path)
+	}
+
+	// no system binary with the same minor: the default logic kicks in
+	actual, err = td.Finder.FindCompatibleKubectl(semver.MustParse("1.6.0"))
+	if err != nil {
+		t.Errorf("Got unexpected error %v", err)
+	}
+	if actual.Path != localBins[1].Path {
+		t.Errorf("Got %+v instead of %+v", actual.Path, localBins[1].Path)
+	}
+}
+
+func TestAllKubectlBinariesWithSharedStore(t *testing.T) {
+	td, err := setupFilesystemTest()
+	if err != nil {
+		t.Errorf("Unexpeted failure: %v", err)
+	}
+	defer func() {
+		if err := teardownFilesystemTest(td); err != nil {
+			fmt.Printf("Error while tearing down test filesystem: %v\n", err)
+		}
+	}()
+	td.Finder.SharedBinaryPath = filepath.Join(td.FakeSysBinPath, "shared")
+
+	sharedBins := fakeKubectlBinaries(
+		td.Finder.SharedBinaryPath,
+		[]string{"1.7.2"},
+		&localKubectlNamer{})
+	if err := createFakeKubectlBinaries(sharedBins); err != nil {
+		t.Error(err)
+	}
+
+	localBins := fakeKubectlBinaries(
+		td.FakeHome,
+		[]string{"1.4.2"},
+		&localKubectlNamer{})
+	if err := createFakeKubectlBinaries(localBins); err != nil {
+		t.Error(err)
+	}
+
+	actual := td.Finder.AllKubectlBinaries(true)
+	if len(actual) != 2 {
+		t.Fatalf("Expected 2 binaries, got %+v", actual)
+	}
+	if actual[0].Path != sharedBins[0].Path {
+		t.Errorf("Got %+v instead of %+v", actual[0].Path, sharedBins[0].Path)
+	}
+}
+
+func TestFindCompatibleKubectlWithPolicy(t *testing.T) {
+	td, err := setupFilesystemTest()
+	if err != nil {
+		t.Errorf("Unexpeted failure: %v", err)
+	}
+	defer func() {
+		if err := teardownFilesystemTest(td); err != nil {
+			fmt.Printf("Error while tearing down test filesystem: %v\n", err)
+		}
+	}()
+
+	localBins := fakeKubectlBinaries(
+		td.FakeHome,
+		[]string{"1.19.3", "1.20.2", "1.21.1"},
+		&localKubectlNamer{})
+	if err := createFakeKubectlBinaries(localBins); err != nil {
+		t.Error(err)
+	}
+
+	p, err := policy.Parse([]byte(`{"allowedVersions": ["<1.21.0"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	td.Finder.Policy = p
+
+	b, err := td.Finder.FindCompatibleKubectl(semver.MustParse("1.20.0"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if b.Version.String() != "1.20.2" {
+		t.Errorf("Expected 1.20.2 to be used instead of the forbidden 1.21.1, got %s", b.Version)
+	}
+
+	p, err = policy.Parse([]byte(`{"skew": "strict"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	td.Finder.Policy = p
+
+	if _, err := td.Finder.FindCompatibleKubectl(semver.MustParse("1.22.0")); !common.IsNoVersionFound(err) {
+		t.Errorf("Expected no binary to be found with the strict skew rule, got %v", err)
+	}
+}
+
+func TestFindCompatibleKubectlWithVersionMatch(t *testing.T) {
+	td, err := setupFilesystemTest()
+	if err != nil {
+		t.Errorf("Unexpeted failure: %v", err)
+	}
+	defer func() {
+		if err := teardownFilesystemTest(td); err != nil {
+			fmt.Printf("Error while tearing down test filesystem: %v\n", err)
+		}
+	}()
+
+	localBins := fakeKubectlBinaries(
+		td.FakeHome,
+		[]string{"1.27.3", "1.28.2", "1.28.9"},
+		&localKubectlNamer{})
+	if err := createFakeKubectlBinaries(localBins); err != nil {
+		t.Error(err)
+	}
+
+	for _, tc := range []struct {
+		match    VersionMatch
+		server   string
+		expected string
+	}{
+		{MatchExact, "1.28.2", "1.28.2"},
+		{MatchExact, "1.28.7", ""},
+		{MatchPatch, "1.28.7", "1.28.9"},
+		{MatchPatch, "1.28.10", ""},
+		{MatchMinor, "1.28.10", "1.28.9"},
+		{MatchMinor, "1.29.0", ""},
+		{MatchSkew, "1.29.0", "1.28.9"},
+	} {
+		td.Finder.Match = tc.match
+		b, err := td.Finder.FindCompatibleKubectl(semver.MustParse(tc.server))
+		if tc.expected == "" {
+			if !common.IsNoVersionFound(err) {
+				t.Errorf("%s %s: expected no binary to be found, got %v %v", tc.match, tc.server, b.Version, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: unexpected error: %v", tc.match, tc.server, err)
+		} else if b.Version.String() != tc.expected {
+			t.Errorf("%s %s: got %s instead of %s", tc.match, tc.server, b.Version, tc.expected)
+		}
+	}
+}
+
+func TestFindMatchingKubectlExactIgnoresPreferSystem(t *testing.T) {
+	td, err := setupFilesystemTest()
+	if err != nil {
+		t.Errorf("Unexpeted failure: %v", err)
+	}
+	defer func() {
+		if err := teardownFilesystemTest(td); err != nil {
+			fmt.Printf("Error while tearing down test filesystem: %v\n", err)
+		}
+	}()
+
+	if err := createFakeKubectlBinaries(fakeKubectlBinaries(td.FakeHome, []string{"1.27.3"}, &localKubectlNamer{})); err != nil {
+		t.Error(err)
+	}
+	if err := createFakeKubectlBinaries(fakeKubectlBinaries(td.FakeSysBinPath, []string{"1.27.8"}, &systemKubectlNamer{})); err != nil {
+		t.Error(err)
+	}
+
+	td.Finder.PreferSystem = true
+	b, err := td.Finder.FindMatchingKubectl(semver.MustParse("1.27.3"), MatchExact)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if b.Version.String() != "1.27.3" {
+		t.Errorf("Got %s instead of 1.27.3", b.Version)
+	}
+}
+
+func TestVersionMatchPrerelease(t *testing.T) {
+	for _, tc := range []struct {
+		match    VersionMatch
+		kubectl  string
+		server   string
+		expected bool
+	}{
+		{MatchSkew, "1.31.0-rc.1", "1.30.4", false},
+		{MatchSkew, "1.31.0-rc.1", "1.31.0", false},
+		{MatchSkew, "1.31.0-rc.2", "1.31.0-rc.1", true},
+		{MatchSkew, "1.30.4", "1.31.0-rc.1", true},
+		{MatchExact, "1.31.0-rc.2", "1.31.0-rc.1", false},
+		{MatchExact, "1.31.0-rc.1", "1.31.0-rc.1", true},
+		{MatchPatch, "1.31.0-rc.1", "1.31.0-rc.2", false},
+		{MatchPatch, "1.31.0", "1.31.0-rc.2", true},
+	} {
+		actual := tc.match.Accepts(semver.MustParse(tc.kubectl), semver.MustParse(tc.server))
+		if actual != tc.expected {
+			t.Errorf("%s: kubectl %s with server %s, got %v instead of %v", tc.match, tc.kubectl, tc.server, actual, tc.expected)
+		}
+	}
+
+	// the legacy naming scheme doesn't include prereleases
+	if v, err := inferLocalKubectlVersion("kubectl1.31.0-rc.1"); err != nil || v.String() != "1.31.0-rc.1" {
+		t.Errorf("Got %s %v instead of 1.31.0-rc.1", v, err)
+	}
+	if err := common.SetLocalNamingTemplate("kubectl-{{.Version}}"); err != nil {
+		t.Fatal(err)
+	}
+	defer common.SetLocalNamingTemplate("")
+	if v, err := inferLocalKubectlVersion("kubectl1.31.0-rc.1"); err == nil {
+		t.Errorf("Expected the prerelease not to be mistaken for %s", v)
+	}
+}
+
+func TestSystemKubectlBinariesManyPaths(t *testing.T) {
+	td, err := setupFilesystemTest()
+	if err != nil {
+		t.Errorf("Unexpeted failure: %v", err)
+	}
+	defer func() {
+		if err := teardownFilesystemTest(td); err != nil {
+			fmt.Printf("Error while tearing down test filesystem: %v\n", err)
+		}
+	}()
+	extra := filepath.Join(td.FakeSysBinPath, "opt")
+	td.Finder.ExtraSysBinaryPaths = []string{extra, filepath.Join(td.FakeSysBinPath, "missing")}
+
+	systemBins := fakeKubectlBinaries(td.FakeSysBinPath, []string{"1.26.0"}, &systemKubectlNamer{})
+	extraBins := fakeKubectlBinaries(extra, []string{"1.28.0"}, &systemKubectlNamer{})
+	if err := createFakeKubectlBinaries(append(systemBins, extraBins...)); err != nil {
+		t.Fatal(err)
+	}
+
+	bins, err := td.Finder.SystemKubectlBinaries()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	SortKubectlByVersion(bins, true)
+	if len(bins) != 2 || bins[0].Path != extraBins[0].Path {
+		t.Errorf("Expected the binaries of both directories, got %+v", bins)
+	}
+}
+
+func TestInferAlternativeKubectlVersion(t *testing.T) {
+	for name, expected := range map[string]string{
+		"kubectl-v1.28.3":          "1.28.3",
+		"kubectl_1.28.3":           "1.28.3",
+		"kubectlv1.28.3":           "1.28.3",
+		"kubectl-1.31.0-rc.1":      "1.31.0-rc.1",
+		"kubectl-view_secret":      "",
+		"kubectl-1.28":             "",
+		"kubectl-v1.28.3.sha256":   "",
+		"kubectl-v1.28.3-linux.gz": "",
+	} {
+		v, err := inferAlternativeKubectlVersion(name + osexec.Ext)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("%s: expected the name to be refused, got %s", name, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		} else if v.String() != expected {
+			t.Errorf("%s: got %s instead of %s", name, v, expected)
+		}
+	}
+}
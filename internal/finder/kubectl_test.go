@@ -61,3 +61,25 @@ func TestSortDesc(t *testing.T) {
 		}
 	}
 }
+
+func TestWithinSkewPolicy(t *testing.T) {
+	server := semver.MustParse("1.20.3")
+
+	tests := []struct {
+		kubectl  string
+		expected bool
+	}{
+		{"1.18.9", false},
+		{"1.19.0", true},
+		{"1.20.0", true},
+		{"1.21.7", true},
+		{"1.22.0", false},
+		{"2.20.0", false},
+	}
+
+	for _, test := range tests {
+		if actual := WithinSkewPolicy(semver.MustParse(test.kubectl), server); actual != test.expected {
+			t.Errorf("%s: got %v instead of %v", test.kubectl, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package finder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// VersionMatch defines which local kubectl binaries can be used to talk
+// with a kubernetes API server
+type VersionMatch string
+
+const (
+	// MatchExact accepts only the version of the API server
+	MatchExact VersionMatch = "exact"
+	// MatchPatch accepts the patch releases of the same minor release of
+	// the API server that are not older than it
+	MatchPatch VersionMatch = "patch"
+	// MatchMinor accepts any patch release of the same minor release of
+	// the API server
+	MatchMinor VersionMatch = "minor"
+	// MatchSkew accepts all the versions supported by the upstream version
+	// skew policy, this is the default
+	MatchSkew VersionMatch = "skew"
+)
+
+// ParseVersionMatch returns the VersionMatch with the given name
+func ParseVersionMatch(name string) (VersionMatch, error) {
+	switch m := VersionMatch(strings.ToLower(name)); m {
+	case MatchExact, MatchPatch, MatchMinor, MatchSkew:
+		return m, nil
+	case "":
+		return MatchSkew, nil
+	default:
+		return "", fmt.Errorf("Unknown version match: %s", name)
+	}
+}
+
+// Accepts returns true when the given kubectl version can be used with
+// the given version of the API server. Prereleases of kubectl, like
+// 1.31.0-rc.1, are used only with the servers running a prerelease of the
+// same version
+func (m VersionMatch) Accepts(kubectl, server semver.Version) bool {
+	if len(kubectl.Pre) > 0 {
+		sameRelease := kubectl.Major == server.Major &&
+			kubectl.Minor == server.Minor &&
+			kubectl.Patch == server.Patch
+		if !sameRelease || len(server.Pre) == 0 {
+			return false
+		}
+	}
+
+	sameMinor := kubectl.Major == server.Major && kubectl.Minor == server.Minor
+	switch m {
+	case MatchExact:
+		return kubectl.Equals(server)
+	case MatchPatch:
+		return sameMinor && kubectl.GTE(server)
+	case MatchMinor:
+		return sameMinor
+	default:
+		return WithinSkewPolicy(kubectl, server)
+	}
+}
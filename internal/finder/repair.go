@@ -0,0 +1,88 @@
+package finder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// Repair describes how a binary whose filename doesn't match the version
+// it reports is fixed
+type Repair struct {
+	Binary KubectlBinary
+	// Reported is the version reported by the binary, it's not set when
+	// the binary cannot be executed
+	Reported *semver.Version
+	// Destination is where the binary is moved to, the quarantine
+	// directory when Quarantine is true
+	Destination string
+	// Quarantine is true when the binary is moved out of the store
+	Quarantine bool
+}
+
+// PlanRepair checks whether the binary reports the version advertised by
+// its filename. When that doesn't happen, the returned Repair describes how
+// to fix the store: the binary is renamed after the version it reports or,
+// when that's not possible or quarantine is true, it's moved to the
+// quarantine directory. The boolean is false when nothing has to be done
+func PlanRepair(b KubectlBinary, quarantineDir string, quarantine bool) (Repair, bool) {
+	repair := Repair{Binary: b}
+
+	reported, err := ReportedVersion(b.Path)
+	if err == nil {
+		if b.MatchesReportedVersion(reported) {
+			return repair, false
+		}
+		repair.Reported = &reported
+	}
+
+	if !quarantine && repair.Reported != nil {
+		destination := filepath.Join(filepath.Dir(b.Path), common.BuildKubectlNameForLocalBin(*repair.Reported))
+		if _, err := os.Stat(destination); os.IsNotExist(err) {
+			repair.Destination = destination
+			return repair, true
+		}
+	}
+
+	repair.Quarantine = true
+	repair.Destination = quarantineDir
+	return repair, true
+}
+
+// Apply moves the binary to its new location. Binaries in use by other
+// invocations of kuberlr are not moved
+func (r Repair) Apply() error {
+	lock, locked, err := common.LockForRemoval(r.Binary.Path)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return fmt.Errorf("%s is in use, try again later", r.Binary.Path)
+	}
+	defer lock.Release()
+
+	if r.Quarantine {
+		reason := "cannot be executed"
+		if r.Reported != nil {
+			reason = fmt.Sprintf("reports kubectl %s instead of %s", r.Reported, r.Binary.Version)
+		}
+		_, err := common.Quarantine(r.Binary.Path, r.Destination, common.Metadata{}, reason, time.Now())
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.Destination), 0700); err != nil {
+		return err
+	}
+	if err := os.Rename(r.Binary.Path, r.Destination); err != nil {
+		return err
+	}
+	if err := common.MoveMetadata(r.Binary.Path, r.Destination); err != nil {
+		return err
+	}
+	return nil
+}
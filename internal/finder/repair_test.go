@@ -0,0 +1,87 @@
+package finder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func createFakeReportingKubectl(dir string, filenameVersion, reportedVersion string) (KubectlBinary, error) {
+	b := KubectlBinary{
+		Path:    filepath.Join(dir, common.BuildKubectlNameForLocalBin(semver.MustParse(filenameVersion))),
+		Version: semver.MustParse(filenameVersion),
+	}
+	script := fmt.Sprintf("#!/bin/sh\necho '{\"clientVersion\": {\"gitVersion\": \"v%s\"}}'\n", reportedVersion)
+	return b, ioutil.WriteFile(b.Path, []byte(script), 0755)
+}
+
+func TestPlanRepair(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake kubectl binaries are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "kuberlr-repair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	quarantineDir := filepath.Join(dir, "quarantine")
+
+	good, err := createFakeReportingKubectl(dir, "1.19.2", "1.19.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, needed := PlanRepair(good, quarantineDir, false); needed {
+		t.Error("Binary reporting the right version should not be repaired")
+	}
+
+	bad, err := createFakeReportingKubectl(dir, "1.20.1", "1.20.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repair, needed := PlanRepair(bad, quarantineDir, false)
+	if !needed {
+		t.Fatal("Mismatch not detected")
+	}
+	if repair.Quarantine {
+		t.Error("Binary should be renamed, not quarantined")
+	}
+	if err := repair.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join(dir, common.BuildKubectlNameForLocalBin(semver.MustParse("1.20.3")))
+	if _, err := os.Stat(expected); err != nil {
+		t.Errorf("Binary not renamed: %v", err)
+	}
+
+	// the right version is already there, hence the mislabeled binary
+	// is quarantined
+	dup, err := createFakeReportingKubectl(dir, "1.20.2", "1.20.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repair, needed = PlanRepair(dup, quarantineDir, false)
+	if !needed || !repair.Quarantine {
+		t.Fatalf("Expected binary to be quarantined, got %+v", repair)
+	}
+	if err := repair.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dup.Path); !os.IsNotExist(err) {
+		t.Error("Quarantined binary is still inside of the store")
+	}
+	quarantined, err := filepath.Glob(filepath.Join(quarantineDir, "kubectl1.20.2.*"))
+	if err != nil || len(quarantined) != 1 {
+		t.Fatalf("Expected the binary inside of the quarantine directory, got %v, %v", quarantined, err)
+	}
+	if m, found, err := common.LoadMetadata(quarantined[0]); err != nil || !found || m.QuarantineReason == "" {
+		t.Errorf("The quarantine reason has not been recorded: %+v, %v", m, err)
+	}
+}
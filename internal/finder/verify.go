@@ -0,0 +1,29 @@
+package finder
+
+import (
+	"path/filepath"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// ReportedVersion executes the given kubectl binary and returns the
+// version it reports
+func ReportedVersion(path string) (semver.Version, error) {
+	return common.ReportedKubectlVersion(path)
+}
+
+// MatchesReportedVersion returns true when the version reported by the
+// binary is the one its filename advertises. System-wide binaries named
+// "kubectl<major>.<minor>" do not advertise the patch level, hence only
+// major and minor are compared for them
+func (b KubectlBinary) MatchesReportedVersion(reported semver.Version) bool {
+	if b.System && filepath.Base(b.Path) == common.BuildKubectlNameForSystemBin(b.Version) {
+		return reported.Major == b.Version.Major && reported.Minor == b.Version.Minor
+	}
+
+	// build metadata is not part of the filename
+	reported.Build = nil
+	return reported.Equals(b.Version)
+}
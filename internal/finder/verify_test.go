@@ -0,0 +1,86 @@
+package finder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestReportedVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake kubectl binaries are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "kuberlr-verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, common.BuildKubectlNameForLocalBin(semver.MustParse("1.20.1")))
+	script := fmt.Sprintf("#!/bin/sh\necho '%s'\n", `{"clientVersion": {"gitVersion": "v1.20.2"}}`)
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	reported, err := ReportedVersion(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reported.Equals(semver.MustParse("1.20.2")) {
+		t.Errorf("Got %s instead of 1.20.2", reported)
+	}
+
+	b := KubectlBinary{Path: path, Version: semver.MustParse("1.20.1")}
+	if b.MatchesReportedVersion(reported) {
+		t.Error("Mismatch not detected")
+	}
+}
+
+func TestMatchesReportedVersionSystemBinary(t *testing.T) {
+	b := KubectlBinary{
+		Path:    filepath.Join("/usr/bin", common.BuildKubectlNameForSystemBin(semver.MustParse("1.19.0"))),
+		Version: semver.MustParse("1.19.0"),
+		System:  true,
+	}
+
+	if !b.MatchesReportedVersion(semver.MustParse("1.19.7")) {
+		t.Error("System binaries should be matched by minor version")
+	}
+	if b.MatchesReportedVersion(semver.MustParse("1.20.0")) {
+		t.Error("Mismatch not detected")
+	}
+}
+
+func TestMatchesReportedVersionCustomName(t *testing.T) {
+	defer common.SetLocalNamingTemplate("")
+	if err := common.SetLocalNamingTemplate("kubectl-{{.Major}}.{{.Minor}}.{{.Patch}}{{.Ext}}"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, system := range []bool{false, true} {
+		for _, name := range []string{
+			common.BuildKubectlNameForLocalBin(semver.MustParse("1.27.3")),
+			"kubectl_v1.27.3",
+		} {
+			b := KubectlBinary{
+				Path:    filepath.Join("/usr/bin", name),
+				Version: semver.MustParse("1.27.3"),
+				System:  system,
+			}
+			if b.MatchesReportedVersion(semver.MustParse("1.27.1")) {
+				t.Errorf("%s: mismatch of the patch level not detected", name)
+			}
+			if !b.MatchesReportedVersion(semver.MustParse("1.27.3")) {
+				t.Errorf("%s: expected match", name)
+			}
+		}
+	}
+}
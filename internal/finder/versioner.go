@@ -2,13 +2,17 @@ package finder
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/flavio/kuberlr/internal/common"
 	"github.com/flavio/kuberlr/internal/downloader"
 	"github.com/flavio/kuberlr/internal/kubehelper"
+	"github.com/flavio/kuberlr/internal/policy"
+	"github.com/flavio/kuberlr/internal/warnings"
 
 	"github.com/blang/semver/v4"
 	"k8s.io/klog"
@@ -21,13 +25,20 @@ type downloadHelper interface {
 
 type kubeAPIHelper interface {
 	Version(timeout int64) (semver.Version, error)
+	Server() string
+}
+
+type warner interface {
+	Warn(class, format string, args ...interface{})
 }
 
 type iFinder interface {
 	SystemKubectlBinaries() (KubectlBinaries, error)
 	LocalKubectlBinaries() (KubectlBinaries, error)
+	SharedKubectlBinaries() (KubectlBinaries, error)
 	AllKubectlBinaries(reverseSort bool) KubectlBinaries
 	FindCompatibleKubectl(requestedVersion semver.Version) (KubectlBinary, error)
+	FindMatchingKubectl(requestedVersion semver.Version, match VersionMatch) (KubectlBinary, error)
 	MostRecentKubectlAvailable() (KubectlBinary, error)
 }
 
@@ -36,46 +47,174 @@ type Versioner struct {
 	kFinder    iFinder
 	downloader downloadHelper
 	apiServer  kubeAPIHelper
+	warner     warner
+
+	defaultVersion *semver.Version
+	pinnedVersion  *semver.Version
+	sharedStore    common.SharedStore
+	policy         *policy.Policy
+	probeObserver  func(latency time.Duration, err error)
+	cacheObserver  func(hit bool)
+	serverCache    string
+	serverCacheTTL time.Duration
+	fallback       []string
+	silentFallback bool
 }
 
 // NewVersioner is an helper function that creates a new Versioner instance
-func NewVersioner(f iFinder) *Versioner {
-	return &Versioner{
+func NewVersioner(f iFinder, d *downloader.Downloder, w *warnings.Warner) *Versioner {
+	v := &Versioner{
 		kFinder:    f,
-		downloader: &downloader.Downloder{},
+		downloader: d,
 		apiServer:  &kubehelper.KubeAPI{},
 	}
+	// avoid storing a nil pointer inside of the interface
+	if w != nil {
+		v.warner = w
+	}
+	return v
+}
+
+// SetDefaultVersion sets the version of kubectl to use when the version
+// of the kubernetes API server cannot be found
+func (v *Versioner) SetDefaultVersion(version semver.Version) {
+	v.defaultVersion = &version
+}
+
+// SetPinnedVersion sets the version of kubectl to use regardless of the
+// version of the kubernetes API server, which isn't contacted at all
+func (v *Versioner) SetPinnedVersion(version semver.Version) {
+	v.pinnedVersion = &version
+}
+
+// SetContext makes the Versioner look for the version of the kubernetes API
+// server of the given kubeconfig context, instead of the current one
+func (v *Versioner) SetContext(context string) {
+	v.apiServer = &kubehelper.KubeAPI{Context: context}
+}
+
+// SetSharedStore makes the Versioner save the binaries it downloads inside of
+// the given shared store, when the current user is allowed to write there
+func (v *Versioner) SetSharedStore(store common.SharedStore) {
+	v.sharedStore = store
+}
+
+// SetPolicy makes the Versioner refuse to download the versions of kubectl
+// forbidden by the given policy
+func (v *Versioner) SetPolicy(p *policy.Policy) {
+	v.policy = p
+}
+
+// SetProbeObserver sets a function that is invoked after each request made
+// to find the version of the kubernetes API server
+func (v *Versioner) SetProbeObserver(observer func(latency time.Duration, err error)) {
+	v.probeObserver = observer
+}
+
+// SetServerCacheObserver sets a function that is invoked each time the
+// version of the kubernetes API server is looked up inside of the cache,
+// hit is true when the cached version has been used
+func (v *Versioner) SetServerCacheObserver(observer func(hit bool)) {
+	v.cacheObserver = observer
+}
+
+// SetServerVersionCache makes the Versioner remember the version of each
+// kubernetes API server inside of the given file, the API server is not
+// contacted again until ttl is elapsed
+func (v *Versioner) SetServerVersionCache(path string, ttl time.Duration) {
+	v.serverCache = path
+	v.serverCacheTTL = ttl
 }
 
 // KubectlVersionToUse returns the kubectl version to be used to interact with
 // the remote server. The method takes into account different failure scenarios
 // and acts accordingly.
 func (v *Versioner) KubectlVersionToUse(timeout int64) (semver.Version, error) {
+	if v.pinnedVersion != nil {
+		klog.V(2).Infof("Using pinned kubectl version %s", v.pinnedVersion)
+		return *v.pinnedVersion, nil
+	}
+
+	if common.IsOffline() {
+		return v.offlineVersion()
+	}
+
+	server := ""
+	if v.serverCacheTTL > 0 {
+		server = v.apiServer.Server()
+	}
+	if server != "" {
+		version, found := common.CachedServerVersion(v.serverCache, server, time.Now(), v.serverCacheTTL)
+		if v.cacheObserver != nil {
+			v.cacheObserver(found)
+		}
+		if found {
+			klog.V(2).Infof("Using cached version %s of %s", version, server)
+			return version, nil
+		}
+	}
+
+	start := time.Now()
 	version, err := v.apiServer.Version(timeout)
+	if v.probeObserver != nil {
+		v.probeObserver(time.Since(start), err)
+	}
+	if err == nil && server != "" {
+		if err := common.RecordServerVersion(v.serverCache, server, version, time.Now()); err != nil {
+			klog.V(1).Infof("Cannot cache the version of %s: %v", server, err)
+		}
+	}
 	if err != nil {
+		class := warnings.Fallback
 		if isUnreachable(err) {
 			// the remote server is unreachable, let's get
 			// the latest version of kubectl that is available on the system
 			klog.V(2).Info("Remote kubernetes server unreachable")
+			class = warnings.Unreachable
 		} else {
 			klog.V(1).Info(err)
 		}
-		kubectl, err := v.kFinder.MostRecentKubectlAvailable()
-		if err == nil {
-			return kubectl.Version, nil
-		} else if common.IsNoVersionFound(err) {
-			klog.V(2).Info("No local kubectl binary found, fetching latest stable release version")
-			return v.downloader.UpstreamStableVersion()
-		}
+		return v.fallbackVersion(class)
 	}
 	return version, err
 }
 
+// offlineVersion returns the kubectl version to use without reaching the
+// kubernetes API server: the cached version of the server is used
+// regardless of its age, the local binaries otherwise
+func (v *Versioner) offlineVersion() (semver.Version, error) {
+	if v.serverCache != "" {
+		if server := v.apiServer.Server(); server != "" {
+			if versions, err := common.LoadServerVersions(v.serverCache); err == nil {
+				if cached, found := versions[server]; found {
+					if version, err := semver.Parse(cached.Version); err == nil {
+						klog.V(2).Infof("Using cached version %s of %s", version, server)
+						return version, nil
+					}
+				}
+			}
+		}
+	}
+
+	version, err := v.fallbackVersion("")
+	if err != nil {
+		return version, fmt.Errorf("Cannot find the kubectl version to use while offline: %v", err)
+	}
+	return version, nil
+}
+
 // EnsureCompatibleKubectlAvailable ensures the kubectl binary with the specified
 // version is available on the system. It will return the full path to the
-// binary
+// binary. A pinned version is used only when it's available as it is,
+// otherwise it's downloaded
 func (v *Versioner) EnsureCompatibleKubectlAvailable(version semver.Version, allowDownload bool) (string, error) {
-	kubectl, err := v.kFinder.FindCompatibleKubectl(version)
+	var kubectl KubectlBinary
+	var err error
+	if v.pinnedVersion != nil && v.pinnedVersion.Equals(version) {
+		kubectl, err = v.kFinder.FindMatchingKubectl(version, MatchExact)
+	} else {
+		kubectl, err = v.kFinder.FindCompatibleKubectl(version)
+	}
 	if err == nil {
 		return kubectl.Path, nil
 	}
@@ -83,21 +222,43 @@ func (v *Versioner) EnsureCompatibleKubectlAvailable(version semver.Version, all
 	if !allowDownload {
 		return "", errors.New("The right kubectl is missing, binary downloads from kubernetes' upstream mirror are disabled")
 	}
+	if common.IsOffline() {
+		return "", fmt.Errorf("kubectl %s is missing and cannot be downloaded: %v", version, common.ErrOffline)
+	}
+	if err := v.policy.Allows(version); err != nil {
+		return "", err
+	}
 
 	klog.Infof("Right kubectl missing, downloading version %s", version.String())
 
-	// download the right kubectl to the local cache
+	// download the right kubectl to the shared store or to the local cache
+	downloadDir := common.LocalDownloadDir()
+	shared := v.sharedStore.Usable()
+	if shared {
+		downloadDir = v.sharedStore.Dir()
+	}
 	filename := filepath.Join(
-		common.LocalDownloadDir(),
+		downloadDir,
 		common.BuildKubectlNameForLocalBin(version))
 
 	if err := v.downloader.GetKubectlBinary(version, filename); err != nil {
 		return "", err
 	}
+	if shared {
+		if err := v.sharedStore.Share(filename); err != nil {
+			klog.Warningf("Cannot share %s with the other users: %v", filename, err)
+		}
+	}
 
 	return filename, nil
 }
 
+func (v *Versioner) warn(class, format string, args ...interface{}) {
+	if v.warner != nil {
+		v.warner.Warn(class, format, args...)
+	}
+}
+
 func isUnreachable(err error) bool {
 	var e *url.Error
 	return os.IsTimeout(err) || errors.As(err, &e)
@@ -2,8 +2,13 @@ package finder
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/blang/semver/v4"
 
@@ -12,9 +17,11 @@ import (
 
 type mockFinder struct {
 	localKubectlBinaries       func() (KubectlBinaries, error)
+	sharedKubectlBinaries      func() (KubectlBinaries, error)
 	systemKubectlBinaries      func() (KubectlBinaries, error)
 	allKubectlBinaries         func(reverseSort bool) KubectlBinaries
 	findCompatibleKubectl      func(requestedVersion semver.Version) (KubectlBinary, error)
+	findMatchingKubectl        func(requestedVersion semver.Version, match VersionMatch) (KubectlBinary, error)
 	mostRecentKubectlAvailable func() (KubectlBinary, error)
 }
 
@@ -22,6 +29,13 @@ func (m *mockFinder) LocalKubectlBinaries() (KubectlBinaries, error) {
 	return m.localKubectlBinaries()
 }
 
+func (m *mockFinder) SharedKubectlBinaries() (KubectlBinaries, error) {
+	if m.sharedKubectlBinaries == nil {
+		return KubectlBinaries{}, nil
+	}
+	return m.sharedKubectlBinaries()
+}
+
 func (m *mockFinder) SystemKubectlBinaries() (KubectlBinaries, error) {
 	return m.systemKubectlBinaries()
 }
@@ -39,6 +53,10 @@ func (m *mockFinder) FindCompatibleKubectl(requestedVersion semver.Version) (Kub
 	return m.findCompatibleKubectl(requestedVersion)
 }
 
+func (m *mockFinder) FindMatchingKubectl(requestedVersion semver.Version, match VersionMatch) (KubectlBinary, error) {
+	return m.findMatchingKubectl(requestedVersion, match)
+}
+
 func (m *mockFinder) MostRecentKubectlAvailable() (KubectlBinary, error) {
 	return m.mostRecentKubectlAvailable()
 }
@@ -56,14 +74,27 @@ func (m *mockDownloader) UpstreamStableVersion() (semver.Version, error) {
 	return m.upstreamStableVersion()
 }
 
+type mockWarner struct {
+	warnings []string
+}
+
+func (m *mockWarner) Warn(class, format string, args ...interface{}) {
+	m.warnings = append(m.warnings, fmt.Sprintf(format, args...))
+}
+
 type mockAPIServer struct {
 	version func(timeout int64) (semver.Version, error)
+	server  string
 }
 
 func (m *mockAPIServer) Version(timeout int64) (semver.Version, error) {
 	return m.version(timeout)
 }
 
+func (m *mockAPIServer) Server() string {
+	return m.server
+}
+
 type mockTimeoutError struct {
 	Err error
 }
@@ -209,18 +240,16 @@ func genericTestKubectlVersionToUseTimeout(localBins, systemBins KubectlBinaries
 
 	finderMock := mockFinder{}
 	finderMock.localKubectlBinaries = func() (KubectlBinaries, error) {
-		err := &common.NoVersionFoundError{}
-		if len(localBins) > 0 {
-			err = nil
+		if len(localBins) == 0 {
+			return localBins, &common.NoVersionFoundError{}
 		}
-		return localBins, err
+		return localBins, nil
 	}
 	finderMock.systemKubectlBinaries = func() (KubectlBinaries, error) {
-		err := &common.NoVersionFoundError{}
-		if len(systemBins) > 0 {
-			err = nil
+		if len(systemBins) == 0 {
+			return systemBins, &common.NoVersionFoundError{}
 		}
-		return systemBins, err
+		return systemBins, nil
 	}
 	finderMock.mostRecentKubectlAvailable = func() (KubectlBinary, error) {
 		return expected, nil
@@ -238,8 +267,263 @@ func genericTestKubectlVersionToUseTimeout(localBins, systemBins KubectlBinaries
 	}
 
 	if !actual.Equals(expected.Version) {
-		return fmt.Errorf("Got %s instead of %s", actual, expected)
+		return fmt.Errorf("Got %s instead of %s", actual, expected.Version)
 	}
 
 	return nil
 }
+
+func TestKubectlVersionToUseTimeoutWithDefaultVersion(t *testing.T) {
+	apiMock := mockAPIServer{}
+	apiMock.version = func(timeout int64) (semver.Version, error) {
+		return semver.Version{}, &mockTimeoutError{}
+	}
+
+	finderMock := mockFinder{}
+	finderMock.mostRecentKubectlAvailable = func() (KubectlBinary, error) {
+		return KubectlBinary{Version: semver.MustParse("1.9.0")}, nil
+	}
+
+	versioner := Versioner{
+		kFinder:   &finderMock,
+		apiServer: &apiMock,
+	}
+	expected := semver.MustParse("1.7.3")
+	versioner.SetDefaultVersion(expected)
+
+	actual, err := versioner.KubectlVersionToUse(1)
+	if err != nil {
+		t.Errorf("Unexpected error %+v", err)
+	}
+	if !actual.Equals(expected) {
+		t.Errorf("Got %s instead of %s", actual, expected)
+	}
+}
+
+func TestKubectlVersionToUsePinnedVersion(t *testing.T) {
+	apiMock := mockAPIServer{}
+	apiMock.version = func(timeout int64) (semver.Version, error) {
+		return semver.Version{}, fmt.Errorf("the API server should not be contacted")
+	}
+
+	v := Versioner{
+		kFinder:    &mockFinder{},
+		downloader: &mockDownloader{},
+		apiServer:  &apiMock,
+	}
+	v.SetPinnedVersion(semver.MustParse("1.27.4"))
+
+	version, err := v.KubectlVersionToUse(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if version.String() != "1.27.4" {
+		t.Errorf("Got %s instead of the pinned 1.27.4", version)
+	}
+}
+
+func TestEnsureCompatibleKubectlAvailablePinnedVersion(t *testing.T) {
+	pinned := semver.MustParse("1.27.3")
+
+	finderMock := mockFinder{}
+	finderMock.findCompatibleKubectl = func(v semver.Version) (KubectlBinary, error) {
+		return KubectlBinary{Version: semver.MustParse("1.28.5"), Path: "/tmp/kubectl1.28.5"}, nil
+	}
+	finderMock.findMatchingKubectl = func(v semver.Version, match VersionMatch) (KubectlBinary, error) {
+		if match != MatchExact {
+			t.Errorf("Pinned versions must be matched exactly, got %s", match)
+		}
+		return KubectlBinary{}, &common.NoVersionFoundError{}
+	}
+
+	downloaded := ""
+	downloaderMock := mockDownloader{}
+	downloaderMock.getKubectlBinary = func(v semver.Version, destination string) error {
+		downloaded = v.String()
+		return nil
+	}
+
+	versioner := Versioner{
+		kFinder:    &finderMock,
+		downloader: &downloaderMock,
+	}
+	versioner.SetPinnedVersion(pinned)
+
+	if _, err := versioner.EnsureCompatibleKubectlAvailable(pinned, true); err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if downloaded != "1.27.3" {
+		t.Errorf("The pinned version should have been downloaded, got %q", downloaded)
+	}
+}
+
+func TestKubectlVersionToUseCachedServerVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-versioner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	probes := 0
+	apiMock := mockAPIServer{server: "https://prod.example.com:6443"}
+	apiMock.version = func(timeout int64) (semver.Version, error) {
+		probes++
+		return semver.MustParse("1.28.7"), nil
+	}
+
+	v := Versioner{
+		kFinder:    &mockFinder{},
+		downloader: &mockDownloader{},
+		apiServer:  &apiMock,
+	}
+	v.SetServerVersionCache(filepath.Join(dir, "server-versions.json"), time.Hour)
+	hits := []bool{}
+	v.SetServerCacheObserver(func(hit bool) {
+		hits = append(hits, hit)
+	})
+
+	for i := 0; i < 3; i++ {
+		version, err := v.KubectlVersionToUse(1)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if version.String() != "1.28.7" {
+			t.Errorf("Got %s instead of 1.28.7", version)
+		}
+	}
+	if probes != 1 {
+		t.Errorf("The API server has been contacted %d times instead of once", probes)
+	}
+	if expected := []bool{false, true, true}; !reflect.DeepEqual(hits, expected) {
+		t.Errorf("Got the cache lookups %v instead of %v", hits, expected)
+	}
+}
+
+func TestKubectlVersionToUseFallback(t *testing.T) {
+	apiMock := mockAPIServer{}
+	apiMock.version = func(timeout int64) (semver.Version, error) {
+		return semver.Version{}, &mockTimeoutError{}
+	}
+
+	finderMock := mockFinder{}
+	finderMock.systemKubectlBinaries = func() (KubectlBinaries, error) {
+		return fakeKubectlBinaries("/usr/bin", []string{"1.26.0", "1.27.0"}, &systemKubectlNamer{}), nil
+	}
+	finderMock.localKubectlBinaries = func() (KubectlBinaries, error) {
+		return fakeKubectlBinaries("/home/user/.kuberlr", []string{"1.29.3"}, &localKubectlNamer{}), nil
+	}
+
+	downloadMock := mockDownloader{}
+	downloadMock.upstreamStableVersion = func() (semver.Version, error) {
+		return semver.MustParse("1.30.1"), nil
+	}
+
+	for _, tc := range []struct {
+		fallback []string
+		expected string
+	}{
+		{[]string{FallbackDefault, FallbackNewestLocal}, "1.29.3"},
+		{[]string{FallbackStableRemote, FallbackNewestLocal}, "1.30.1"},
+		{[]string{FallbackSystem, FallbackStableRemote}, "1.27.0"},
+	} {
+		v := Versioner{
+			kFinder:    &finderMock,
+			apiServer:  &apiMock,
+			downloader: &downloadMock,
+		}
+		v.SetFallback(tc.fallback)
+
+		actual, err := v.KubectlVersionToUse(1)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tc.fallback, err)
+		} else if actual.String() != tc.expected {
+			t.Errorf("%v: got %s instead of %s", tc.fallback, actual, tc.expected)
+		}
+	}
+
+	v := Versioner{
+		kFinder:    &finderMock,
+		apiServer:  &apiMock,
+		downloader: &downloadMock,
+	}
+	v.SetFallback([]string{FallbackDefault})
+	if _, err := v.KubectlVersionToUse(1); !common.IsNoVersionFound(err) {
+		t.Errorf("Expected no version to be found without a default version, got %v", err)
+	}
+
+	if _, err := ParseFallback([]string{"newest-local", "latest"}); err == nil {
+		t.Error("Expected unknown fallbacks to be refused")
+	}
+}
+
+func TestSilentNewestLocalFallback(t *testing.T) {
+	apiMock := mockAPIServer{}
+	apiMock.version = func(timeout int64) (semver.Version, error) {
+		return semver.Version{}, &mockTimeoutError{}
+	}
+
+	// the system-wide binary is newer, but it's not downloaded by kuberlr
+	finderMock := mockFinder{}
+	finderMock.systemKubectlBinaries = func() (KubectlBinaries, error) {
+		return fakeKubectlBinaries("/usr/bin", []string{"1.30.0"}, &systemKubectlNamer{}), nil
+	}
+	finderMock.localKubectlBinaries = func() (KubectlBinaries, error) {
+		return fakeKubectlBinaries("/home/user/.kuberlr", []string{"1.27.1"}, &localKubectlNamer{}), nil
+	}
+	finderMock.sharedKubectlBinaries = func() (KubectlBinaries, error) {
+		return fakeKubectlBinaries("/opt/kuberlr", []string{"1.28.4"}, &localKubectlNamer{}), nil
+	}
+
+	fallback, err := FallbackFor(FallbackNewestLocal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warner := mockWarner{}
+	v := Versioner{
+		kFinder:    &finderMock,
+		apiServer:  &apiMock,
+		downloader: &mockDownloader{},
+		warner:     &warner,
+	}
+	v.SetFallback(fallback)
+	v.SetSilentFallback(true)
+
+	actual, err := v.KubectlVersionToUse(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if actual.String() != "1.28.4" {
+		t.Errorf("Got %s instead of 1.28.4", actual)
+	}
+	if len(warner.warnings) != 0 {
+		t.Errorf("Unexpected warnings %v", warner.warnings)
+	}
+
+	// the same fallback warns when it has not been asked for
+	v.SetSilentFallback(false)
+	if _, err := v.KubectlVersionToUse(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(warner.warnings) != 1 {
+		t.Errorf("Expected a warning, got %v", warner.warnings)
+	}
+}
+
+func TestFallbackFor(t *testing.T) {
+	fallback, err := FallbackFor(FallbackStableRemote)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(fallback, ",") != "default,stable-remote,newest-local" {
+		t.Errorf("Unexpected fallbacks %v", fallback)
+	}
+	if fallback, _ := FallbackFor(FallbackNewestLocal); strings.Join(fallback, ",") != "default,newest-local,stable-remote" {
+		t.Errorf("Unexpected fallbacks %v", fallback)
+	}
+	if fallback, _ := FallbackFor(""); strings.Join(fallback, ",") != strings.Join(DefaultFallback, ",") {
+		t.Errorf("Expected the default fallbacks, got %v", fallback)
+	}
+	if _, err := FallbackFor("system"); err == nil {
+		t.Error("Expected unknown preferences to be refused")
+	}
+}
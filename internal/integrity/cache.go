@@ -0,0 +1,89 @@
+package integrity
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// cacheEntry records the checksum of a file, together with the attributes
+// used to detect whether the file changed since then
+type cacheEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Digest  string    `json:"digest"`
+}
+
+// Cache keeps the checksums computed by previous verifications. The
+// checksum of a file is computed again only when its size or its
+// modification time change
+type Cache struct {
+	// Path is the file holding the cache
+	Path    string
+	entries map[string]cacheEntry
+}
+
+// LoadCache reads the cache saved at the given path, an empty cache is
+// returned when the file doesn't exist or cannot be parsed
+func LoadCache(path string) *Cache {
+	c := &Cache{Path: path, entries: map[string]cacheEntry{}}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		c.entries = map[string]cacheEntry{}
+	}
+	return c
+}
+
+// Checksum returns the SHA256 checksum of the given file, using the cached
+// value when the file didn't change. The boolean is true when the cached
+// value has been used
+func (c *Cache) Checksum(path string) (string, bool, error) {
+	if c == nil {
+		sum, err := Checksum(path)
+		return sum, false, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false, err
+	}
+	if e, found := c.entries[path]; found && e.Size == info.Size() && e.ModTime.Equal(info.ModTime()) {
+		return e.Digest, true, nil
+	}
+
+	sum, err := Checksum(path)
+	if err != nil {
+		return "", false, err
+	}
+	c.entries[path] = cacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Digest:  sum,
+	}
+	return sum, false, nil
+}
+
+// Save writes the cache to disk, merging it with the entries recorded
+// meanwhile by other invocations of kuberlr. The entries of the files that
+// no longer exist are dropped
+func (c *Cache) Save() error {
+	entries := map[string]cacheEntry{}
+	return common.UpdateStateFile(c.Path, &entries, func() {
+		for path, e := range c.entries {
+			entries[path] = e
+		}
+		for path := range entries {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				delete(entries, path)
+			}
+		}
+		c.entries = entries
+	})
+}
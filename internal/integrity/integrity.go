@@ -0,0 +1,102 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// Status is the outcome of the verification of a binary
+type Status string
+
+const (
+	// StatusOK is the status of binaries matching the checksum recorded
+	// at install time
+	StatusOK Status = "ok"
+	// StatusCorrupted is the status of binaries that changed since
+	// their installation
+	StatusCorrupted Status = "corrupted"
+	// StatusUnverifiable is the status of binaries whose checksum has not
+	// been recorded, like the ones not installed by kuberlr
+	StatusUnverifiable Status = "unverifiable"
+)
+
+// Result describes the verification of a binary
+type Result struct {
+	Path     string `json:"path"`
+	Status   Status `json:"status"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Cached is true when the checksum has not been computed again
+	// because the binary didn't change since the last verification
+	Cached bool `json:"cached"`
+}
+
+// Checksum returns the SHA256 checksum of the given file
+func Checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify compares the checksum of the binary with the one recorded inside
+// of its metadata when it has been installed. The checksum is taken from
+// the given cache when the binary didn't change, the cache can be nil
+func Verify(path string, cache *Cache) Result {
+	res := Result{Path: path, Status: StatusUnverifiable}
+
+	metadata, found, err := common.LoadMetadata(path)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if !found || metadata.SHA256 == "" {
+		return res
+	}
+	res.Expected = metadata.SHA256
+
+	actual, cached, err := cache.Checksum(path)
+	if err != nil {
+		res.Status = StatusCorrupted
+		res.Error = err.Error()
+		return res
+	}
+	res.Actual = actual
+	res.Cached = cached
+
+	if actual == metadata.SHA256 {
+		res.Status = StatusOK
+	} else {
+		res.Status = StatusCorrupted
+	}
+	return res
+}
+
+// Summary counts the verification results by status
+type Summary map[Status]int
+
+// Summarize counts the given results by status
+func Summarize(results []Result) Summary {
+	s := Summary{}
+	for _, r := range results {
+		s[r.Status]++
+	}
+	return s
+}
+
+// Failed returns true when some binaries are corrupted
+func (s Summary) Failed() bool {
+	return s[StatusCorrupted] > 0
+}
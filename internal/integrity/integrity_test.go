@@ -0,0 +1,136 @@
+package integrity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-integrity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "kubectl1.20.1")
+	bad := filepath.Join(dir, "kubectl1.20.2")
+	unknown := filepath.Join(dir, "kubectl1.20.3")
+	for _, f := range []string{good, bad, unknown} {
+		if err := ioutil.WriteFile(f, []byte(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{good, bad} {
+		sum, err := Checksum(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := common.SaveMetadata(f, common.Metadata{SHA256: sum}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(bad, []byte("tampered"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	results := []Result{Verify(good, nil), Verify(bad, nil), Verify(unknown, nil)}
+	expected := []Status{StatusOK, StatusCorrupted, StatusUnverifiable}
+	for i, r := range results {
+		if r.Status != expected[i] {
+			t.Errorf("%s: got %s instead of %s", r.Path, r.Status, expected[i])
+		}
+	}
+
+	summary := Summarize(results)
+	if !summary.Failed() {
+		t.Error("Summary should report a failure")
+	}
+	if summary[StatusOK] != 1 || summary[StatusCorrupted] != 1 || summary[StatusUnverifiable] != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
+
+func TestVerifyWithCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-integrity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "kubectl1.20.1")
+	if err := ioutil.WriteFile(binary, []byte("kubectl"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := Checksum(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := common.SaveMetadata(binary, common.Metadata{SHA256: sum}); err != nil {
+		t.Fatal(err)
+	}
+
+	cachePath := filepath.Join(dir, "cache.json")
+	cache := LoadCache(cachePath)
+	if r := Verify(binary, cache); r.Status != StatusOK || r.Cached {
+		t.Errorf("Unexpected result %+v", r)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cache = LoadCache(cachePath)
+	if r := Verify(binary, cache); r.Status != StatusOK || !r.Cached {
+		t.Errorf("Expected cached result, got %+v", r)
+	}
+
+	// the binary changes: the checksum is computed again
+	if err := ioutil.WriteFile(binary, []byte("tampered kubectl"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if r := Verify(binary, cache); r.Status != StatusCorrupted || r.Cached {
+		t.Errorf("Expected corrupted binary, got %+v", r)
+	}
+}
+
+func TestCacheSaveMerges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-integrity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	first := filepath.Join(dir, "kubectl1.20.1")
+	second := filepath.Join(dir, "kubectl1.20.2")
+	for _, f := range []string{first, second} {
+		if err := ioutil.WriteFile(f, []byte(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// two invocations of kuberlr loading the cache at the same time
+	cachePath := filepath.Join(dir, "cache.json")
+	c1 := LoadCache(cachePath)
+	c2 := LoadCache(cachePath)
+	if _, _, err := c1.Checksum(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c2.Checksum(second); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Cache{c1, c2} {
+		if err := c.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache := LoadCache(cachePath)
+	for _, f := range []string{first, second} {
+		if _, cached, err := cache.Checksum(f); err != nil || !cached {
+			t.Errorf("%s: expected cached checksum, got %v %v", f, cached, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package krew
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/blang/semver/v4"
+	"sigs.k8s.io/yaml"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// KubectlVersionsAnnotation can be added to the manifest of a krew plugin
+// to declare the range of kubectl versions it supports, using the syntax
+// of semver ranges (e.g. ">=1.20.0 <1.25.0")
+const KubectlVersionsAnnotation = "kuberlr.io/kubectl-versions"
+
+// Plugin describes a plugin installed via krew
+type Plugin struct {
+	Name    string
+	Version string
+	// KubectlVersions is the range of kubectl versions supported by the
+	// plugin, empty when unknown
+	KubectlVersions string
+}
+
+// receipt holds the fields of krew receipts kuberlr cares about
+type receipt struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Version string `json:"version"`
+	} `json:"spec"`
+}
+
+// Root returns the directory where krew installs its plugins
+func Root() string {
+	if root := os.Getenv("KREW_ROOT"); root != "" {
+		return root
+	}
+	return filepath.Join(common.HomeDir(), ".krew")
+}
+
+// FindPlugin looks for the receipt of the given plugin inside of krew's
+// root directory. The boolean is false when the plugin is not installed
+func FindPlugin(root, name string) (Plugin, bool, error) {
+	data, err := ioutil.ReadFile(filepath.Join(root, "receipts", name+".yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Plugin{}, false, nil
+		}
+		return Plugin{}, false, err
+	}
+
+	var r receipt
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return Plugin{}, false, fmt.Errorf("Cannot parse krew receipt of %s: %v", name, err)
+	}
+
+	return Plugin{
+		Name:            r.Metadata.Name,
+		Version:         r.Spec.Version,
+		KubectlVersions: r.Metadata.Annotations[KubectlVersionsAnnotation],
+	}, true, nil
+}
+
+// CheckCompatibility returns an error when the plugin doesn't support the
+// given version of kubectl. The ranges given via the overrides parameter,
+// keyed by plugin name, take precedence over the ones declared by
+// the plugins
+func CheckCompatibility(p Plugin, overrides map[string]string, version semver.Version) error {
+	rangeRule := p.KubectlVersions
+	if r, found := overrides[p.Name]; found {
+		rangeRule = r
+	}
+	if rangeRule == "" {
+		return nil
+	}
+
+	validRange, err := semver.ParseRange(rangeRule)
+	if err != nil {
+		return fmt.Errorf("Invalid kubectl version range %q for krew plugin %s: %v", rangeRule, p.Name, err)
+	}
+	if !validRange(version) {
+		return fmt.Errorf("krew plugin %s supports kubectl %s, but kubectl %s is going to be used", p.Name, rangeRule, version)
+	}
+	return nil
+}
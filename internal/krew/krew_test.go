@@ -0,0 +1,71 @@
+package krew
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+const fakeReceipt = `apiVersion: krew.googlecontainertools.github.com/v1alpha2
+kind: Plugin
+metadata:
+  name: foo
+  annotations:
+    kuberlr.io/kubectl-versions: ">=1.20.0 <1.23.0"
+spec:
+  version: v0.1.0
+`
+
+func TestFindPlugin(t *testing.T) {
+	root, err := ioutil.TempDir("", "kuberlr-krew")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.MkdirAll(filepath.Join(root, "receipts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "receipts", "foo.yaml"), []byte(fakeReceipt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, found, err := FindPlugin(root, "bar")
+	if err != nil || found {
+		t.Errorf("Plugin bar should not be found: %v", err)
+	}
+
+	p, found, err := FindPlugin(root, "foo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !found {
+		t.Fatal("Plugin foo not found")
+	}
+	if p.Version != "v0.1.0" || p.KubectlVersions != ">=1.20.0 <1.23.0" {
+		t.Errorf("Unexpected plugin %+v", p)
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	p := Plugin{Name: "foo", KubectlVersions: ">=1.20.0 <1.23.0"}
+
+	if err := CheckCompatibility(p, nil, semver.MustParse("1.21.3")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := CheckCompatibility(p, nil, semver.MustParse("1.24.0")); err == nil {
+		t.Error("Expected incompatibility to be detected")
+	}
+
+	overrides := map[string]string{"foo": ">=1.24.0"}
+	if err := CheckCompatibility(p, overrides, semver.MustParse("1.24.0")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err := CheckCompatibility(Plugin{Name: "bar"}, nil, semver.MustParse("1.24.0")); err != nil {
+		t.Errorf("Plugins without range should always be compatible: %v", err)
+	}
+}
@@ -6,11 +6,14 @@ import (
 
 // KubeAPI helps interactions with kubernetes API server
 type KubeAPI struct {
+	// Context is the kubeconfig context to connect to, the current
+	// context is used when empty
+	Context string
 }
 
 // Version returns the version of the remote kubernetes API server
 func (k *KubeAPI) Version(timeout int64) (semver.Version, error) {
-	client, err := createKubeClient(timeout)
+	client, err := createKubeClient(k.Context, timeout)
 	if err != nil {
 		return semver.Version{}, err
 	}
@@ -19,5 +22,41 @@ func (k *KubeAPI) Version(timeout int64) (semver.Version, error) {
 	if err != nil {
 		return semver.Version{}, err
 	}
-	return semver.ParseTolerant(v.GitVersion)
+	return parseServerVersion(v.GitVersion)
+}
+
+// upstreamPrereleases are the prerelease identifiers used by kubernetes
+var upstreamPrereleases = []string{"alpha", "beta", "rc"}
+
+// parseServerVersion parses the version reported by the API server. Vendors
+// append their own identifiers to the upstream version, like
+// "v1.28.9-eks-036c24b", "v1.27.8-gke.1067004" or "v1.26.3+k3s1": they are
+// dropped, since there's no kubectl release with such a version. The
+// prereleases of kubernetes, like "v1.30.0-rc.1", are kept
+func parseServerVersion(gitVersion string) (semver.Version, error) {
+	v, err := semver.ParseTolerant(gitVersion)
+	if err != nil {
+		return v, err
+	}
+
+	v.Build = nil
+	if len(v.Pre) > 0 && !v.Pre[0].IsNum {
+		for _, pre := range upstreamPrereleases {
+			if v.Pre[0].VersionStr == pre {
+				return v, nil
+			}
+		}
+	}
+	v.Pre = nil
+	return v, nil
+}
+
+// Server returns the address of the remote kubernetes API server, which
+// isn't contacted. An empty string is returned when it cannot be found
+func (k *KubeAPI) Server() string {
+	restConfig, err := clientConfig(k.Context).ClientConfig()
+	if err != nil {
+		return ""
+	}
+	return restConfig.Host
 }
@@ -0,0 +1,29 @@
+package kubehelper
+
+import (
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	for gitVersion, expected := range map[string]string{
+		"v1.28.9":                 "1.28.9",
+		"v1.28.9-eks-036c24b":     "1.28.9",
+		"v1.27.8-gke.1067004":     "1.27.8",
+		"v1.26.3+k3s1":            "1.26.3",
+		"v1.27.6+b49f9d1":         "1.27.6",
+		"v1.29.1-aliyun.1":        "1.29.1",
+		"v1.30.0-rc.1":            "1.30.0-rc.1",
+		"v1.31.0-alpha.2+a1b2c3d": "1.31.0-alpha.2",
+	} {
+		v, err := parseServerVersion(gitVersion)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", gitVersion, err)
+		} else if v.String() != expected {
+			t.Errorf("%s: got %s instead of %s", gitVersion, v, expected)
+		}
+	}
+
+	if _, err := parseServerVersion("unknown"); err == nil {
+		t.Error("Expected invalid versions to be refused")
+	}
+}
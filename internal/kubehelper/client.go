@@ -1,61 +1,197 @@
 package kubehelper
 
 import (
+	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
-	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/flavio/kuberlr/internal/common"
 )
 
-func createKubeClient(timeout int64) (*kubernetes.Clientset, error) {
-	var cliKubeconfig string
-	for i := 1; i < len(os.Args); i++ {
-		if i+1 < len(os.Args) && os.Args[i] == "--kubeconfig" {
-			cliKubeconfig = os.Args[i+1]
-			// don't break here; in case there are multiple --kubeconfig options,
-			// the last one takes precedence
-			continue
-		}
-		if strings.HasPrefix(os.Args[i], "--kubeconfig=") {
-			cliKubeconfig = strings.TrimPrefix(os.Args[i], "--kubeconfig=")
-			continue
-		}
-		if os.Args[i] == "--" {
+// probeTimeouts limits the requests made against the API server
+var probeTimeouts common.Timeouts
+
+// SetProbeTimeouts sets the timeouts of the requests made to find the
+// version of the API server. When the overall timeout is zero, the one
+// given to KubeAPI.Version is used
+func SetProbeTimeouts(t common.Timeouts) {
+	probeTimeouts = t
+}
+
+// kubeconfigFromArgs returns the value of the `--kubeconfig` flag given
+// to kubectl, if any
+func kubeconfigFromArgs() string {
+	return flagFromArgs("kubeconfig", "")
+}
+
+// contextFromArgs returns the value of the `--context` flag given to
+// kubectl, if any
+func contextFromArgs() string {
+	return flagFromArgs("context", "")
+}
+
+// clusterFromArgs returns the value of the `--cluster` flag given to
+// kubectl, if any
+func clusterFromArgs() string {
+	return flagFromArgs("cluster", "")
+}
+
+// serverFromArgs returns the value of the `--server` flag, or of its `-s`
+// short form, given to kubectl, if any
+func serverFromArgs() string {
+	return flagFromArgs("server", "s")
+}
+
+// flagFromArgs returns the value of the given kubectl global flag, the
+// short form is ignored when empty. Only the flags preceding the kubectl
+// command are considered: the ones following it belong to the command,
+// like `kubectl config set-cluster --server=...`
+func flagFromArgs(name, short string) string {
+	args := os.Args[1:]
+	if end := common.CommandIndex(args); end >= 0 {
+		args = args[:end]
+	}
+
+	forms := []string{"--" + name}
+	if short != "" {
+		forms = append(forms, "-"+short)
+	}
+
+	var value string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
 			break
 		}
+		for _, form := range forms {
+			if i+1 < len(args) && args[i] == form {
+				value = args[i+1]
+				// don't break here; in case the flag is given multiple
+				// times, the last one takes precedence
+				i++
+				break
+			}
+			if strings.HasPrefix(args[i], form+"=") {
+				value = strings.TrimPrefix(args[i], form+"=")
+				break
+			}
+		}
 	}
 
-	var restConfig *restclient.Config
-	var err error
+	return value
+}
+
+// clientConfig returns the configuration used to connect to the given
+// context. When the context is empty the one given via the `--context`
+// flag is used, falling back to the current one. The cluster and the
+// server given via the `--cluster` and `--server` flags take precedence
+// over the ones of the context, like kubectl does
+func clientConfig(context string) clientcmd.ClientConfig {
+	if context == "" {
+		context = contextFromArgs()
+	}
 
-	if cliKubeconfig != "" {
+	// Let the NewDefaultClientConfigLoadingRules do the heavy lifting like
+	// parsing the KUBECONFIG value
+	// TIL: it's possible to specify multiple kubeconfig files via KUBECONFIG
+	// For example: `KUBECONFIG=~/cluster1.yaml:~/cluster2.yaml`
+	// See https://github.com/kubernetes/kubernetes/issues/46381#issuecomment-303926031
+	//
+	// The NewDefaultClientConfigLoadingRules function has all the logic built
+	// inside of it that handles this special case.
+	clientConfLoadingrules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if cliKubeconfig := kubeconfigFromArgs(); cliKubeconfig != "" {
 		// give precedence to --kubeconfig flag
-		restConfig, err = clientcmd.BuildConfigFromFlags("", cliKubeconfig)
-	} else {
-		// Let the NewDefaultClientConfigLoadingRules do the heavy lifting like
-		// parsing the KUBECONFIG value
-		// TIL: it's possible to specify multiple kubeconfig files via KUBECONFIG
-		// For example: `KUBECONFIG=~/cluster1.yaml:~/cluster2.yaml`
-		// See https://github.com/kubernetes/kubernetes/issues/46381#issuecomment-303926031
-		//
-		// The NewDefaultClientConfigLoadingRules function has all the logic built
-		// inside of it that handles this special case.
-		clientConfLoadingrules := clientcmd.NewDefaultClientConfigLoadingRules()
-
-		restConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-			clientConfLoadingrules,
-			&clientcmd.ConfigOverrides{}).ClientConfig()
+		clientConfLoadingrules.ExplicitPath = cliKubeconfig
 	}
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	overrides.Context.Cluster = clusterFromArgs()
+	overrides.ClusterInfo.Server = serverFromArgs()
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientConfLoadingrules,
+		overrides)
+}
+
+// CurrentContext returns the name of the kubernetes context in use, which
+// is the one given via the `--context` flag or the current one of the
+// kubeconfig. An empty string is returned when it cannot be determined
+func CurrentContext() string {
+	if context := contextFromArgs(); context != "" {
+		return context
+	}
+	rawConfig, err := clientConfig("").RawConfig()
+	if err != nil {
+		return ""
+	}
+	return rawConfig.CurrentContext
+}
+
+// Contexts returns the names of all the contexts defined inside of
+// the kubeconfig, sorted alphabetically
+func Contexts() ([]string, error) {
+	rawConfig, err := clientConfig("").RawConfig()
+	if err != nil {
+		return []string{}, err
+	}
+
+	contexts := []string{}
+	for name := range rawConfig.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+
+	return contexts, nil
+}
+
+// Servers returns the address of the API server of each context defined
+// inside of the kubeconfig
+func Servers() map[string]string {
+	servers := map[string]string{}
+
+	rawConfig, err := clientConfig("").RawConfig()
+	if err != nil {
+		return servers
+	}
+	for name, context := range rawConfig.Contexts {
+		if cluster, found := rawConfig.Clusters[context.Cluster]; found {
+			servers[name] = cluster.Server
+		}
+	}
+	return servers
+}
+
+func createKubeClient(context string, timeout int64) (*kubernetes.Clientset, error) {
+	restConfig, err := clientConfig(context).ClientConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	// lower the timeout value
 	restConfig.Timeout = time.Duration(timeout) * time.Second
+	if probeTimeouts.Overall != 0 {
+		restConfig.Timeout = probeTimeouts.Overall
+	}
+	restConfig.WrapTransport = wrapProbeTransport
 
 	// create the clientset
 	return kubernetes.NewForConfig(restConfig)
 }
+
+// wrapProbeTransport applies the probe timeouts to the transport built
+// by client-go, which is shared with the other clients and must not
+// be changed in place
+func wrapProbeTransport(rt http.RoundTripper) http.RoundTripper {
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return rt
+	}
+	transport = transport.Clone()
+	probeTimeouts.Apply(transport)
+	return transport
+}
@@ -0,0 +1,82 @@
+package kubehelper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlagFromArgs(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"kubectl", "get", "pods"}, ""},
+		{[]string{"kubectl", "--context", "prod", "get", "pods"}, "prod"},
+		{[]string{"kubectl", "--context=dev", "--context", "prod", "get", "pods"}, "prod"},
+		{[]string{"kubectl", "-n", "--context", "get", "pods"}, ""},
+		{[]string{"kubectl", "config", "set-context", "--context", "prod"}, ""},
+		{[]string{"kubectl", "exec", "pod", "--", "tool", "--context", "prod"}, ""},
+	}
+	for _, test := range tests {
+		os.Args = test.args
+		if actual := contextFromArgs(); actual != test.expected {
+			t.Errorf("%v: got %q instead of %q", test.args, actual, test.expected)
+		}
+	}
+}
+
+func TestServerHonorsFlags(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+
+	dir, err := ioutil.TempDir("", "kuberlr-kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kubeconfig := filepath.Join(dir, "config")
+	data := `apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+- name: staging
+  cluster:
+    server: https://staging.example.com
+contexts:
+- name: prod
+  context:
+    cluster: prod
+- name: staging
+  context:
+    cluster: staging
+`
+	if err := ioutil.WriteFile(kubeconfig, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"kubectl", "get", "pods"}, "https://prod.example.com"},
+		{[]string{"kubectl", "--context", "staging", "get", "pods"}, "https://staging.example.com"},
+		{[]string{"kubectl", "--cluster=staging", "get", "pods"}, "https://staging.example.com"},
+		{[]string{"kubectl", "--server", "https://other.example.com", "get", "pods"}, "https://other.example.com"},
+		{[]string{"kubectl", "-s", "https://other.example.com", "get", "pods"}, "https://other.example.com"},
+		{[]string{"kubectl", "-s=https://other.example.com", "get", "pods"}, "https://other.example.com"},
+		{[]string{"kubectl", "config", "set-cluster", "prod", "--server=https://other.example.com"}, "https://prod.example.com"},
+	}
+	for _, test := range tests {
+		os.Args = append([]string{test.args[0], "--kubeconfig", kubeconfig}, test.args[1:]...)
+		if actual := (&KubeAPI{}).Server(); actual != test.expected {
+			t.Errorf("%v: got %q instead of %q", test.args, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package kubehelper
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	clientcmdv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// MinimalKubeconfig returns a self-contained kubeconfig holding only the
+// given context, together with its cluster and its user. The credentials
+// referenced by path are embedded. The kubeconfig is read from the given
+// file, the default locations are used when it's empty. The current
+// context is used when no context is given
+func MinimalKubeconfig(kubeconfig, context string) ([]byte, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+
+	rawConfig, err := rules.Load()
+	if err != nil {
+		return nil, err
+	}
+	if context != "" {
+		rawConfig.CurrentContext = context
+	}
+	if rawConfig.CurrentContext == "" {
+		return nil, fmt.Errorf("No context given and no current context set")
+	}
+	if _, found := rawConfig.Contexts[rawConfig.CurrentContext]; !found {
+		return nil, fmt.Errorf("Context %q not found", rawConfig.CurrentContext)
+	}
+
+	if err := clientcmdapi.MinifyConfig(rawConfig); err != nil {
+		return nil, err
+	}
+	if err := clientcmdapi.FlattenConfig(rawConfig); err != nil {
+		return nil, err
+	}
+
+	// the conversion is done explicitly, the serializer of client-go
+	// relies on reflection tricks broken by recent Go releases
+	var out clientcmdv1.Config
+	if err := clientcmdlatest.Scheme.Convert(rawConfig, &out, nil); err != nil {
+		return nil, err
+	}
+	out.APIVersion = "v1"
+	out.Kind = "Config"
+	return yaml.Marshal(out)
+}
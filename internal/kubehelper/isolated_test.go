@@ -0,0 +1,75 @@
+package kubehelper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const twoContextsKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: staging
+  cluster:
+    server: https://staging.example.com
+    certificate-authority: ca.crt
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+- name: staging
+  context:
+    cluster: staging
+    user: staging
+users:
+- name: dev
+  user:
+    token: dev-token
+- name: staging
+  user:
+    token: staging-token
+`
+
+func TestMinimalKubeconfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kubeconfig := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(kubeconfig, []byte(twoContextsKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "ca.crt"), []byte("fake ca"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := MinimalKubeconfig(kubeconfig, "staging")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CurrentContext != "staging" || len(config.Contexts) != 1 || len(config.Clusters) != 1 || len(config.AuthInfos) != 1 {
+		t.Errorf("Unexpected kubeconfig %+v", config)
+	}
+	cluster := config.Clusters["staging"]
+	if cluster == nil || string(cluster.CertificateAuthorityData) != "fake ca" {
+		t.Errorf("The certificate authority has not been embedded: %+v", cluster)
+	}
+
+	if _, err := MinimalKubeconfig(kubeconfig, "prod"); err == nil {
+		t.Error("Expected an error for an unknown context")
+	}
+}
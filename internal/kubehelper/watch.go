@@ -0,0 +1,246 @@
+package kubehelper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// settleDelay is how long the watcher waits for the burst of events
+// caused by a single update of the kubeconfig to end
+const settleDelay = 100 * time.Millisecond
+
+// KubeconfigFiles returns the kubeconfig files kuberlr reads, in order
+// of precedence
+func KubeconfigFiles() []string {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if cliKubeconfig := kubeconfigFromArgs(); cliKubeconfig != "" {
+		return []string{cliKubeconfig}
+	}
+	return rules.GetLoadingPrecedence()
+}
+
+// ContextWatcher observes the kubeconfig files and reports the changes
+// of the current context, the contexts being added and the contexts
+// whose API server changes. Tools like kubectx change the current context
+// by rewriting the kubeconfig
+type ContextWatcher struct {
+	// Files are the kubeconfig files to observe
+	Files []string
+	// CurrentContext returns the name of the current context
+	CurrentContext func() string
+	// Servers returns the API server of each context
+	Servers func() map[string]string
+}
+
+// NewContextWatcher returns a ContextWatcher observing the kubeconfig
+// files used by kuberlr
+func NewContextWatcher() *ContextWatcher {
+	return &ContextWatcher{
+		Files:          KubeconfigFiles(),
+		CurrentContext: CurrentContext,
+		Servers:        Servers,
+	}
+}
+
+// Watch invokes onChange with the current context right away and then
+// every time the current context changes, a context is added or the
+// API server of a context changes, until stop is closed. onChange runs
+// in the background, one context at a time, the changes happening
+// meanwhile are queued
+func (w *ContextWatcher) Watch(stop <-chan struct{}, onChange func(context string)) error {
+	// the directories are observed because the kubeconfig files might not
+	// exist yet and because they are often replaced instead of being
+	// written in place
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	files := map[string]bool{}
+	for _, f := range w.Files {
+		files[filepath.Clean(f)] = true
+	}
+	watched := map[string]bool{}
+	if err := w.watchDirs(watcher, watched); err != nil {
+		return err
+	}
+
+	d := newDispatcher(onChange)
+	defer d.close()
+
+	last := w.CurrentContext()
+	if last != "" {
+		d.push(last)
+	}
+	servers := w.servers()
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil
+		case err := <-watcher.Errors:
+			return err
+		case event := <-watcher.Events:
+			name := filepath.Clean(event.Name)
+			if event.Op&fsnotify.Create != 0 && w.isParentDir(name) {
+				// a missing directory has been created, the ones
+				// inside of it can be observed now
+				if err := w.watchDirs(watcher, watched); err != nil {
+					return err
+				}
+				settle = time.After(settleDelay)
+			}
+			if files[name] {
+				settle = time.After(settleDelay)
+			}
+			continue
+		case <-settle:
+			settle = nil
+		}
+
+		current := w.CurrentContext()
+		updated := w.servers()
+		if current != "" && (current != last || servers[current] != updated[current]) {
+			d.push(current)
+		}
+		last = current
+
+		// prefetch the kubectl binaries of the other contexts that
+		// have been added or moved to another API server
+		for context, server := range updated {
+			if context != current && servers[context] != server {
+				d.push(context)
+			}
+		}
+		servers = updated
+	}
+}
+
+// watchDirs observes the directory of each kubeconfig file or, when it
+// doesn't exist yet, its closest existing parent
+func (w *ContextWatcher) watchDirs(watcher *fsnotify.Watcher, watched map[string]bool) error {
+	for _, f := range w.Files {
+		dir := filepath.Dir(filepath.Clean(f))
+		for {
+			if _, err := os.Stat(dir); err == nil {
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+		if watched[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+		watched[dir] = true
+	}
+	return nil
+}
+
+// isParentDir returns true when the given path is one of the directories
+// holding a kubeconfig file
+func (w *ContextWatcher) isParentDir(path string) bool {
+	for _, f := range w.Files {
+		dir := filepath.Dir(filepath.Clean(f))
+		if dir == path || strings.HasPrefix(dir, path+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatcher invokes the callback of the watcher in the background, so
+// that a slow callback, like a download, doesn't stop the observation of
+// the kubeconfig files
+type dispatcher struct {
+	onChange func(context string)
+	mutex    sync.Mutex
+	queue    []string
+	wake     chan struct{}
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+func newDispatcher(onChange func(context string)) *dispatcher {
+	d := &dispatcher{
+		onChange: onChange,
+		wake:     make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// push queues the given context, unless it's already waiting
+func (d *dispatcher) push(context string) {
+	d.mutex.Lock()
+	queued := false
+	for _, c := range d.queue {
+		queued = queued || c == context
+	}
+	if !queued {
+		d.queue = append(d.queue, context)
+	}
+	d.mutex.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *dispatcher) pop() (string, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if len(d.queue) == 0 {
+		return "", false
+	}
+	context := d.queue[0]
+	d.queue = d.queue[1:]
+	return context, true
+}
+
+func (d *dispatcher) run() {
+	defer close(d.done)
+	for {
+		select {
+		case <-d.quit:
+			return
+		case <-d.wake:
+		}
+		for {
+			context, found := d.pop()
+			if !found {
+				break
+			}
+			d.onChange(context)
+		}
+	}
+}
+
+// close stops the dispatcher, waiting for the callback in progress
+func (d *dispatcher) close() {
+	close(d.quit)
+	<-d.done
+}
+
+func (w *ContextWatcher) servers() map[string]string {
+	if w.Servers == nil {
+		return map[string]string{}
+	}
+	return w.Servers()
+}
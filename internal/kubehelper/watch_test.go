@@ -0,0 +1,194 @@
+package kubehelper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestContextWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the fake kubeconfig holds the current context on the first line,
+	// followed by one "context=server" line per context
+	kubeconfig := filepath.Join(dir, "config")
+	write := func(contents string) {
+		if err := ioutil.WriteFile(kubeconfig, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func() []string {
+		data, _ := ioutil.ReadFile(kubeconfig)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+	write("dev\ndev=https://dev:6443")
+
+	w := &ContextWatcher{
+		Files: []string{kubeconfig},
+		CurrentContext: func() string {
+			return read()[0]
+		},
+		Servers: func() map[string]string {
+			servers := map[string]string{}
+			for _, line := range read()[1:] {
+				if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+					servers[parts[0]] = parts[1]
+				}
+			}
+			return servers
+		},
+	}
+
+	var mutex sync.Mutex
+	seen := []string{}
+	changes := make(chan struct{}, 10)
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- w.Watch(stop, func(context string) {
+			mutex.Lock()
+			seen = append(seen, context)
+			mutex.Unlock()
+			changes <- struct{}{}
+		})
+	}()
+
+	waitChange := func() {
+		select {
+		case <-changes:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Change not detected")
+		}
+	}
+
+	waitChange()
+
+	// a new context is prefetched
+	write("dev\ndev=https://dev:6443\nprod=https://prod:6443")
+	waitChange()
+
+	// the current context changes
+	write("prod\ndev=https://dev:6443\nprod=https://prod:6443")
+	waitChange()
+
+	// the API server of the current context changes
+	write("prod\ndev=https://dev:6443\nprod=https://prod2:6443")
+	waitChange()
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := []string{"dev", "prod", "prod", "prod"}
+	if strings.Join(seen, ",") != strings.Join(expected, ",") {
+		t.Errorf("Got %v instead of %v", seen, expected)
+	}
+}
+
+func TestContextWatcherMissingDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kubeconfig := filepath.Join(dir, "home", ".kube", "config")
+	w := &ContextWatcher{
+		Files: []string{kubeconfig},
+		CurrentContext: func() string {
+			data, _ := ioutil.ReadFile(kubeconfig)
+			return strings.TrimSpace(string(data))
+		},
+	}
+
+	changes := make(chan string, 10)
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- w.Watch(stop, func(context string) {
+			changes <- context
+		})
+	}()
+
+	// give the watcher the time to start
+	time.Sleep(100 * time.Millisecond)
+	if err := os.MkdirAll(filepath.Dir(kubeconfig), 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := ioutil.WriteFile(kubeconfig, []byte("dev"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case context := <-changes:
+		if context != "dev" {
+			t.Errorf("Got %q instead of dev", context)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("The kubeconfig created inside of a missing directory has not been detected")
+	}
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContextWatcherSlowCallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kubeconfig := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(kubeconfig, []byte("dev"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reads := make(chan string, 10)
+	w := &ContextWatcher{
+		Files: []string{kubeconfig},
+		CurrentContext: func() string {
+			data, _ := ioutil.ReadFile(kubeconfig)
+			reads <- string(data)
+			return string(data)
+		},
+	}
+
+	release := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- w.Watch(stop, func(context string) {
+			<-release
+		})
+	}()
+	<-reads
+
+	// the callback of dev is still running, the change is observed anyway
+	if err := ioutil.WriteFile(kubeconfig, []byte("prod"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reads:
+	case <-time.After(5 * time.Second):
+		t.Error("The watcher is blocked by the callback")
+	}
+
+	close(release)
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
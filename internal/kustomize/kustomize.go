@@ -0,0 +1,133 @@
+package kustomize
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"sigs.k8s.io/yaml"
+)
+
+// VersionAnnotation is the annotation of the kustomization file used to
+// pin the version of kustomize a project is written for
+const VersionAnnotation = "kuberlr.io/kustomize-version"
+
+// embedded maps each minor release of kubectl to the version of
+// kustomize it embeds
+var embedded = map[string]string{
+	"1.14": "2.0.3",
+	"1.15": "2.0.3",
+	"1.16": "2.0.3",
+	"1.17": "2.0.3",
+	"1.18": "2.0.3",
+	"1.19": "2.0.3",
+	"1.20": "2.0.3",
+	"1.21": "4.0.5",
+	"1.22": "4.2.0",
+	"1.23": "4.4.1",
+	"1.24": "4.5.4",
+	"1.25": "4.5.7",
+	"1.26": "4.5.7",
+	"1.27": "5.0.1",
+	"1.28": "5.0.4",
+	"1.29": "5.0.4",
+	"1.30": "5.0.4",
+	"1.31": "5.4.2",
+	"1.32": "5.5.0",
+	"1.33": "5.6.0",
+}
+
+// kustomizationFiles are the names kustomize looks for, in order
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// Embedded returns the version of kustomize embedded by the given version
+// of kubectl. The boolean is false when it's not known
+func Embedded(kubectl semver.Version) (semver.Version, bool) {
+	v, found := embedded[fmt.Sprintf("%d.%d", kubectl.Major, kubectl.Minor)]
+	if !found {
+		return semver.Version{}, false
+	}
+	return semver.MustParse(v), true
+}
+
+// Target returns the directory holding the kustomization used by the
+// given kubectl arguments. The boolean is false when kubectl is not going
+// to use kustomize
+func Target(args []string) (string, bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return "", false
+		case arg == "-k" || arg == "--kustomize":
+			if i+1 < len(args) {
+				return args[i+1], true
+			}
+			return "", false
+		case strings.HasPrefix(arg, "--kustomize="):
+			return strings.TrimPrefix(arg, "--kustomize="), true
+		case strings.HasPrefix(arg, "-k="):
+			return strings.TrimPrefix(arg, "-k="), true
+		case arg == "kustomize":
+			// `kubectl kustomize [dir]`, the current directory is the default
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				return args[i+1], true
+			}
+			return ".", true
+		}
+	}
+	return "", false
+}
+
+// PinnedVersion returns the version of kustomize the kustomization found
+// inside of dir is written for, taken from its VersionAnnotation. The
+// boolean is false when the kustomization doesn't pin any version
+func PinnedVersion(dir string) (semver.Version, bool, error) {
+	for _, name := range kustomizationFiles {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return semver.Version{}, false, err
+		}
+
+		var k struct {
+			Metadata struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal(data, &k); err != nil {
+			return semver.Version{}, false, fmt.Errorf("Cannot parse %s: %v", name, err)
+		}
+		pinned, found := k.Metadata.Annotations[VersionAnnotation]
+		if !found {
+			return semver.Version{}, false, nil
+		}
+		v, err := semver.ParseTolerant(pinned)
+		if err != nil {
+			return semver.Version{}, false, fmt.Errorf("Invalid %s annotation: %v", VersionAnnotation, err)
+		}
+		return v, true, nil
+	}
+	return semver.Version{}, false, nil
+}
+
+// Check returns an error when the pinned version of kustomize differs
+// materially, that is by major or minor version, from the one embedded
+// by the given version of kubectl
+func Check(kubectl, pinned semver.Version) error {
+	e, found := Embedded(kubectl)
+	if !found {
+		return nil
+	}
+	if e.Major == pinned.Major && e.Minor == pinned.Minor {
+		return nil
+	}
+	return fmt.Errorf(
+		"kubectl %s embeds kustomize %s, while kustomize %s is expected",
+		kubectl, e, pinned)
+}
@@ -0,0 +1,82 @@
+package kustomize
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+		found    bool
+	}{
+		{[]string{"get", "pods"}, "", false},
+		{[]string{"apply", "-k", "overlays/prod"}, "overlays/prod", true},
+		{[]string{"apply", "--kustomize=base"}, "base", true},
+		{[]string{"kustomize"}, ".", true},
+		{[]string{"kustomize", "base", "--enable-helm"}, "base", true},
+		{[]string{"exec", "pod", "--", "kustomize"}, "", false},
+	}
+	for _, test := range tests {
+		dir, found := Target(test.args)
+		if dir != test.expected || found != test.found {
+			t.Errorf("%v: got %q %v instead of %q %v", test.args, dir, found, test.expected, test.found)
+		}
+	}
+}
+
+func TestPinnedVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-kustomize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, found, err := PinnedVersion(dir); err != nil || found {
+		t.Errorf("Expected no pinned version, got %v %v", found, err)
+	}
+
+	kustomization := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+metadata:
+  annotations:
+    kuberlr.io/kustomize-version: v4.5.7
+resources:
+- deployment.yaml
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(kustomization), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v, found, err := PinnedVersion(dir)
+	if err != nil || !found {
+		t.Fatalf("Expected pinned version, got %v %v", found, err)
+	}
+	if v.String() != "4.5.7" {
+		t.Errorf("Got %s instead of 4.5.7", v)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		kubectl, pinned string
+		ok              bool
+	}{
+		{"1.25.4", "4.5.7", true},
+		{"1.26.0", "4.5.2", true},
+		{"1.27.3", "4.5.7", false},
+		{"1.20.1", "4.0.5", false},
+		{"1.10.0", "4.0.5", true},
+	}
+	for _, test := range tests {
+		err := Check(semver.MustParse(test.kubectl), semver.MustParse(test.pinned))
+		if (err == nil) != test.ok {
+			t.Errorf("kubectl %s, kustomize %s: unexpected result %v", test.kubectl, test.pinned, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package linker
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// unsafeAliasChars are the characters not allowed inside of the name of
+// the scripts, like the "/" and ":" of the ARNs naming the EKS contexts
+var unsafeAliasChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Alias describes a wrapper script bound to a kubernetes context
+type Alias struct {
+	// Name is the name of the script, e.g. "kubectl-prod"
+	Name string
+	// Context is the kubernetes context used by the script
+	Context string
+	// Kuberlr is the path to the kuberlr binary
+	Kuberlr string
+	// Kubectl is the path to the kubectl binary used by the script. When
+	// empty, kuberlr picks the binary compatible with the context
+	Kubectl string
+}
+
+// AliasName returns the name of the script bound to the given context, the
+// characters that cannot be part of a filename are replaced by "-"
+func AliasName(prefix, context string) string {
+	return prefix + strings.Trim(unsafeAliasChars.ReplaceAllString(context, "-"), "-")
+}
+
+// shellQuote quotes the given string for sh, a single quote is the only
+// character that has to be escaped inside of single quotes
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// DefaultAliasTemplate returns the template used to generate the
+// wrapper scripts on the current operating system
+func DefaultAliasTemplate() string {
+	return aliasTemplate
+}
+
+// Path returns where the script is created inside of dir
+func (a Alias) Path(dir string) string {
+	return filepath.Join(dir, a.Name+shimExt)
+}
+
+// Write renders the given template and saves the script inside of dir, the
+// template can quote the fields for sh via the shellQuote function.
+// Existing files are replaced only when overwrite is true
+func (a Alias) Write(dir, tmpl string, overwrite bool) error {
+	t, err := template.New(a.Name).Funcs(template.FuncMap{"shellQuote": shellQuote}).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("Invalid alias template: %v", err)
+	}
+
+	var contents bytes.Buffer
+	data := struct {
+		Alias
+		ShimFlag string
+	}{a, ShimFlag}
+	if err := t.Execute(&contents, data); err != nil {
+		return fmt.Errorf("Cannot render alias %s: %v", a.Name, err)
+	}
+
+	dst := a.Path(dir)
+	if _, err := os.Lstat(dst); err == nil && !overwrite {
+		return fmt.Errorf("%s already exists", dst)
+	}
+	return ioutil.WriteFile(dst, contents.Bytes(), 0755)
+}
@@ -0,0 +1,111 @@
+package linker
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+// Strategy describes how the kubectl entry pointing to kuberlr is created
+type Strategy string
+
+const (
+	// Auto tries the strategies known to work on the current system, in
+	// order of preference
+	Auto Strategy = "auto"
+	// Symlink creates a symbolic link, on Windows this requires either
+	// developer mode or administrative rights
+	Symlink Strategy = "symlink"
+	// Hardlink creates a hard link, this requires kuberlr and the link to
+	// be on the same filesystem
+	Hardlink Strategy = "hardlink"
+	// Copy creates a copy of kuberlr, this must be repeated when kuberlr
+	// is upgraded
+	Copy Strategy = "copy"
+	// Shim creates a small script invoking kuberlr (a .cmd file on Windows)
+	Shim Strategy = "shim"
+)
+
+// ShimFlag is passed by the shims to kuberlr to make it behave like kubectl
+const ShimFlag = "--kubectl-shim"
+
+// ParseStrategy returns the Strategy with the given name
+func ParseStrategy(name string) (Strategy, error) {
+	s := Strategy(strings.ToLower(name))
+	switch s {
+	case Auto, Symlink, Hardlink, Copy, Shim:
+		return s, nil
+	default:
+		return "", fmt.Errorf("Unknown link strategy: %s", name)
+	}
+}
+
+// Destination returns the path of the kubectl entry created inside
+// of dir using the given strategy
+func Destination(dir string, s Strategy) string {
+	if s == Shim {
+		return filepath.Join(dir, "kubectl"+shimExt)
+	}
+	return filepath.Join(dir, "kubectl"+osexec.Ext)
+}
+
+// Create creates inside of dir a kubectl entry pointing to the kuberlr binary
+// found at target. The strategy that has been used is returned
+func Create(target, dir string, s Strategy) (Strategy, error) {
+	if s != Auto {
+		return s, create(target, Destination(dir, s), s)
+	}
+
+	var errs []string
+	for _, candidate := range autoStrategies {
+		err := create(target, Destination(dir, candidate), candidate)
+		if err == nil {
+			return candidate, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", candidate, err))
+	}
+	return "", fmt.Errorf("Cannot link kubectl to kuberlr: %s", strings.Join(errs, ", "))
+}
+
+func create(target, dst string, s Strategy) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+
+	switch s {
+	case Symlink:
+		return os.Symlink(target, dst)
+	case Hardlink:
+		return os.Link(target, dst)
+	case Copy:
+		return copyFile(target, dst)
+	case Shim:
+		return ioutil.WriteFile(dst, []byte(shimContents(target)), 0755)
+	default:
+		return fmt.Errorf("Unknown link strategy: %s", s)
+	}
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
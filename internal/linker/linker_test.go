@@ -0,0 +1,183 @@
+package linker
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "kuberlr")
+	if err := ioutil.WriteFile(target, []byte("fake kuberlr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []Strategy{Hardlink, Copy, Shim} {
+		linkDir := filepath.Join(dir, string(s))
+		if err := os.Mkdir(linkDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		used, err := Create(target, linkDir, s)
+		if err != nil {
+			t.Errorf("Unexpected error with strategy %s: %v", s, err)
+			continue
+		}
+		if used != s {
+			t.Errorf("Got strategy %s instead of %s", used, s)
+		}
+
+		data, err := ioutil.ReadFile(Destination(linkDir, s))
+		if err != nil {
+			t.Errorf("Cannot read link created with strategy %s: %v", s, err)
+			continue
+		}
+		if s == Shim {
+			if !strings.Contains(string(data), ShimFlag) {
+				t.Errorf("Unexpected shim contents: %s", data)
+			}
+		} else if string(data) != "fake kuberlr" {
+			t.Errorf("Unexpected contents with strategy %s: %s", s, data)
+		}
+
+		if _, err := Create(target, linkDir, s); err == nil {
+			t.Errorf("Strategy %s should not overwrite existing files", s)
+		}
+	}
+
+	used, err := Create(target, filepath.Join(dir, "auto-dir-missing"), Auto)
+	if err == nil {
+		t.Errorf("Expected failure, got strategy %s", used)
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	if s, err := ParseStrategy("HardLink"); err != nil || s != Hardlink {
+		t.Errorf("Got %s, %v", s, err)
+	}
+	if _, err := ParseStrategy("junction"); err == nil {
+		t.Error("Expected unknown strategy to be refused")
+	}
+}
+
+func TestAliasWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := Alias{Name: "kubectl-prod", Context: "prod", Kuberlr: "/usr/bin/kuberlr"}
+	if err := a.Write(dir, DefaultAliasTemplate(), false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(a.Path(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), ShimFlag) || !strings.Contains(string(data), "prod") {
+		t.Errorf("Unexpected alias contents: %s", data)
+	}
+
+	if err := a.Write(dir, DefaultAliasTemplate(), false); err == nil {
+		t.Error("Existing aliases should not be overwritten")
+	}
+
+	a.Kubectl = "/home/user/.kuberlr/linux-amd64/kubectl1.20.4"
+	if err := a.Write(dir, "{{.Kubectl}} {{.Context}}", true); err != nil {
+		t.Fatal(err)
+	}
+	data, err = ioutil.ReadFile(a.Path(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != a.Kubectl+" prod" {
+		t.Errorf("Unexpected alias contents: %s", data)
+	}
+
+	if err := a.Write(dir, "{{.Unknown", true); err == nil {
+		t.Error("Expected invalid template to be rejected")
+	}
+}
+
+func TestAliasName(t *testing.T) {
+	tests := map[string]string{
+		"prod": "kubectl-prod",
+		"arn:aws:eks:eu-west-1:123456789012:cluster/prod": "kubectl-arn-aws-eks-eu-west-1-123456789012-cluster-prod",
+		"gke_project_europe-west1_prod":                   "kubectl-gke_project_europe-west1_prod",
+		"admin@prod ":                                     "kubectl-admin-prod",
+	}
+	for context, expected := range tests {
+		if actual := AliasName("kubectl-", context); actual != expected {
+			t.Errorf("%q: got %q instead of %q", context, actual, expected)
+		}
+	}
+}
+
+func TestAliasQuotesContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the default template of windows is a batch file")
+	}
+
+	dir, err := ioutil.TempDir("", "kuberlr-alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the fake kubectl prints the context it has been given
+	kubectl := filepath.Join(dir, "kubectl")
+	if err := ioutil.WriteFile(kubectl, []byte("#!/bin/sh\nprintf '%s' \"$2\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	context := "arn:aws:eks:eu-west-1:123456789012:cluster/it's $prod"
+	a := Alias{Name: AliasName("kubectl-", context), Context: context, Kubectl: kubectl}
+	if err := a.Write(dir, DefaultAliasTemplate(), false); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(a.Path(dir)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != context {
+		t.Errorf("Got the context %q instead of %q", out, context)
+	}
+}
+
+func TestShimQuotesTarget(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the shim of windows is a batch file")
+	}
+
+	dir, err := ioutil.TempDir("", "kuberlr-shim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the fake kuberlr prints the flag it has been given
+	target := filepath.Join(dir, "it's $kuberlr")
+	if err := ioutil.WriteFile(target, []byte("#!/bin/sh\nprintf '%s' \"$1\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Create(target, dir, Shim); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(Destination(dir, Shim)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != ShimFlag {
+		t.Errorf("Got %q instead of %q", out, ShimFlag)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package linker
+
+import "fmt"
+
+const shimExt = ""
+
+// autoStrategies lists the strategies attempted by Auto
+var autoStrategies = []Strategy{Symlink, Hardlink, Copy}
+
+func shimContents(target string) string {
+	return fmt.Sprintf("#!/bin/sh\nexec %s %s \"$@\"\n", shellQuote(target), ShimFlag)
+}
+
+const aliasTemplate = `#!/bin/sh
+# generated by kuberlr alias
+{{- if .Kubectl}}
+exec {{shellQuote .Kubectl}} --context {{shellQuote .Context}} "$@"
+{{- else}}
+exec {{shellQuote .Kuberlr}} {{.ShimFlag}} --context {{shellQuote .Context}} "$@"
+{{- end}}
+`
@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package linker
+
+import "fmt"
+
+const shimExt = ".cmd"
+
+// autoStrategies lists the strategies attempted by Auto, symlinks require
+// special privileges on windows hence the shim is kept as the last resort
+var autoStrategies = []Strategy{Symlink, Hardlink, Copy, Shim}
+
+func shimContents(target string) string {
+	return fmt.Sprintf("@echo off\r\n\"%s\" %s %%*\r\n", target, ShimFlag)
+}
+
+const aliasTemplate = "@echo off\r\n" +
+	"rem generated by kuberlr alias\r\n" +
+	"{{if .Kubectl}}\"{{.Kubectl}}\" --context \"{{.Context}}\" %*" +
+	"{{else}}\"{{.Kuberlr}}\" {{.ShimFlag}} --context \"{{.Context}}\" %*{{end}}\r\n"
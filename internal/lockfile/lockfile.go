@@ -0,0 +1,119 @@
+package lockfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"sigs.k8s.io/yaml"
+
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/integrity"
+)
+
+// Entry describes a kubectl binary listed inside of a lockfile
+type Entry struct {
+	// Version is the version of kubectl
+	Version string `json:"version"`
+	// SHA256 is the expected checksum of the binary, it's optional
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Lockfile lists the kubectl binaries a team agreed upon. It's usually
+// committed inside of a repository, e.g.:
+//
+//	binaries:
+//	- version: 1.20.4
+//	  sha256: 3f4b52a8072013e4cd34c9ea07e3c4c4e0350b34bb3e2d8f19d8ddf12c5b2a4a
+//	- version: 1.19.8
+type Lockfile struct {
+	Binaries []Entry `json:"binaries"`
+}
+
+// Load reads and validates the lockfile saved at the given path
+func Load(path string) (Lockfile, error) {
+	var l Lockfile
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	if err := yaml.UnmarshalStrict(data, &l); err != nil {
+		return l, fmt.Errorf("Cannot parse lockfile %s: %v", path, err)
+	}
+	for _, e := range l.Binaries {
+		if _, err := e.ParsedVersion(); err != nil {
+			return l, fmt.Errorf("Invalid version %q inside of lockfile %s: %v", e.Version, path, err)
+		}
+	}
+	return l, nil
+}
+
+// ParsedVersion returns the version of kubectl described by the entry
+func (e Entry) ParsedVersion() (semver.Version, error) {
+	return semver.ParseTolerant(e.Version)
+}
+
+// Matches returns true when the checksum of the given binary is the one
+// recorded by the lockfile, or when the lockfile doesn't record any
+func (e Entry) Matches(path string) (bool, error) {
+	if e.SHA256 == "" {
+		return true, nil
+	}
+	sum, err := integrity.Checksum(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(sum, e.SHA256), nil
+}
+
+// Plan describes what has to be done to make a store hold exactly the
+// binaries listed by a lockfile
+type Plan struct {
+	// Install are the versions that are missing, or whose binary doesn't
+	// have the checksum recorded by the lockfile
+	Install []Entry
+	// Unlisted are the binaries of the store not listed by the lockfile
+	Unlisted finder.KubectlBinaries
+}
+
+// PlanFor compares the lockfile with the binaries currently available
+// inside of a store
+func (l Lockfile) PlanFor(installed finder.KubectlBinaries) (Plan, error) {
+	plan := Plan{Install: []Entry{}, Unlisted: finder.KubectlBinaries{}}
+
+	byVersion := map[string]finder.KubectlBinary{}
+	for _, b := range installed {
+		byVersion[b.Version.String()] = b
+	}
+
+	listed := map[string]bool{}
+	for _, e := range l.Binaries {
+		v, err := e.ParsedVersion()
+		if err != nil {
+			return plan, err
+		}
+		listed[v.String()] = true
+
+		b, found := byVersion[v.String()]
+		if !found {
+			plan.Install = append(plan.Install, e)
+			continue
+		}
+		matches, err := e.Matches(b.Path)
+		if err != nil {
+			return plan, err
+		}
+		if !matches {
+			plan.Install = append(plan.Install, e)
+		}
+	}
+
+	for _, b := range installed {
+		if !listed[b.Version.String()] {
+			plan.Unlisted = append(plan.Unlisted, b)
+		}
+	}
+	return plan, nil
+}
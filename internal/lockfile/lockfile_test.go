@@ -0,0 +1,90 @@
+package lockfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/integrity"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-lockfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "kuberlr.lock")
+	contents := `
+binaries:
+- version: v1.20.4
+  sha256: abc
+- version: 1.19.8
+`
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Binaries) != 2 || l.Binaries[0].SHA256 != "abc" || l.Binaries[1].Version != "1.19.8" {
+		t.Errorf("Unexpected lockfile %+v", l)
+	}
+
+	invalid := map[string]string{
+		"bad version": "binaries:\n- version: latest\n",
+		"unknown key": "binaries:\n- version: 1.20.4\n  digest: abc\n",
+	}
+	for name, contents := range invalid {
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPlanFor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-lockfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	installed := finder.KubectlBinaries{}
+	for _, v := range []string{"1.18.2", "1.19.8", "1.20.4"} {
+		path := filepath.Join(dir, "kubectl"+v)
+		if err := ioutil.WriteFile(path, []byte(v), 0755); err != nil {
+			t.Fatal(err)
+		}
+		installed = append(installed, finder.KubectlBinary{Path: path, Version: semver.MustParse(v)})
+	}
+	sum, err := integrity.Checksum(filepath.Join(dir, "kubectl1.20.4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := Lockfile{Binaries: []Entry{
+		{Version: "1.20.4", SHA256: sum},
+		{Version: "1.19.8", SHA256: "0000"},
+		{Version: "1.21.0"},
+	}}
+	plan, err := l.PlanFor(installed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Install) != 2 || plan.Install[0].Version != "1.19.8" || plan.Install[1].Version != "1.21.0" {
+		t.Errorf("Unexpected versions to install: %+v", plan.Install)
+	}
+	if len(plan.Unlisted) != 1 || plan.Unlisted[0].Version.String() != "1.18.2" {
+		t.Errorf("Unexpected unlisted binaries: %+v", plan.Unlisted)
+	}
+}
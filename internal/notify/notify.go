@@ -0,0 +1,17 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Desktop shows a desktop notification with the given title and message.
+// An error is returned when the tool used to show notifications on the
+// current platform is not available
+func Desktop(title, message string) error {
+	name, args := command(title, message)
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("Cannot show desktop notifications: %v", err)
+	}
+	return exec.Command(name, args...).Run()
+}
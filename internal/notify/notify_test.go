@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	name, args := command("kubectl ready", `kubectl "1.20.1" downloaded`)
+
+	switch runtime.GOOS {
+	case "darwin":
+		if name != "osascript" || len(args) != 2 || !strings.Contains(args[1], `\"1.20.1\"`) {
+			t.Errorf("Unexpected command %s %v", name, args)
+		}
+	case "windows":
+		if name != "powershell.exe" || !strings.Contains(args[len(args)-1], "kubectl ready") {
+			t.Errorf("Unexpected command %s %v", name, args)
+		}
+	default:
+		if name != "notify-send" || args[len(args)-2] != "kubectl ready" {
+			t.Errorf("Unexpected command %s %v", name, args)
+		}
+	}
+}
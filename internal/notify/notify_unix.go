@@ -0,0 +1,17 @@
+//go:build unix
+
+package notify
+
+import (
+	"runtime"
+	"strconv"
+)
+
+// command returns the program, and its arguments, showing a notification
+func command(title, message string) (string, []string) {
+	if runtime.GOOS == "darwin" {
+		script := "display notification " + strconv.Quote(message) + " with title " + strconv.Quote(title)
+		return "osascript", []string{"-e", script}
+	}
+	return "notify-send", []string{"--app-name=kuberlr", title, message}
+}
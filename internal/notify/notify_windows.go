@@ -0,0 +1,26 @@
+//go:build windows
+// +build windows
+
+package notify
+
+import "strings"
+
+// toastScript shows a toast notification using the WinRT API, the
+// placeholders are replaced with the title and the message
+const toastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode('{{title}}')) | Out-Null
+$text.Item(1).AppendChild($template.CreateTextNode('{{message}}')) | Out-Null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('kuberlr').Show([Windows.UI.Notifications.ToastNotification]::new($template))`
+
+// command returns the program, and its arguments, showing a notification
+func command(title, message string) (string, []string) {
+	// single quotes are escaped by doubling them inside of PowerShell strings
+	quote := strings.NewReplacer("'", "''")
+	script := strings.NewReplacer(
+		"{{title}}", quote.Replace(title),
+		"{{message}}", quote.Replace(message),
+	).Replace(toastScript)
+	return "powershell.exe", []string{"-NoProfile", "-NonInteractive", "-Command", script}
+}
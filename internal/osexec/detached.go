@@ -0,0 +1,29 @@
+package osexec
+
+import (
+	"os"
+	"os/exec"
+)
+
+// StartDetached starts the program referred to by pathname in the background
+// and returns immediately. The program keeps running after the current
+// process exits, its output is written to the given log file
+func StartDetached(pathname string, argv []string, logFile string) error {
+	log, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	cmd := exec.Command(pathname)
+	cmd.Args = argv
+	cmd.Stdout = log
+	cmd.Stderr = log
+	detach(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// the child is not waited for, release the resources associated with it
+	return cmd.Process.Release()
+}
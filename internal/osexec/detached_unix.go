@@ -0,0 +1,14 @@
+//go:build unix
+
+package osexec
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach makes the command run inside of its own session, this ensures
+// it's not killed when the terminal of the parent process goes away
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
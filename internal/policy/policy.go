@@ -0,0 +1,257 @@
+package policy
+
+import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// DefaultMaxAge is how long a policy is used before fetching it again
+const DefaultMaxAge = time.Hour
+
+// DefaultTimeout limits the download of the policy when the timeouts of
+// the Fetcher don't: the policy is fetched by kubectl invocations
+const DefaultTimeout = 10 * time.Second
+
+// SkewStrict is the skew rule allowing only kubectl binaries with the
+// same minor version of the kubernetes API server
+const SkewStrict = "strict"
+
+// SkewUpstream is the skew rule of the upstream version skew policy:
+// kubectl is supported within one minor version of the API server
+const SkewUpstream = "upstream"
+
+// Policy is published by a platform team to steer the kuberlr instances
+// of an organization, e.g.:
+//
+//	{
+//	  "allowedVersions": [">=1.19.0 <1.23.0"],
+//	  "mirror": "https://mirror.corp/kubernetes-release/release",
+//	  "skew": "strict"
+//	}
+type Policy struct {
+	// AllowedVersions are the ranges of the kubectl versions that can be
+	// used, any version is allowed when empty
+	AllowedVersions []string `json:"allowedVersions,omitempty"`
+	// Mirror is the location of the kubectl binaries, it replaces the
+	// upstream release bucket
+	Mirror string `json:"mirror,omitempty"`
+	// Skew is either "upstream" or "strict", upstream is used when empty
+	Skew string `json:"skew,omitempty"`
+
+	ranges []semver.Range
+}
+
+// Parse reads and validates a policy
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("Cannot parse policy: %v", err)
+	}
+
+	for _, r := range p.AllowedVersions {
+		parsed, err := semver.ParseRange(r)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid range of allowed versions %q: %v", r, err)
+		}
+		p.ranges = append(p.ranges, parsed)
+	}
+	if p.Skew != "" && p.Skew != SkewUpstream && p.Skew != SkewStrict {
+		return nil, fmt.Errorf("Unknown skew rule %q, use %q or %q", p.Skew, SkewUpstream, SkewStrict)
+	}
+	return p, nil
+}
+
+// Allows returns an error when the policy forbids the given version of
+// kubectl. A nil policy allows all the versions
+func (p *Policy) Allows(v semver.Version) error {
+	if p == nil || len(p.ranges) == 0 {
+		return nil
+	}
+	for _, r := range p.ranges {
+		if r(v) {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"kubectl %s is not allowed by the policy, allowed versions: %s",
+		v, strings.Join(p.AllowedVersions, ", "))
+}
+
+// StrictSkew returns true when only the kubectl binaries with the same
+// minor version of the API server can be used
+func (p *Policy) StrictSkew() bool {
+	return p != nil && p.Skew == SkewStrict
+}
+
+// cachedPolicy is the policy saved on disk, together with its signature
+type cachedPolicy struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	Body      []byte    `json:"body"`
+	Signature string    `json:"signature,omitempty"`
+}
+
+// Fetcher retrieves the policy published by a platform team and keeps
+// a copy of it on disk
+type Fetcher struct {
+	// URL is the location of the policy. Its signature is expected to
+	// be found at the same URL, with the ".sig" suffix
+	URL string
+	// PublicKey is the base64 encoded ed25519 key used to verify the
+	// signature of the policy. When set, policies without a valid
+	// signature are refused. Signatures are not checked when empty
+	PublicKey string
+	// CacheFile is where the policy is saved
+	CacheFile string
+	// MaxAge is how long the cached policy is used before fetching
+	// it again
+	MaxAge time.Duration
+	// Timeouts limits the requests made against the policy server,
+	// DefaultTimeout is used when the overall timeout is zero
+	Timeouts common.Timeouts
+	// RootCAs are the certificate authorities trusted when talking with
+	// the policy server over TLS, the ones of the system are used when nil
+	RootCAs *x509.CertPool
+}
+
+// Get returns the policy. A fresh copy is downloaded when the cached one
+// is older than MaxAge, the cached copy is used when the download fails
+func (f *Fetcher) Get(now time.Time) (*Policy, error) {
+	cached, cacheErr := f.loadCache()
+	if common.IsOffline() {
+		// the cached copy is used regardless of its age
+		if cacheErr != nil {
+			return nil, fmt.Errorf("The policy %s has never been fetched: %w", f.URL, common.ErrOffline)
+		}
+		return f.verify(cached)
+	}
+	if cacheErr == nil && now.Sub(cached.FetchedAt) < f.MaxAge {
+		if p, err := f.verify(cached); err == nil {
+			return p, nil
+		}
+	}
+
+	fresh, err := f.download(now)
+	if err == nil {
+		var p *Policy
+		if p, err = f.verify(fresh); err == nil {
+			f.saveCache(fresh)
+			return p, nil
+		}
+	}
+	if cacheErr != nil {
+		return nil, err
+	}
+
+	klog.V(1).Infof("Cannot refresh policy, using the cached one: %v", err)
+	return f.verify(cached)
+}
+
+// Cached returns the policy saved on disk regardless of its age, the
+// network is never reached
+func (f *Fetcher) Cached() (*Policy, error) {
+	cached, err := f.loadCache()
+	if err != nil {
+		return nil, err
+	}
+	return f.verify(cached)
+}
+
+// verify checks the signature of the policy and parses it
+func (f *Fetcher) verify(c cachedPolicy) (*Policy, error) {
+	if f.PublicKey != "" {
+		key, err := base64.StdEncoding.DecodeString(f.PublicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Invalid policy public key")
+		}
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(c.Signature))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid policy signature: %v", err)
+		}
+		if !ed25519.Verify(ed25519.PublicKey(key), c.Body, sig) {
+			return nil, fmt.Errorf("The signature of the policy %s is not valid", f.URL)
+		}
+	}
+	return Parse(c.Body)
+}
+
+func (f *Fetcher) download(now time.Time) (cachedPolicy, error) {
+	c := cachedPolicy{FetchedAt: now}
+
+	body, err := f.get(f.URL)
+	if err != nil {
+		return c, err
+	}
+	c.Body = body
+
+	if f.PublicKey != "" {
+		sig, err := f.get(f.URL + ".sig")
+		if err != nil {
+			return c, err
+		}
+		c.Signature = string(sig)
+	}
+	return c, nil
+}
+
+// get fetches the given URL honoring the timeouts, the proxy and the
+// offline mode
+func (f *Fetcher) get(url string) ([]byte, error) {
+	timeouts := f.Timeouts
+	if timeouts.Overall == 0 {
+		timeouts.Overall = DefaultTimeout
+	}
+	client := timeouts.Client()
+	if f.RootCAs != nil {
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: f.RootCAs}
+	}
+
+	res, err := client.Get(url)
+	if err != nil {
+		return []byte{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return []byte{}, fmt.Errorf("GET %s returned http status %s", url, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+func (f *Fetcher) loadCache() (cachedPolicy, error) {
+	var c cachedPolicy
+
+	data, err := ioutil.ReadFile(f.CacheFile)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+func (f *Fetcher) saveCache(c cachedPolicy) {
+	data, err := json.Marshal(c)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(f.CacheFile), 0755)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(f.CacheFile, data, 0644)
+	}
+	if err != nil {
+		klog.V(1).Infof("Cannot cache policy: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestAllows(t *testing.T) {
+	p, err := Parse([]byte(`{"allowedVersions": [">=1.19.0 <1.21.0", "1.22.3"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"1.18.9": false,
+		"1.19.0": true,
+		"1.20.5": true,
+		"1.21.0": false,
+		"1.22.3": true,
+	}
+	for v, allowed := range tests {
+		if err := p.Allows(semver.MustParse(v)); (err == nil) != allowed {
+			t.Errorf("%s: expected allowed to be %v, got %v", v, allowed, err)
+		}
+	}
+
+	var nilPolicy *Policy
+	if err := nilPolicy.Allows(semver.MustParse("1.0.0")); err != nil {
+		t.Errorf("A nil policy should allow everything: %v", err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"allowedVersions": ["latest"]}`,
+		`{"skew": "loose"}`,
+		`not json`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestFetcher(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`{"skew": "strict"}`)
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, body))
+	online := true
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !online {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		requests++
+		switch r.URL.Path {
+		case "/policy.json":
+			w.Write(body)
+		case "/policy.json.sig":
+			w.Write([]byte(signature))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kuberlr-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := &Fetcher{
+		URL:       server.URL + "/policy.json",
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+		CacheFile: filepath.Join(dir, "policy.json"),
+		MaxAge:    time.Hour,
+	}
+
+	now := time.Now()
+	p, err := f.Get(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.StrictSkew() {
+		t.Errorf("Unexpected policy %+v", p)
+	}
+
+	// the cached policy is used
+	if _, err := f.Get(now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+
+	// an expired policy is still used when the server is down
+	online = false
+	if _, err := f.Get(now.Add(2 * time.Hour)); err != nil {
+		t.Errorf("Expected the cached policy to be used: %v", err)
+	}
+	if p, err := f.Cached(); err != nil || !p.StrictSkew() {
+		t.Errorf("Expected the cached policy, got %+v %v", p, err)
+	}
+	if requests != 2 {
+		t.Errorf("The cached policy has been fetched again")
+	}
+
+	// tampered policies are rejected
+	online = true
+	body = []byte(`{"skew": "upstream"}`)
+	os.Remove(f.CacheFile)
+	if _, err := f.Get(now); err == nil {
+		t.Error("Expected the tampered policy to be rejected")
+	}
+
+	// unsigned policies are rejected when a key is configured
+	body = []byte(`{"skew": "strict"}`)
+	signature = ""
+	if _, err := f.Get(now); err == nil {
+		t.Error("Expected the unsigned policy to be rejected")
+	}
+}
@@ -0,0 +1,62 @@
+package progress
+
+import "time"
+
+const (
+	// sampleInterval is the minimum amount of time between two samples
+	sampleInterval = 500 * time.Millisecond
+	// smoothing is the weight given to the most recent sample
+	smoothing = 0.3
+	// minSamples is the number of samples required before
+	// providing an estimate
+	minSamples = 4
+)
+
+// rateEstimator computes the transfer rate using an exponentially weighted
+// moving average, which prevents the estimated time of arrival from jumping
+// wildly because of short bursts or stalls
+type rateEstimator struct {
+	rate      float64
+	samples   int
+	lastTime  time.Time
+	lastBytes int64
+}
+
+func newRateEstimator(start time.Time) *rateEstimator {
+	return &rateEstimator{lastTime: start}
+}
+
+// Update records the total amount of bytes transferred at the given time
+func (e *rateEstimator) Update(now time.Time, transferred int64) {
+	elapsed := now.Sub(e.lastTime)
+	if elapsed < sampleInterval {
+		return
+	}
+
+	sample := float64(transferred-e.lastBytes) / elapsed.Seconds()
+	if e.samples == 0 {
+		e.rate = sample
+	} else {
+		e.rate = smoothing*sample + (1-smoothing)*e.rate
+	}
+	e.samples++
+	e.lastTime = now
+	e.lastBytes = transferred
+}
+
+// Rate returns the smoothed transfer rate in bytes per second. The
+// boolean is false when not enough samples have been collected yet
+func (e *rateEstimator) Rate() (float64, bool) {
+	return e.rate, e.samples > 0
+}
+
+// ETA returns the estimated amount of time required to transfer the
+// remaining bytes. The boolean is false when not enough samples have been
+// collected yet to provide a meaningful estimate
+func (e *rateEstimator) ETA(remaining int64) (time.Duration, bool) {
+	if e.samples < minSamples || e.rate <= 0 {
+		return 0, false
+	}
+	eta := time.Duration(float64(remaining) / e.rate * float64(time.Second))
+	return eta.Round(time.Second), true
+}
@@ -0,0 +1,44 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateEstimator(t *testing.T) {
+	start := time.Now()
+	e := newRateEstimator(start)
+
+	if _, ok := e.ETA(1000); ok {
+		t.Error("No ETA should be given without samples")
+	}
+
+	// 100 bytes per second, with a burst in the middle
+	transferred := int64(0)
+	for i, chunk := range []int64{100, 100, 1000, 100, 100, 100} {
+		transferred += chunk
+		e.Update(start.Add(time.Duration(i+1)*time.Second), transferred)
+		if i+1 < minSamples {
+			if _, ok := e.ETA(1000); ok {
+				t.Errorf("ETA given after only %d samples", i+1)
+			}
+		}
+	}
+
+	eta, ok := e.ETA(1000)
+	if !ok {
+		t.Fatal("Expected ETA to be available")
+	}
+	// the burst is smoothed out: a plain average of the last sample would
+	// give 10s, the overall average would give ~6s
+	if eta < 4*time.Second || eta > 10*time.Second {
+		t.Errorf("Unexpected ETA %v", eta)
+	}
+
+	// samples taken too close are ignored
+	samples := e.samples
+	e.Update(start.Add(6*time.Second+time.Millisecond), transferred+10)
+	if e.samples != samples {
+		t.Error("Sample taken too early has been recorded")
+	}
+}
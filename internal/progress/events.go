@@ -0,0 +1,95 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// quietBar doesn't show anything
+type quietBar struct{}
+
+func (quietBar) Write(p []byte) (int, error) { return len(p), nil }
+func (quietBar) Status(string)               {}
+func (quietBar) Finish(string)               {}
+
+// Event is printed by the JSON style, one per line
+type Event struct {
+	// Type is one of "start", "progress", "status", "finish" and
+	// "message". Message events aren't tied to a download
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Bytes       int64  `json:"bytes"`
+	// Total is the size of the download, zero when unknown
+	Total   int64  `json:"total,omitempty"`
+	Percent int64  `json:"percent,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Printf prints a message about the downloads using the given style:
+// the JSON style prints a message event, the Quiet one prints nothing
+func Printf(out io.Writer, style Style, format string, a ...interface{}) {
+	switch style {
+	case Quiet:
+	case JSON:
+		msg := strings.TrimRight(fmt.Sprintf(format, a...), "\n")
+		json.NewEncoder(out).Encode(Event{Type: "message", Message: msg})
+	default:
+		fmt.Fprintf(out, format, a...)
+	}
+}
+
+// jsonBar prints the progress as JSON events, a progress event is printed
+// every time the download makes some progress
+type jsonBar struct {
+	enc         *json.Encoder
+	desc        string
+	total       int64
+	current     int64
+	lastPercent int64
+}
+
+func newJSONBar(out io.Writer, desc string, total int64) *jsonBar {
+	b := &jsonBar{enc: json.NewEncoder(out), desc: desc, total: total}
+	b.emit("start", "")
+	return b
+}
+
+// emit prints an event describing the current state of the download
+func (b *jsonBar) emit(kind, status string) {
+	e := Event{
+		Type:        kind,
+		Description: b.desc,
+		Bytes:       b.current,
+		Total:       b.total,
+		Status:      status,
+	}
+	if b.total > 0 {
+		e.Percent = b.current * 100 / b.total
+	}
+	b.enc.Encode(e)
+}
+
+func (b *jsonBar) Write(p []byte) (int, error) {
+	b.current += int64(len(p))
+	if b.total <= 0 {
+		return len(p), nil
+	}
+
+	percent := b.current * 100 / b.total
+	if percent >= b.lastPercent+plainStep {
+		b.lastPercent = percent - percent%plainStep
+		b.emit("progress", "")
+	}
+	return len(p), nil
+}
+
+func (b *jsonBar) Status(status string) {
+	b.emit("status", status)
+}
+
+func (b *jsonBar) Finish(status string) {
+	b.emit("finish", status)
+}
@@ -0,0 +1,70 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONBar(t *testing.T) {
+	out := &bytes.Buffer{}
+	bar := newJSONBar(out, "kubectl v1.20.0 linux/amd64", 100)
+	for i := 0; i < 100; i++ {
+		bar.Write([]byte{0})
+	}
+	bar.Status("verifying...")
+	bar.Finish("verified, done.")
+
+	events := []Event{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Invalid event %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+
+	// start, one event every 10%, the status and the final one
+	if len(events) != 13 {
+		t.Fatalf("Got %d events: %q", len(events), out.String())
+	}
+	if events[0].Type != "start" || events[1].Type != "progress" || events[1].Percent != 10 {
+		t.Errorf("Unexpected first events %+v", events[:2])
+	}
+	last := events[12]
+	if last.Type != "finish" || last.Status != "verified, done." || last.Bytes != 100 || last.Percent != 100 {
+		t.Errorf("Unexpected last event %+v", last)
+	}
+}
+
+func TestQuietBar(t *testing.T) {
+	bar := New(nil, "kubectl", 100, Quiet)
+	if n, err := bar.Write(make([]byte, 10)); n != 10 || err != nil {
+		t.Errorf("Got %d, %v", n, err)
+	}
+	bar.Finish("done.")
+}
+
+func TestPrintf(t *testing.T) {
+	out := &bytes.Buffer{}
+	Printf(out, Quiet, "Downloading %s\n", "kubectl")
+	if out.Len() != 0 {
+		t.Errorf("Nothing should be printed by the quiet style, got %q", out.String())
+	}
+
+	Printf(out, JSON, "Downloading %s\n", "kubectl")
+	var e Event
+	if err := json.Unmarshal(out.Bytes(), &e); err != nil {
+		t.Fatalf("Invalid event %q: %v", out.String(), err)
+	}
+	if e.Type != "message" || e.Message != "Downloading kubectl" {
+		t.Errorf("Unexpected event %+v", e)
+	}
+
+	out.Reset()
+	Printf(out, Plain, "Downloading %s\n", "kubectl")
+	if out.String() != "Downloading kubectl\n" {
+		t.Errorf("Unexpected message %q", out.String())
+	}
+}
@@ -0,0 +1,206 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Pool shows the progress of many downloads running at the same time.
+// On terminals every download gets its own line, the lines are redrawn
+// together below the messages printed so far. On the other outputs the
+// bars of the chosen style are used, their lines are never interleaved
+type Pool struct {
+	mu    sync.Mutex
+	out   io.Writer
+	style Style
+	// redraw is true when the lines of the downloads are redrawn in place
+	redraw bool
+	// termWidth returns the number of columns of the terminal
+	termWidth func() int
+
+	// bars are the downloads shown on the terminal, in order of start
+	bars []*poolBar
+	// drawn is the number of lines drawn by the last render
+	drawn    int
+	lastDraw time.Time
+}
+
+// NewPool returns a Pool writing to the given output
+func NewPool(out *os.File, style Style) *Pool {
+	p := newPool(out, style, func() int { return terminalWidth(out) })
+	p.redraw = (style == Detailed || style == Minimal) &&
+		supportsRedraw(out) && term.IsTerminal(int(out.Fd()))
+	return p
+}
+
+func newPool(out io.Writer, style Style, termWidth func() int) *Pool {
+	return &Pool{out: out, style: style, termWidth: termWidth}
+}
+
+// New returns the Bar of a new download
+func (p *Pool) New(desc string, total int64) Bar {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.redraw {
+		var bar Bar
+		switch p.style {
+		case Quiet:
+			bar = quietBar{}
+		case JSON:
+			bar = newJSONBar(p.out, desc, total)
+		default:
+			bar = newPlainBar(p.out, desc, total)
+		}
+		return &lockedBar{mu: &p.mu, bar: bar}
+	}
+
+	// the bar computes the text of the line, the pool draws it
+	tb := newTerminalBar(ioutil.Discard, desc, total, p.termWidth)
+	tb.style = p.style
+	b := &poolBar{pool: p, bar: tb, text: tb.line()}
+	p.bars = append(p.bars, b)
+	p.render()
+	return b
+}
+
+// Interrupt removes the lines of the downloads from the terminal, runs fn
+// and draws the lines again. Messages printed by fn don't get mixed with
+// the progress of the downloads
+func (p *Pool) Interrupt(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.redraw {
+		fn()
+		return
+	}
+	p.clear()
+	fn()
+	p.render()
+}
+
+// Printf prints the given message above the lines of the downloads,
+// using the style of the pool
+func (p *Pool) Printf(format string, a ...interface{}) {
+	p.Interrupt(func() {
+		Printf(p.out, p.style, format, a...)
+	})
+}
+
+// clear removes the lines drawn by the last render, the cursor is left
+// where the first one started
+func (p *Pool) clear() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\x1b[%dA\r\x1b[J", p.drawn)
+	}
+	p.drawn = 0
+}
+
+// render redraws the lines of the downloads. The lines of the completed
+// downloads are printed one last time above the others and are forgotten,
+// this keeps the number of redrawn lines as low as the number of running
+// downloads
+func (p *Pool) render() {
+	if len(p.bars) == 0 && p.drawn == 0 {
+		return
+	}
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\x1b[%dA", p.drawn)
+	}
+
+	running := p.bars[:0]
+	for _, b := range p.bars {
+		if b.finished {
+			fmt.Fprintf(p.out, "\r%s\x1b[K\n", b.text)
+		}
+	}
+	for _, b := range p.bars {
+		if !b.finished {
+			fmt.Fprintf(p.out, "\r%s\x1b[K\n", b.text)
+			running = append(running, b)
+		}
+	}
+	// clean up the lines left behind by the downloads that completed
+	fmt.Fprint(p.out, "\x1b[J")
+
+	p.bars = running
+	p.drawn = len(running)
+	p.lastDraw = time.Now()
+}
+
+// poolBar is the line of a download inside of a Pool
+type poolBar struct {
+	pool     *Pool
+	bar      *terminalBar
+	text     string
+	finished bool
+}
+
+func (b *poolBar) Write(p []byte) (int, error) {
+	b.pool.mu.Lock()
+	defer b.pool.mu.Unlock()
+
+	b.bar.current += int64(len(p))
+	b.bar.estimator.Update(time.Now(), b.bar.current)
+	if b.finished {
+		return len(p), nil
+	}
+	b.text = b.bar.line()
+	// all the lines are redrawn together, the interval is shared
+	if time.Since(b.pool.lastDraw) >= redrawInterval || b.bar.current == b.bar.total {
+		b.pool.render()
+	}
+	return len(p), nil
+}
+
+func (b *poolBar) Status(status string) {
+	b.pool.mu.Lock()
+	defer b.pool.mu.Unlock()
+
+	b.text = b.bar.statusText(status)
+	b.pool.render()
+}
+
+func (b *poolBar) Finish(status string) {
+	b.pool.mu.Lock()
+	defer b.pool.mu.Unlock()
+
+	if b.finished {
+		return
+	}
+	b.text = b.bar.statusText(status)
+	b.finished = true
+	b.pool.render()
+}
+
+// lockedBar prevents the lines of a bar from being printed in the middle
+// of the lines of another one
+type lockedBar struct {
+	mu  *sync.Mutex
+	bar Bar
+}
+
+func (b *lockedBar) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bar.Write(p)
+}
+
+func (b *lockedBar) Status(status string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bar.Status(status)
+}
+
+func (b *lockedBar) Finish(status string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bar.Finish(status)
+}
@@ -0,0 +1,68 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPoolRedrawsOneLinePerDownload(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := newPool(out, Detailed, func() int { return 80 })
+	p.redraw = true
+
+	first := p.New("kubectl v1.27.0", 100)
+	second := p.New("kubectl v1.28.0", 100)
+	if p.drawn != 2 {
+		t.Fatalf("Got %d lines drawn instead of 2", p.drawn)
+	}
+
+	first.Write(make([]byte, 100))
+	first.Finish("verified, done.")
+	if p.drawn != 1 {
+		t.Errorf("Got %d lines drawn after a download completed instead of 1", p.drawn)
+	}
+
+	out.Reset()
+	p.Printf("Downloaded kubectl %s\n", "1.27.0")
+	// the line of the running download is removed and drawn again below
+	// the message
+	expected := "\x1b[1A\r\x1b[JDownloaded kubectl 1.27.0\n"
+	if !strings.HasPrefix(out.String(), expected) {
+		t.Errorf("Got %q, expected it to start with %q", out.String(), expected)
+	}
+	if !strings.Contains(out.String(), "kubectl v1.28.0") {
+		t.Errorf("The running download hasn't been drawn again: %q", out.String())
+	}
+
+	second.Finish("failed.")
+	out.Reset()
+	p.Printf("done\n")
+	if out.String() != "done\n" {
+		t.Errorf("Nothing should be redrawn once all the downloads completed, got %q", out.String())
+	}
+}
+
+func TestPoolDoesNotInterleavePlainLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := newPool(out, Detailed, func() int { return 80 })
+
+	bars := []Bar{p.New("first", 10), p.New("second", 10)}
+	for i := 0; i < 10; i++ {
+		for _, bar := range bars {
+			bar.Write([]byte{0})
+		}
+	}
+	for _, bar := range bars {
+		bar.Finish("done.")
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if !strings.HasPrefix(line, "first ") && !strings.HasPrefix(line, "second ") {
+			t.Errorf("Unexpected line %q", line)
+		}
+	}
+	if strings.Contains(out.String(), "\x1b") {
+		t.Errorf("Unexpected escape sequence in %q", out.String())
+	}
+}
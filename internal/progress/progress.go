@@ -0,0 +1,284 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// Bar reports the progress of a download
+type Bar interface {
+	// Write accounts the given bytes as transferred
+	io.Writer
+	// Status replaces the bar with the given status, the bar is
+	// considered completed
+	Status(status string)
+	// Finish prints the given final status and terminates the line
+	Finish(status string)
+}
+
+// Style describes what is shown while downloading
+type Style string
+
+const (
+	// Detailed shows the amount of bytes transferred, the speed and
+	// the estimated time of arrival
+	Detailed Style = "detailed"
+	// Minimal shows only a progress bar
+	Minimal Style = "minimal"
+	// Plain prints a line every time the download makes some progress,
+	// even on terminals. It suits CI logs
+	Plain Style = "plain"
+	// Quiet doesn't show anything
+	Quiet Style = "quiet"
+	// JSON prints the progress as JSON events, one per line
+	JSON Style = "json"
+)
+
+// StyleEnvKey is the environment variable overriding the style set
+// inside of the configuration
+const StyleEnvKey = "KUBERLR_PROGRESS"
+
+// ParseStyle returns the Style with the given name
+func ParseStyle(name string) (Style, error) {
+	switch s := Style(strings.ToLower(name)); s {
+	case Detailed, Minimal, Plain, Quiet, JSON:
+		return s, nil
+	case "", "bar":
+		return Detailed, nil
+	default:
+		return "", fmt.Errorf("Unknown progress style: %s", name)
+	}
+}
+
+// New returns the Bar that works best with the given output
+func New(out *os.File, desc string, total int64, style Style) Bar {
+	switch style {
+	case Plain:
+		return newPlainBar(out, desc, total)
+	case Quiet:
+		return quietBar{}
+	case JSON:
+		return newJSONBar(out, desc, total)
+	}
+	if supportsRedraw(out) {
+		b := newTerminalBar(out, desc, total, func() int {
+			return terminalWidth(out)
+		})
+		b.style = style
+		return b
+	}
+	return newPlainBar(out, desc, total)
+}
+
+// terminalBar redraws the same line of the terminal
+type terminalBar struct {
+	out   io.Writer
+	desc  string
+	total int64
+	style Style
+	// termWidth returns the number of columns of the terminal
+	termWidth func() int
+
+	current   int64
+	lastWidth int
+	lastDraw  time.Time
+	estimator *rateEstimator
+}
+
+// redrawInterval is the minimum amount of time between two redraws
+const redrawInterval = 50 * time.Millisecond
+
+// barWidth is the number of cells of the bar, when there's enough room
+const barWidth = 40
+
+func newTerminalBar(out io.Writer, desc string, total int64, termWidth func() int) *terminalBar {
+	return &terminalBar{
+		out:       out,
+		desc:      desc,
+		total:     total,
+		style:     Detailed,
+		termWidth: termWidth,
+		estimator: newRateEstimator(time.Now()),
+	}
+}
+
+func (b *terminalBar) Write(p []byte) (int, error) {
+	b.current += int64(len(p))
+	b.estimator.Update(time.Now(), b.current)
+	if time.Since(b.lastDraw) >= redrawInterval || b.current == b.total {
+		b.draw(b.line())
+		b.lastDraw = time.Now()
+	}
+	return len(p), nil
+}
+
+func (b *terminalBar) Status(status string) {
+	b.draw(b.statusText(status))
+}
+
+func (b *terminalBar) Finish(status string) {
+	b.Status(status)
+	fmt.Fprintln(b.out)
+}
+
+// statusText renders the description followed by the given status
+func (b *terminalBar) statusText(status string) string {
+	return fmt.Sprintf("%s %s", b.fitDescription(runewidth.StringWidth(status)+1), status)
+}
+
+// line renders the current state of the download
+func (b *terminalBar) line() string {
+	if b.style == Minimal && b.total > 0 {
+		return b.barLine()
+	}
+	return b.statusLine()
+}
+
+// statusLine renders something like:
+// `kubectl v1.20.0 linux/arm64  23.0 MiB/49.0 MiB (46%)  8.1 MiB/s  eta 3s`
+func (b *terminalBar) statusLine() string {
+	fields := []string{HumanizeBytes(b.current)}
+	if b.total > 0 {
+		fields[0] = fmt.Sprintf("%s/%s (%d%%)", fields[0], HumanizeBytes(b.total), b.percent())
+	}
+	if rate, ok := b.estimator.Rate(); ok {
+		fields = append(fields, fmt.Sprintf("%s/s", HumanizeBytes(int64(rate))))
+	}
+	if b.total > 0 {
+		if eta, ok := b.estimator.ETA(b.total - b.current); ok {
+			fields = append(fields, fmt.Sprintf("eta %s", eta))
+		}
+	}
+
+	rest := strings.Join(fields, "  ")
+	return fmt.Sprintf("%s  %s", b.fitDescription(runewidth.StringWidth(rest)+2), rest)
+}
+
+// barLine renders something like: `kubectl v1.20.0  46% [=====     ]`
+func (b *terminalBar) barLine() string {
+	// room left for the description and the bar
+	room := b.termWidth() - len(" 100% [] ") - 1
+	width := barWidth
+	if room-width < minDescWidth {
+		width = room - minDescWidth
+	}
+	if width < 0 {
+		width = 0
+	}
+	filled := int(int64(width) * b.percent() / 100)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+
+	rest := fmt.Sprintf("%4d%% [%s]", b.percent(), bar)
+	return fmt.Sprintf("%s %s", b.fitDescription(runewidth.StringWidth(rest)+1), rest)
+}
+
+func (b *terminalBar) percent() int64 {
+	percent := b.current * 100 / b.total
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// minDescWidth is the number of cells always granted to the description
+const minDescWidth = 10
+
+// fitDescription returns the description, truncated when it doesn't fit
+// inside of the terminal together with the given amount of cells. The
+// computation takes into account characters taking more than one cell,
+// like CJK characters and emoji
+func (b *terminalBar) fitDescription(reserved int) string {
+	room := b.termWidth() - reserved - 1
+	if room < minDescWidth {
+		room = minDescWidth
+	}
+	return runewidth.Truncate(b.desc, room, "…")
+}
+
+// draw replaces the current line of the terminal with the given one
+func (b *terminalBar) draw(line string) {
+	width := runewidth.StringWidth(line)
+	padding := ""
+	if width < b.lastWidth {
+		// clean up the leftovers of the previous line
+		padding = strings.Repeat(" ", b.lastWidth-width)
+	}
+	fmt.Fprintf(b.out, "\r%s%s", line, padding)
+	b.lastWidth = width
+}
+
+// plainBar prints a new line every time the download makes
+// some progress, it's used when the output cannot be redrawn
+type plainBar struct {
+	out         io.Writer
+	desc        string
+	total       int64
+	current     int64
+	lastPercent int64
+}
+
+// plainStep is the minimum increment, in percentage points, between
+// two lines printed by plainBar
+const plainStep = 10
+
+func newPlainBar(out io.Writer, desc string, total int64) *plainBar {
+	return &plainBar{
+		out:   out,
+		desc:  desc,
+		total: total,
+	}
+}
+
+func (b *plainBar) Write(p []byte) (int, error) {
+	b.current += int64(len(p))
+	if b.total <= 0 {
+		return len(p), nil
+	}
+
+	percent := b.current * 100 / b.total
+	if percent >= b.lastPercent+plainStep {
+		b.lastPercent = percent - percent%plainStep
+		fmt.Fprintf(b.out, "%s %3d%% (%s/%s)\n", b.desc, percent, HumanizeBytes(b.current), HumanizeBytes(b.total))
+	}
+	return len(p), nil
+}
+
+func (b *plainBar) Status(status string) {
+	fmt.Fprintf(b.out, "%s %s\n", b.desc, status)
+}
+
+func (b *plainBar) Finish(status string) {
+	b.Status(status)
+}
+
+// defaultTerminalWidth is used when the size of the terminal is unknown
+const defaultTerminalWidth = 80
+
+func terminalWidth(out *os.File) int {
+	width, _, err := term.GetSize(int(out.Fd()))
+	if err != nil || width <= 0 {
+		return defaultTerminalWidth
+	}
+	return width
+}
+
+// HumanizeBytes returns the given amount of bytes using binary prefixes,
+// like "23.0 MiB"
+func HumanizeBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,107 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestPlainBar(t *testing.T) {
+	out := &bytes.Buffer{}
+	bar := newPlainBar(out, "kubectl1.20.0", 100)
+
+	for i := 0; i < 100; i++ {
+		bar.Write([]byte{0})
+	}
+	bar.Finish("done.")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	// one line every 10%, plus the final status
+	if len(lines) != 11 {
+		t.Errorf("Got %d lines: %q", len(lines), out.String())
+	}
+	if !strings.Contains(lines[0], " 10%") {
+		t.Errorf("Unexpected first line %q", lines[0])
+	}
+	if lines[10] != "kubectl1.20.0 done." {
+		t.Errorf("Unexpected last line %q", lines[10])
+	}
+}
+
+func TestHumanizeBytes(t *testing.T) {
+	for n, expected := range map[int64]string{
+		512:              "512 B",
+		2048:             "2.0 KiB",
+		49 * 1024 * 1024: "49.0 MiB",
+	} {
+		if actual := HumanizeBytes(n); actual != expected {
+			t.Errorf("Got %s instead of %s", actual, expected)
+		}
+	}
+}
+
+func TestTerminalBarWideCharacters(t *testing.T) {
+	out := &bytes.Buffer{}
+	// each of these characters takes two cells
+	desc := strings.Repeat("生", 30)
+	bar := newTerminalBar(out, desc, 100, func() int { return 60 })
+
+	bar.Write(make([]byte, 50))
+	bar.Finish("done.")
+
+	for _, line := range strings.Split(out.String(), "\r") {
+		line = strings.TrimRight(line, " \n")
+		if w := runewidth.StringWidth(line); w > 60 {
+			t.Errorf("Line %q takes %d cells, more than the terminal width", line, w)
+		}
+	}
+	if !strings.Contains(out.String(), "…") {
+		t.Errorf("Expected description to be truncated: %q", out.String())
+	}
+}
+
+func TestTerminalBarClearsLongerLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	bar := newTerminalBar(out, "kubectl", 100, func() int { return 80 })
+	bar.draw("生生生生")
+	bar.draw("ab")
+
+	lines := strings.Split(out.String(), "\r")
+	last := lines[len(lines)-1]
+	// 4 wide characters take 8 cells
+	if runewidth.StringWidth(last) != 8 {
+		t.Errorf("Previous line not cleaned up: %q", last)
+	}
+}
+
+func TestTerminalBarStyles(t *testing.T) {
+	out := &bytes.Buffer{}
+	bar := newTerminalBar(out, "kubectl v1.20.0 linux/amd64", 2048, func() int { return 120 })
+	bar.Write(make([]byte, 1024))
+
+	if line := bar.line(); !strings.Contains(line, "1.0 KiB/2.0 KiB (50%)") {
+		t.Errorf("Unexpected status line %q", line)
+	}
+
+	bar.style = Minimal
+	if line := bar.line(); !strings.Contains(line, "50% [====") {
+		t.Errorf("Unexpected bar line %q", line)
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	if s, err := ParseStyle(""); err != nil || s != Detailed {
+		t.Errorf("Got %s, %v", s, err)
+	}
+	if s, err := ParseStyle("Minimal"); err != nil || s != Minimal {
+		t.Errorf("Got %s, %v", s, err)
+	}
+	if s, err := ParseStyle("json"); err != nil || s != JSON {
+		t.Errorf("Got %s, %v", s, err)
+	}
+	if _, err := ParseStyle("fancy"); err == nil {
+		t.Error("Expected unknown style to be refused")
+	}
+}
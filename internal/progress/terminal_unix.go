@@ -0,0 +1,11 @@
+//go:build unix
+
+package progress
+
+import "os"
+
+// supportsRedraw returns true when the output can be redrawn using
+// carriage returns, which is always the case on Linux and macOS
+func supportsRedraw(out *os.File) bool {
+	return true
+}
@@ -0,0 +1,26 @@
+//go:build windows
+// +build windows
+
+package progress
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// supportsRedraw returns true when the output is a console that
+// understands virtual terminal sequences. Legacy consoles and outputs
+// redirected to files or pipes (e.g. CI logs) get plain lines
+func supportsRedraw(out *os.File) bool {
+	handle := windows.Handle(out.Fd())
+
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
@@ -0,0 +1,68 @@
+package prune
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+// Rules define which kubectl binaries are removed by a cleanup
+type Rules struct {
+	// KeepLast is the number of newest patch releases of each minor
+	// release that are always kept, zero disables this rule
+	KeepLast int
+	// UnusedFor removes the binaries that haven't been used for this
+	// long, zero disables this rule
+	UnusedFor time.Duration
+	// Keep are the versions that are never removed, like the
+	// default version and the pinned ones
+	Keep []semver.Version
+}
+
+// Enabled returns true when at least one rule can remove binaries
+func (r Rules) Enabled() bool {
+	return r.KeepLast > 0 || r.UnusedFor > 0
+}
+
+// Plan returns the binaries to remove. When both rules are enabled, a
+// binary is removed only when it's unused and it's not among the newest
+// KeepLast patch releases of its minor release. lastUsed returns when a
+// binary has been used for the last time
+func Plan(bins finder.KubectlBinaries, rules Rules, lastUsed func(finder.KubectlBinary) time.Time, now time.Time) finder.KubectlBinaries {
+	remove := finder.KubectlBinaries{}
+	if !rules.Enabled() {
+		return remove
+	}
+
+	sorted := append(finder.KubectlBinaries{}, bins...)
+	finder.SortKubectlByVersion(sorted, true)
+
+	newest := map[string]int{}
+	for _, b := range sorted {
+		minor := fmt.Sprintf("%d.%d", b.Version.Major, b.Version.Minor)
+		newest[minor]++
+		if rules.KeepLast > 0 && newest[minor] <= rules.KeepLast {
+			continue
+		}
+		if rules.UnusedFor > 0 && now.Sub(lastUsed(b)) < rules.UnusedFor {
+			continue
+		}
+		if kept(b.Version, rules.Keep) {
+			continue
+		}
+		remove = append(remove, b)
+	}
+	return remove
+}
+
+func kept(v semver.Version, keep []semver.Version) bool {
+	for _, k := range keep {
+		if v.Equals(k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package prune
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+func TestPlan(t *testing.T) {
+	now := time.Now()
+	bins := finder.KubectlBinaries{}
+	used := map[string]time.Time{}
+	for v, daysAgo := range map[string]int{
+		"1.19.1": 90,
+		"1.19.2": 60,
+		"1.19.3": 1,
+		"1.20.1": 90,
+		"1.20.2": 90,
+		"1.21.0": 90,
+	} {
+		bins = append(bins, finder.KubectlBinary{Path: "kubectl" + v, Version: semver.MustParse(v)})
+		used["kubectl"+v] = now.Add(-time.Duration(daysAgo) * 24 * time.Hour)
+	}
+	lastUsed := func(b finder.KubectlBinary) time.Time {
+		return used[b.Path]
+	}
+
+	tests := []struct {
+		name     string
+		rules    Rules
+		expected []string
+	}{
+		{"disabled", Rules{}, []string{}},
+		{"keep last", Rules{KeepLast: 1}, []string{"1.20.1", "1.19.2", "1.19.1"}},
+		{"unused", Rules{UnusedFor: 30 * 24 * time.Hour}, []string{"1.21.0", "1.20.2", "1.20.1", "1.19.2", "1.19.1"}},
+		{"both", Rules{KeepLast: 1, UnusedFor: 75 * 24 * time.Hour}, []string{"1.20.1", "1.19.1"}},
+		{"keep default", Rules{KeepLast: 1, Keep: []semver.Version{semver.MustParse("1.19.1")}}, []string{"1.20.1", "1.19.2"}},
+	}
+	for _, test := range tests {
+		actual := Plan(bins, test.rules, lastUsed, now)
+		if len(actual) != len(test.expected) {
+			t.Errorf("%s: got %v instead of %v", test.name, actual, test.expected)
+			continue
+		}
+		for i, v := range test.expected {
+			if actual[i].Version.String() != v {
+				t.Errorf("%s: got %v instead of %v", test.name, actual, test.expected)
+				break
+			}
+		}
+	}
+}
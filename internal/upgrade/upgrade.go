@@ -0,0 +1,92 @@
+package upgrade
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// Off is the value of the AutoUpgrade and AutoPrune settings that disables
+// the automatic runs
+const Off = "off"
+
+// ParseInterval returns the interval between two automatic runs. Besides
+// "off", "daily" and "weekly", any duration understood by time.ParseDuration
+// is accepted. A zero interval means the automatic runs are disabled
+func ParseInterval(value string) (time.Duration, error) {
+	switch strings.ToLower(value) {
+	case Off, "":
+		return 0, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("Invalid interval %q, use \"off\", \"daily\", \"weekly\" or a duration like \"72h\"", value)
+	}
+	return d, nil
+}
+
+// Due returns true when the last automatic run, recorded by the stamp
+// file, happened more than interval ago
+func Due(stampFile string, interval time.Duration, now time.Time) bool {
+	if interval <= 0 {
+		return false
+	}
+	info, err := os.Stat(stampFile)
+	if err != nil {
+		return true
+	}
+	return now.Sub(info.ModTime()) >= interval
+}
+
+// Touch records an automatic run is happening at the given time
+func Touch(stampFile string, now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(stampFile), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(stampFile, []byte{}, 0644); err != nil {
+		return err
+	}
+	return os.Chtimes(stampFile, now, now)
+}
+
+// Plan returns the patch releases that have to be downloaded to bring all
+// the installed minor releases up to date
+func Plan(installed []semver.Version, remote semver.Versions) semver.Versions {
+	newest := map[string]semver.Version{}
+	for _, v := range installed {
+		key := fmt.Sprintf("%d.%d", v.Major, v.Minor)
+		if current, found := newest[key]; !found || v.GT(current) {
+			newest[key] = v
+		}
+	}
+
+	latest := map[string]semver.Version{}
+	for _, v := range remote {
+		if len(v.Pre) > 0 {
+			continue
+		}
+		key := fmt.Sprintf("%d.%d", v.Major, v.Minor)
+		if current, found := latest[key]; !found || v.GT(current) {
+			latest[key] = v
+		}
+	}
+
+	res := semver.Versions{}
+	for key, current := range newest {
+		if l, found := latest[key]; found && l.GT(current) {
+			res = append(res, l)
+		}
+	}
+	semver.Sort(res)
+	return res
+}
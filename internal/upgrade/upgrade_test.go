@@ -0,0 +1,80 @@
+package upgrade
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := map[string]time.Duration{
+		"off":    0,
+		"daily":  24 * time.Hour,
+		"Weekly": 7 * 24 * time.Hour,
+		"72h":    72 * time.Hour,
+	}
+	for value, expected := range tests {
+		actual, err := ParseInterval(value)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", value, err)
+		}
+		if actual != expected {
+			t.Errorf("%s: got %v instead of %v", value, actual, expected)
+		}
+	}
+
+	for _, value := range []string{"monthly", "-1h"} {
+		if _, err := ParseInterval(value); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberlr-upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stamp := filepath.Join(dir, "stamp")
+	now := time.Now()
+
+	if !Due(stamp, time.Hour, now) {
+		t.Error("First upgrade should be due")
+	}
+	if err := Touch(stamp, now); err != nil {
+		t.Fatal(err)
+	}
+	if Due(stamp, time.Hour, now.Add(time.Minute)) {
+		t.Error("Upgrade should not be due")
+	}
+	if !Due(stamp, time.Hour, now.Add(2*time.Hour)) {
+		t.Error("Upgrade should be due")
+	}
+	if Due(stamp, 0, now.Add(2*time.Hour)) {
+		t.Error("Disabled upgrades are never due")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	installed := []semver.Version{
+		semver.MustParse("1.19.2"),
+		semver.MustParse("1.19.4"),
+		semver.MustParse("1.20.1"),
+		semver.MustParse("1.21.0"),
+	}
+	remote := semver.Versions{}
+	for _, v := range []string{"1.19.4", "1.20.1", "1.20.3", "1.20.4-rc.0", "1.21.0", "1.22.0"} {
+		remote = append(remote, semver.MustParse(v))
+	}
+
+	actual := Plan(installed, remote)
+	if len(actual) != 1 || actual[0].String() != "1.20.3" {
+		t.Errorf("Got %v instead of [1.20.3]", actual)
+	}
+}
@@ -0,0 +1,102 @@
+package warnings
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+const (
+	// Unreachable is the class of warnings emitted when the remote
+	// kubernetes API server cannot be contacted
+	Unreachable = "unreachable"
+	// Fallback is the class of warnings emitted when kuberlr has to guess
+	// the version of kubectl to use
+	Fallback = "fallback"
+	// EndOfLife is the class of warnings emitted when the version of
+	// kubernetes in use is no longer supported upstream
+	EndOfLife = "eol"
+	// Kustomize is the class of warnings emitted when the version of
+	// kustomize embedded by kubectl is not the one expected by a project
+	Kustomize = "kustomize"
+)
+
+// DefaultInterval is the amount of time during which the same warning
+// is not shown again
+const DefaultInterval = 24 * time.Hour
+
+// Warner prints warnings to the user, making sure the same message is
+// not repeated on every invocation of kuberlr
+type Warner struct {
+	// StateFile is where the time of the last occurrence of each
+	// warning is recorded
+	StateFile string
+	// Context is the kubernetes context the warnings refer to
+	Context string
+	// Interval is the amount of time during which a warning is not repeated
+	Interval time.Duration
+	// Silenced holds the classes of warnings that are never shown
+	Silenced []string
+	// Out is where the warnings are written to
+	Out io.Writer
+}
+
+// NewWarner returns a Warner that keeps its state inside of the
+// kuberlr directory of the user
+func NewWarner(context string, interval time.Duration, silenced []string) *Warner {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Warner{
+		StateFile: filepath.Join(common.KuberlrDir(), "warnings.json"),
+		Context:   context,
+		Interval:  interval,
+		Silenced:  silenced,
+		Out:       os.Stderr,
+	}
+}
+
+// Warn prints the warning, unless its class has been silenced or the very
+// same message has already been shown for the current context during the
+// last Interval
+func (w *Warner) Warn(class, format string, args ...interface{}) {
+	for _, s := range w.Silenced {
+		if s == class {
+			return
+		}
+	}
+
+	msg := fmt.Sprintf(format, args...)
+	key := fmt.Sprintf("%s/%s/%s", class, w.Context, msg)
+	now := time.Now()
+
+	// the state is updated while holding its lock, otherwise concurrent
+	// invocations of kubectl would show the same warning
+	decided := false
+	state := make(map[string]time.Time)
+	err := common.UpdateStateFile(w.StateFile, &state, func() {
+		decided = true
+		// forget about the warnings that expired, this keeps the
+		// state file small
+		for k, last := range state {
+			if now.Sub(last) >= w.Interval {
+				delete(state, k)
+			}
+		}
+		if _, found := state[key]; found {
+			return
+		}
+		fmt.Fprintf(w.Out, "Warning: %s\n", msg)
+		state[key] = now
+	})
+	// not being able to persist the state is not worth a failure,
+	// the worst case scenario is the warning being shown again
+	if err != nil && !decided {
+		fmt.Fprintf(w.Out, "Warning: %s\n", msg)
+	}
+}
@@ -0,0 +1,103 @@
+package warnings
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestWarner(stateDir string) (*Warner, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &Warner{
+		StateFile: filepath.Join(stateDir, "warnings.json"),
+		Context:   "test",
+		Interval:  time.Hour,
+		Out:       out,
+	}, out
+}
+
+func TestWarnOnce(t *testing.T) {
+	stateDir, err := ioutil.TempDir("", "kuberlr-warnings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+
+	w, out := newTestWarner(stateDir)
+	w.Warn(Unreachable, "server %s unreachable", "foo")
+
+	// a different invocation of kuberlr sharing the same state
+	w2, out2 := newTestWarner(stateDir)
+	w2.Warn(Unreachable, "server %s unreachable", "foo")
+
+	if !strings.Contains(out.String(), "server foo unreachable") {
+		t.Errorf("Warning not printed: %q", out.String())
+	}
+	if out2.Len() != 0 {
+		t.Errorf("Warning printed twice: %q", out2.String())
+	}
+
+	// same message for another context must be shown
+	w3, out3 := newTestWarner(stateDir)
+	w3.Context = "another"
+	w3.Warn(Unreachable, "server %s unreachable", "foo")
+	if out3.Len() == 0 {
+		t.Error("Warning for a different context not printed")
+	}
+}
+
+func TestWarnSilenced(t *testing.T) {
+	stateDir, err := ioutil.TempDir("", "kuberlr-warnings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+
+	w, out := newTestWarner(stateDir)
+	w.Silenced = []string{Fallback}
+
+	w.Warn(Fallback, "falling back")
+	if out.Len() != 0 {
+		t.Errorf("Silenced warning printed: %q", out.String())
+	}
+
+	w.Warn(Unreachable, "unreachable")
+	if out.Len() == 0 {
+		t.Error("Warning not printed")
+	}
+}
+
+func TestWarnConcurrently(t *testing.T) {
+	stateDir, err := ioutil.TempDir("", "kuberlr-warnings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+
+	outs := make([]*bytes.Buffer, 10)
+	var wg sync.WaitGroup
+	for i := range outs {
+		w, out := newTestWarner(stateDir)
+		outs[i] = out
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w.Warn(Unreachable, "server %d unreachable", i)
+		}(i)
+	}
+	wg.Wait()
+
+	// every warning has been recorded, none of them is shown again
+	for i := range outs {
+		w, out := newTestWarner(stateDir)
+		w.Warn(Unreachable, "server %d unreachable", i)
+		if out.Len() != 0 {
+			t.Errorf("Warning %d printed twice: %q", i, out.String())
+		}
+	}
+}